@@ -1,6 +1,7 @@
 package main
 
 import (
+	"COM3D2_MOD_EDITOR_V2/internal/service/COM3D2"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -32,6 +33,22 @@ func NewApp() *App {
 	return &App{}
 }
 
+// 打开文件相关的事件名称
+const (
+	FilesOpenedEvent    = "files-opened"    // 启动参数中的所有文件，数据为 []OpenedFile
+	FilesForwardedEvent = "files-forwarded" // 其他实例转发的文件，数据为 []OpenedFile
+	FilesDroppedEvent   = "files-dropped"   // 拖放到窗口的文件，数据为 []OpenedFile
+)
+
+// OpenedFile 需要打开的文件和识别出的文件类型，无法识别时 Error 不为空
+// Info 为 FileTypeDetermine 的结果，前端直接使用，不再重新识别
+type OpenedFile struct {
+	Path     string          `json:"Path"`
+	FileType string          `json:"FileType"`
+	Info     COM3D2.FileInfo `json:"Info"`
+	Error    string          `json:"Error"`
+}
+
 // Startup is called when the app starts.
 // The context is saved,
 // so we can call the runtime methods
@@ -39,22 +56,67 @@ func NewApp() *App {
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx // 保存上下文，重要
 
-	args := os.Args[1:] // 排除第一个参数（程序路径）
-	// 过滤其他参数
-	var filePath string
+	filePaths := startupFilePaths(os.Args[1:]) // 排除第一个参数（程序路径）
+
+	runtime.EventsOnce(ctx, "app-ready", func(_ ...interface{}) {
+		if len(filePaths) > 0 {
+			runtime.EventsEmit(ctx, FilesOpenedEvent, identifyFiles(filePaths))
+		}
+	})
+
+	// 拖放的文件由后端识别类型后再交给前端
+	runtime.OnFileDrop(ctx, a.onFileDrop)
+}
+
+// onFileDrop 处理拖放到窗口的文件，识别类型后发送 FilesDroppedEvent 事件
+func (a *App) onFileDrop(x int, y int, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	runtime.EventsEmit(a.ctx, FilesDroppedEvent, identifyFiles(paths))
+}
+
+// openForwardedFiles 打开其他实例转发的文件，并将窗口切换到前台
+// 没有文件时只切换窗口
+func (a *App) openForwardedFiles(paths []string) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.WindowUnminimise(a.ctx)
+	runtime.WindowShow(a.ctx)
+	if len(paths) > 0 {
+		runtime.EventsEmit(a.ctx, FilesForwardedEvent, identifyFiles(paths))
+	}
+}
+
+// startupFilePaths 过滤命令行参数中的选项，返回所有文件路径
+func startupFilePaths(args []string) []string {
+	var filePaths []string
 	for _, arg := range args {
-		if !strings.HasPrefix(arg, "--") && !strings.HasPrefix(arg, "-") && arg != "" {
-			filePath = arg
-			break
+		if !strings.HasPrefix(arg, "-") && arg != "" {
+			filePaths = append(filePaths, arg)
 		}
 	}
+	return filePaths
+}
 
-	runtime.EventsOnce(ctx, "app-ready", func(_ ...interface{}) {
-		if filePath != "" {
-			runtime.EventsOnce(ctx, "file-opened", func(_ ...interface{}) {})
-			runtime.EventsEmit(ctx, "file-opened", filePath)
+// identifyFiles 识别文件类型，使用设置中的严格模式
+func identifyFiles(paths []string) []OpenedFile {
+	strict := COM3D2.CurrentSettings().FileTypeStrictMode
+	commonService := &COM3D2.CommonService{}
+	files := make([]OpenedFile, 0, len(paths))
+	for _, path := range paths {
+		file := OpenedFile{Path: path}
+		fileInfo, err := commonService.FileTypeDetermine(path, strict)
+		if err != nil {
+			file.Error = err.Error()
+		} else {
+			file.FileType = fileInfo.FileType
+			file.Info = fileInfo
 		}
-	})
+		files = append(files, file)
+	}
+	return files
 }
 
 // SelectFile 选择需要处理的文件，返回用户选择的文件路径
@@ -196,15 +258,9 @@ func (a *App) IsSupportedImageType(filePath string) bool {
 	return true
 }
 
-// GetFileSize 获取文件大小
+// GetFileSize 获取文件大小，支持压缩包内的虚拟路径
 func (a *App) GetFileSize(path string) (int64, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-
-	fi, err := f.Stat()
+	fi, err := COM3D2.StatFile(path)
 	if err != nil {
 		return 0, err
 	}
@@ -212,18 +268,7 @@ func (a *App) GetFileSize(path string) (int64, error) {
 	return fi.Size(), nil
 }
 
-// GetFileInfo 获取文件信息
+// GetFileInfo 获取文件信息，支持压缩包内的虚拟路径
 func (a *App) GetFileInfo(path string) (fs.FileInfo, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	fi, err := f.Stat()
-	if err != nil {
-		return nil, err
-	}
-
-	return fi, nil
+	return COM3D2.StatFile(path)
 }
@@ -2,7 +2,7 @@
 import React, {useEffect, useState} from "react";
 import {Route, Routes} from "react-router-dom";
 import HomePage from "./components/HomePage";
-import {EventsEmit, EventsOnce, OnFileDrop} from "../wailsjs/runtime";
+import {EventsEmit, EventsOn} from "../wailsjs/runtime";
 import MenuEditorPage from "./components/MenuEditorPage";
 import MateEditorPage from "./components/MateEditorPage";
 import PMatEditorPage from "./components/PMatEditorPage";
@@ -12,7 +12,7 @@ import ColEditorPage from "./components/ColEditorPage";
 import PhyEditorPage from "./components/PhyEditorPage";
 import SettingsPage from "./components/SettingsPage";
 import TexEditorPage from "./components/TexEditorPage";
-import useFileHandlers from "./hooks/fileHanlder";
+import useFileHandlers, {OpenedFile} from "./hooks/fileHanlder";
 import AnmEditorPage from "./components/AnmEditorPage";
 import PskEditorPage from "./components/PskEditorPage";
 import ModelEditorPage from "./components/ModelEditorPage";
@@ -21,7 +21,7 @@ import {DisclaimerAgreedKey} from "./utils/LocalStorageKeys";
 
 const App: React.FC = () => {
     const isDarkMode = useDarkMode();
-    const {handleOpenedFile} = useFileHandlers();
+    const {handleOpenedFiles} = useFileHandlers();
     const [showDisclaimer, setShowDisclaimer] = useState(() => {
         return localStorage.getItem(DisclaimerAgreedKey) !== 'true';
     });
@@ -32,26 +32,19 @@ const App: React.FC = () => {
         localStorage.setItem(DisclaimerAgreedKey, 'true');
     };
 
-    // 监听 Wails 事件 "file-opened" (即用户可以通过双击某种类型的文件，然后让应用打开该文件)
+    // 监听 Wails 事件 "files-opened" (即用户可以通过双击某种类型的文件，然后让应用打开该文件)、
+    // "files-forwarded" (之后启动的实例转发过来的文件) 和 "files-dropped" (用户拖放文件)，都由后端识别文件类型后发送
+    // 只有本进程的启动参数在直接转换后退出，转发和拖放的文件转换后继续运行
     useEffect(() => {
-        const handleFileOpened = async (filePath: string) => {
-            await handleOpenedFile(filePath);
-        };
-
-        EventsOnce('file-opened', handleFileOpened);
+        const offOpened = EventsOn('files-opened', (files: OpenedFile[]) => handleOpenedFiles(files, true));
+        const offForwarded = EventsOn('files-forwarded', (files: OpenedFile[]) => handleOpenedFiles(files, false));
+        const offDropped = EventsOn('files-dropped', (files: OpenedFile[]) => handleOpenedFiles(files, false));
         return () => {
+            offOpened();
+            offForwarded();
+            offDropped();
         }
-    }, [handleOpenedFile])
-
-
-    // 用户拖放文件
-    useEffect(() => {
-        const handleDrop = async (x: number, y: number, paths: string[]) => {
-            await handleOpenedFile(paths[0]);
-        };
-
-        OnFileDrop(handleDrop, false);
-    }, [handleOpenedFile]);
+    }, [handleOpenedFiles])
 
     // 通知后端前端已就绪
     useEffect(() => {
@@ -160,7 +160,7 @@ const AnmEditor = forwardRef<AnmEditorRef, AnmEditorProps>((props, ref) => {
         }
 
         try {
-            const newPath = await SelectPathToSave("*.anm;*.anm.json", t('Infos.com3d2_anm_file'));
+            const newPath = await SelectPathToSave("*.anm;*.anm.json;*.anm.yaml;*.anm.yml", t('Infos.com3d2_anm_file'));
             if (!newPath) {
                 // 用户取消
                 return;
@@ -190,8 +190,8 @@ const AnmEditor = forwardRef<AnmEditorRef, AnmEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.anm\.json$/, '.anm');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.anm\.(json|ya?ml)$/, '.anm');
                     await ConvertJsonToAnm(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
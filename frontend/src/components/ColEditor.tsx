@@ -8,7 +8,7 @@ import {useTranslation} from "react-i18next";
 import {COM3D2HeaderConstants} from "../utils/ConstCOM3D2";
 import Style1ColProperties from "./col/Style1ColProperties";
 import Style2ColProperties from "./col/Style2ColProperties";
-import {colEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import DynamicBoneColliderBase = COM3D2.DynamicBoneColliderBase;
 import DynamicBoneCollider = COM3D2.DynamicBoneCollider;
 import DynamicBonePlaneCollider = COM3D2.DynamicBonePlaneCollider;
@@ -55,10 +55,7 @@ const ColEditor = forwardRef<ColEditorRef, ColEditorProps>((props, ref) => {
     const [form] = Form.useForm();
 
     // 用来切换视图模式
-    const [viewMode, setViewMode] = useState<1 | 2>(() => {
-        const saved = localStorage.getItem(colEditorViewModeKey);
-        return saved ? Number(saved) as 1 | 2 : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2>(() => getEditorViewMode<1 | 2>("col", 1));
 
     // 大文件警告模态框
     const [isConfirmModalOpen, setIsConfirmModalOpen] = useState(false);
@@ -134,8 +131,8 @@ const ColEditor = forwardRef<ColEditorRef, ColEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.col\.json$/, '.col');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.col\.(json|ya?ml)$/, '.col');
                     await ConvertJsonToCol(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -207,7 +204,7 @@ const ColEditor = forwardRef<ColEditorRef, ColEditorProps>((props, ref) => {
             // 模式 2 JSON 编辑，直接保存
             if (viewMode === 2) {
 
-                const newPath = await SelectPathToSave("*.col;*.col.json", t('Infos.com3d2_col_file'));
+                const newPath = await SelectPathToSave("*.col;*.col.json;*.col.yaml;*.col.yml", t('Infos.com3d2_col_file'));
                 if (!newPath) {
                     // 用户取消
                     return;
@@ -221,7 +218,7 @@ const ColEditor = forwardRef<ColEditorRef, ColEditorProps>((props, ref) => {
             const newCol = transformFormToCol(values, colData);
 
             // 让用户选择一个保存路径
-            const newPath = await SelectPathToSave("*.col;*.col.json", t('Infos.com3d2_col_file'));
+            const newPath = await SelectPathToSave("*.col;*.col.json;*.col.yaml;*.col.yml", t('Infos.com3d2_col_file'));
             if (!newPath) {
                 // 用户取消
                 return;
@@ -453,7 +450,7 @@ const ColEditor = forwardRef<ColEditorRef, ColEditorProps>((props, ref) => {
                                 }
 
                                 setViewMode(e.target.value);
-                                localStorage.setItem(colEditorViewModeKey, e.target.value.toString());
+                                setEditorViewMode("col", e.target.value);
                             }}
                             options={[
                                 {label: t('ColEditor.style1'), value: 1},
@@ -24,7 +24,7 @@ import Style3MateProperties from "./mate/Style3MateProperties";
 import Style2MateProperties from "./mate/Style2MateProperties";
 import Style1MateProperties from "./mate/Style1MateProperties";
 import Style1MatePropertiesVirtualized from "./mate/Style1MatePropertiesVirtualized";
-import {MateEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import Mate = COM3D2.Mate;
 import Material = COM3D2.Material;
 import TexProperty = COM3D2.TexProperty;
@@ -74,10 +74,7 @@ const MateEditor = forwardRef<MateEditorRef, MateEditorProps>((props, ref) => {
     const [form] = Form.useForm();
 
     // 用来切换样式模式：1 or 2 or 3
-    const [viewMode, setViewMode] = useState<1 | 2 | 3>(() => {
-        const saved = localStorage.getItem(MateEditorViewModeKey);
-        return saved ? Number(saved) as 1 | 2 | 3 : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2 | 3>(() => getEditorViewMode<1 | 2 | 3>("mate", 1));
 
     // 大文件警告模态框
     const [isConfirmModalOpen, setIsConfirmModalOpen] = useState(false);
@@ -154,8 +151,8 @@ const MateEditor = forwardRef<MateEditorRef, MateEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.mate\.json$/, '.mate');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.mate\.(json|ya?ml)$/, '.mate');
                     await ConvertJsonToMate(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -232,7 +229,7 @@ const MateEditor = forwardRef<MateEditorRef, MateEditorProps>((props, ref) => {
             // 模式 3 JSON 编辑，直接保存
             if (viewMode === 3) {
                 // 询问保存路径
-                const newPath = await SelectPathToSave("*.mate;*.mate.json", t('Infos.com3d2_mate_file'));
+                const newPath = await SelectPathToSave("*.mate;*.mate.json;*.mate.yaml;*.mate.yml", t('Infos.com3d2_mate_file'));
                 if (!newPath) {
                     // 用户取消
                     return;
@@ -249,7 +246,7 @@ const MateEditor = forwardRef<MateEditorRef, MateEditorProps>((props, ref) => {
             const newMate = transformFormToMate(values, mateData);
 
             // 询问保存路径
-            const newPath = await SelectPathToSave("*.mate;*.mate.json", t('Infos.com3d2_mate_file'));
+            const newPath = await SelectPathToSave("*.mate;*.mate.json;*.mate.yaml;*.mate.yml", t('Infos.com3d2_mate_file'));
             if (!newPath) {
                 // 用户取消
                 return;
@@ -670,7 +667,7 @@ const MateEditor = forwardRef<MateEditorRef, MateEditorProps>((props, ref) => {
                                 }
 
                                 setViewMode(e.target.value);
-                                localStorage.setItem(MateEditorViewModeKey, e.target.value.toString());
+                                setEditorViewMode("mate", e.target.value);
                             }}
                             options={[
                                 {label: t('MateEditor.style1'), value: 1},
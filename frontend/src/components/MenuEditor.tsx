@@ -25,7 +25,7 @@ import {useDarkMode} from "../hooks/themeSwitch";
 import {setupMonacoEditor} from "../utils/menuMonacoConfig";
 import {ConvertJsonToMenu, ConvertMenuToJson, ReadMenuFile, WriteMenuFile} from "../../wailsjs/go/COM3D2/MenuService";
 import {COM3D2HeaderConstants} from "../utils/ConstCOM3D2";
-import {MenuEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import Menu = COM3D2.Menu;
 import Command = COM3D2.Command;
 import FileInfo = COM3D2.FileInfo;
@@ -67,8 +67,8 @@ const MenuEditor = forwardRef<MenuEditorRef, MenuEditorProps>((props, ref) => {
 
         // 切换显示格式
         const [displayFormat, setDisplayFormat] = useState<FormatType>(
-            // 从 localStorage 读取上次保存的格式，默认使用 treeIndent
-            () => (localStorage.getItem(MenuEditorViewModeKey) as FormatType) || "treeIndent"
+            // 读取上次保存的格式，默认使用 treeIndent
+            () => getEditorViewMode<FormatType>("menu", "treeIndent")
         );
 
         // 只读字段是否可编辑
@@ -80,7 +80,7 @@ const MenuEditor = forwardRef<MenuEditorRef, MenuEditorProps>((props, ref) => {
         // Monaco Editor 编程语言
         const [language, setLanguage] = useState(() => {
             // 根据当前的 displayFormat 初始化 language
-            const fmt = localStorage.getItem(MenuEditorViewModeKey) as FormatType || "treeIndent";
+            const fmt = getEditorViewMode<FormatType>("menu", "treeIndent");
             if (fmt === "JSON") {
                 return "json";
             } else if (fmt === "treeIndent") {
@@ -192,8 +192,8 @@ const MenuEditor = forwardRef<MenuEditorRef, MenuEditorProps>((props, ref) => {
             if (DirectlyConvert) {
                 const hide = message.loading(t('Infos.converting_please_wait'), 0);
                 try {
-                    if (fileInfo.StorageFormat == "json") {
-                        const path = filePath.replace(/\.menu\.json$/, '.menu');
+                    if (fileInfo.StorageFormat != "binary") {
+                        const path = filePath.replace(/\.menu\.(json|ya?ml)$/, '.menu');
                         await ConvertJsonToMenu(filePath, path);
                         message.success(t('Infos.directly_convert_success') + path, 5);
                     } else {
@@ -396,7 +396,7 @@ const MenuEditor = forwardRef<MenuEditorRef, MenuEditorProps>((props, ref) => {
                     Commands: parsedCommands
                 });
 
-                const path = await SelectPathToSave("*.menu;*.menu.json", t('Infos.com3d2_menu_file'));
+                const path = await SelectPathToSave("*.menu;*.menu.json;*.menu.yaml;*.menu.yml", t('Infos.com3d2_menu_file'));
                 if (!path) {
                     // 用户取消了保存
                     return;
@@ -595,7 +595,7 @@ const MenuEditor = forwardRef<MenuEditorRef, MenuEditorProps>((props, ref) => {
                                         value={displayFormat}
                                         onChange={(e) => {
                                             setDisplayFormat(e.target.value);
-                                            localStorage.setItem(MenuEditorViewModeKey, e.target.value);
+                                            setEditorViewMode("menu", e.target.value);
                                         }}
                                     />
                                 </Flex>
@@ -12,7 +12,7 @@ import {
     WriteModelMetadata
 } from "../../wailsjs/go/COM3D2/ModelService";
 import {SelectPathToSave} from "../../wailsjs/go/main/App";
-import {ModelEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import ModelMonacoEditor from "./model/ModelMonacoEditor";
 import ModelMetadataEditor from "./model/ModelMetadataEditor";
 import Model = COM3D2.Model;
@@ -42,10 +42,7 @@ const ModelEditor = forwardRef<ModelEditorRef, ModelEditorProps>((props, ref) =>
     const [modelMetadata, setModelMetadata] = useState<ModelMetadata | null>(null);
 
     // 用来切换视图模式: 1=完整JSON编辑, 2=元数据编辑
-    const [viewMode, setViewMode] = useState<1 | 2>(() => {
-        const saved = localStorage.getItem(ModelEditorViewModeKey);
-        return saved ? Number(saved) as 1 | 2 : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2>(() => getEditorViewMode<1 | 2>("model", 1));
 
     const [isConfirmModalOpen, setIsConfirmModalOpen] = useState(false);
     const [pendingFileContent, setPendingFileContent] = useState<{ size: number }>({size: 0});
@@ -167,7 +164,7 @@ const ModelEditor = forwardRef<ModelEditorRef, ModelEditorProps>((props, ref) =>
             }
 
             try {
-                const newPath = await SelectPathToSave("*.model;*.model.json", t('Infos.com3d2_model_file'));
+                const newPath = await SelectPathToSave("*.model;*.model.json;*.model.yaml;*.model.yml", t('Infos.com3d2_model_file'));
                 if (!newPath) {
                     // 用户取消
                     return;
@@ -196,7 +193,7 @@ const ModelEditor = forwardRef<ModelEditorRef, ModelEditorProps>((props, ref) =>
             }
 
             try {
-                const newPath = await SelectPathToSave("*.model;*.model.json", t('Infos.com3d2_model_file'));
+                const newPath = await SelectPathToSave("*.model;*.model.json;*.model.yaml;*.model.yml", t('Infos.com3d2_model_file'));
                 if (!newPath) {
                     // 用户取消
                     return;
@@ -261,8 +258,8 @@ const ModelEditor = forwardRef<ModelEditorRef, ModelEditorProps>((props, ref) =>
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.model\.json$/, '.model');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.model\.(json|ya?ml)$/, '.model');
                     await ConvertJsonToModel(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -332,7 +329,7 @@ const ModelEditor = forwardRef<ModelEditorRef, ModelEditorProps>((props, ref) =>
                     value={viewMode}
                     onChange={(e) => {
                         setViewMode(e.target.value);
-                        localStorage.setItem(ModelEditorViewModeKey, e.target.value.toString());
+                        setEditorViewMode("model", e.target.value);
                     }}
                     options={[
                         {label: t('ModelEditor.full_model'), value: 1},
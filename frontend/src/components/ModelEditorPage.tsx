@@ -26,7 +26,7 @@ const ModelEditorPage: React.FC = () => {
     return (
         <Layout style={{height: "100vh"}}>
             <NavBar
-                onSelectFile={() => handleSelectFile("*.model;*.model.json", t('Infos.com3d2_model_file'))}
+                onSelectFile={() => handleSelectFile("*.model;*.model.json;*.model.yaml;*.model.yml", t('Infos.com3d2_model_file'))}
                 onSaveFile={() => handleSaveFile(modelEditorRef)}
                 onSaveAsFile={() => handleSaveAsFile(modelEditorRef)}
             />
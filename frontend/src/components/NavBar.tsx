@@ -1,12 +1,13 @@
 // frontend/src/components/NavBar.tsx
 import React, {useEffect} from "react";
-import {Button, Layout, Menu, Tooltip} from "antd";
+import {Button, Dropdown, Layout, Menu, Tooltip} from "antd";
 import {useLocation, useNavigate} from "react-router-dom";
 import {useTranslation} from "react-i18next";
-import {HomeOutlined} from "@ant-design/icons";
+import {FileOutlined, HomeOutlined} from "@ant-design/icons";
 import {GitHubReleaseUrl} from "../utils/consts";
 import {BrowserOpenURL} from "../../wailsjs/runtime";
 import {useVersionCheck} from "../utils/CheckUpdate";
+import {clearOpenedFiles, editorRoute, useOpenedFiles} from "../utils/OpenedFiles";
 
 const {Header} = Layout;
 
@@ -30,10 +31,23 @@ const NavBar: React.FC<EditorNavBarProps> = ({
 
     const hasUpdate = useVersionCheck();
 
+    // 本次运行中打开过的文件，点击切换到该文件
+    const openedFiles = useOpenedFiles();
+    const currentPath = (location.state as { fileInfo?: { Path: string } } | undefined)?.fileInfo?.Path;
+
     const handleMenuClick = (e: any) => {
         navigate(`/${e.key}`);
     };
 
+    const handleOpenedFileClick = (e: any) => {
+        if (e.key === "clear") {
+            clearOpenedFiles();
+            return;
+        }
+        const fileInfo = openedFiles[Number(e.key)];
+        navigate(editorRoute(fileInfo), {state: {fileInfo}});
+    };
+
     // 监听快捷键
     useEffect(() => {
         const handleKeyDown = (e: KeyboardEvent) => {
@@ -116,6 +130,26 @@ const NavBar: React.FC<EditorNavBarProps> = ({
                     whiteSpace: "nowrap", // 防止按钮换行
                     marginLeft: 16 // 添加左侧间距
                 }}>
+                {openedFiles.length > 0 && (
+                    <Dropdown
+                        menu={{
+                            items: [
+                                ...openedFiles.map((fileInfo, index) => ({
+                                    key: String(index),
+                                    label: <span title={fileInfo.Path}>{fileInfo.Path.split(/[\\/]/).pop()}</span>,
+                                })),
+                                {type: "divider" as const},
+                                {key: "clear", label: t('EditorNavBar.clear_opened_files')},
+                            ],
+                            selectable: true,
+                            selectedKeys: [String(openedFiles.findIndex(f => f.Path === currentPath))],
+                            onClick: handleOpenedFileClick,
+                        }}>
+                        <Button icon={<FileOutlined/>} style={{marginRight: 8}}>
+                            {t('EditorNavBar.opened_files')} ({openedFiles.length})
+                        </Button>
+                    </Dropdown>
+                )}
                 <Tooltip title={t('Common.open_file_shortcut')}>
                     <Button type="primary" onClick={onSelectFile}
                             style={{marginRight: 8}}>{t('EditorNavBar.open_file')}</Button>
@@ -117,8 +117,8 @@ const PMatEditor = forwardRef<PMatEditorRef, PMatEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.pmat\.json$/, '.pmat');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.pmat\.(json|ya?ml)$/, '.pmat');
                     await ConvertJsonToPMat(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -204,7 +204,7 @@ const PMatEditor = forwardRef<PMatEditorRef, PMatEditorProps>((props, ref) => {
             };
 
             // 让用户选择要保存的位置
-            const path = await SelectPathToSave("*.pmat;*.pmat.json", t("Infos.com3d2_pmat_file"));
+            const path = await SelectPathToSave("*.pmat;*.pmat.json;*.pmat.yaml;*.pmat.yml", t("Infos.com3d2_pmat_file"));
             if (!path) {
                 // 用户取消了保存
                 return;
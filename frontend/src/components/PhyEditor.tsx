@@ -10,7 +10,7 @@ import {useTranslation} from "react-i18next";
 import {ReadColFile} from "../../wailsjs/go/COM3D2/ColService";
 import Style2PhyProperties from "./phy/Style2PhyProperties";
 import Style1PhyProperties from "./phy/Style1PhyProperties";
-import {PhyEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import Phy = COM3D2.Phy;
 import BoneValue = COM3D2.BoneValue;
 import AnimationCurve = COM3D2.AnimationCurve;
@@ -318,10 +318,7 @@ const PhyEditor = forwardRef<PhyEditorRef, PhyEditorProps>((props, ref) => {
     const colliderFileName = Form.useWatch('colliderFileName', form);
 
     //  viewMode，1=表单模式，2=JSON模式
-    const [viewMode, setViewMode] = useState<1 | 2>(() => {
-        const saved = localStorage.getItem(PhyEditorViewModeKey);
-        return saved ? (Number(saved) as 1 | 2) : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2>(() => getEditorViewMode<1 | 2>("phy", 1));
 
     // 大文件警告模态框
     const [isConfirmModalOpen, setIsConfirmModalOpen] = useState(false);
@@ -405,8 +402,8 @@ const PhyEditor = forwardRef<PhyEditorRef, PhyEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.phy\.json$/, '.phy');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.phy\.(json|ya?ml)$/, '.phy');
                     await ConvertJsonToPhy(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -473,7 +470,7 @@ const PhyEditor = forwardRef<PhyEditorRef, PhyEditorProps>((props, ref) => {
         }
         try {
             // 询问保存路径
-            const newPath = await SelectPathToSave("*.phy;*.phy.json", t('Infos.com3d2_phy_file'));
+            const newPath = await SelectPathToSave("*.phy;*.phy.json;*.phy.yaml;*.phy.yml", t('Infos.com3d2_phy_file'));
             if (!newPath) {
                 return; // 用户取消
             }
@@ -619,7 +616,7 @@ const PhyEditor = forwardRef<PhyEditorRef, PhyEditorProps>((props, ref) => {
                                     setPhyData(updatedPhy);
                                 }
                                 setViewMode(e.target.value);
-                                localStorage.setItem(PhyEditorViewModeKey, e.target.value.toString());
+                                setEditorViewMode("phy", e.target.value);
                             }}
                             options={[
                                 {label: t('PhyEditor.style1'), value: 1},
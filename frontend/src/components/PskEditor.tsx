@@ -7,7 +7,7 @@ import {COM3D2} from "../../wailsjs/go/models";
 import {ConvertJsonToPsk, ConvertPskToJson, ReadPskFile, WritePskFile} from "../../wailsjs/go/COM3D2/PskService";
 import {SelectPathToSave} from "../../wailsjs/go/main/App";
 import Style2PskProperties from "./psk/Style2PskProperties";
-import {PskEditorViewModeKey} from "../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../utils/Settings";
 import Style1PskProperties from "./psk/Style1PskProperties";
 import Psk = COM3D2.Psk;
 import FileInfo = COM3D2.FileInfo;
@@ -38,10 +38,7 @@ const PskEditor = forwardRef<PskEditorRef, PskEditorProps>((props, ref) => {
     const [form] = Form.useForm();
 
     // 用来切换视图模式
-    const [viewMode, setViewMode] = useState<1 | 2>(() => {
-        const saved = localStorage.getItem(PskEditorViewModeKey);
-        return saved ? Number(saved) as 1 | 2 : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2>(() => getEditorViewMode<1 | 2>("psk", 1));
 
     // 大文件警告模态框
     const [isConfirmModalOpen, setIsConfirmModalOpen] = useState(false);
@@ -120,8 +117,8 @@ const PskEditor = forwardRef<PskEditorRef, PskEditorProps>((props, ref) => {
         if (DirectlyConvert) {
             const hide = message.loading(t('Infos.converting_please_wait'), 0);
             try {
-                if (fileInfo.StorageFormat == "json") {
-                    const path = filePath.replace(/\.psk\.json$/, '.psk');
+                if (fileInfo.StorageFormat != "binary") {
+                    const path = filePath.replace(/\.psk\.(json|ya?ml)$/, '.psk');
                     await ConvertJsonToPsk(filePath, path);
                     message.success(t('Infos.directly_convert_success') + path, 5);
                 } else {
@@ -177,7 +174,7 @@ const PskEditor = forwardRef<PskEditorRef, PskEditorProps>((props, ref) => {
         }
 
         try {
-            const newPath = await SelectPathToSave("*.psk;*.psk.json", t('Infos.com3d2_psk_file'));
+            const newPath = await SelectPathToSave("*.psk;*.psk.json;*.psk.yaml;*.psk.yml", t('Infos.com3d2_psk_file'));
             if (!newPath) {
                 // 用户取消
                 return;
@@ -468,7 +465,7 @@ const PskEditor = forwardRef<PskEditorRef, PskEditorProps>((props, ref) => {
                                 }
 
                                 setViewMode(e.target.value);
-                                localStorage.setItem(PskEditorViewModeKey, e.target.value.toString());
+                                setEditorViewMode("psk", e.target.value);
                             }}
                             options={[
                                 {label: t('PskEditor.style1'), value: 1},
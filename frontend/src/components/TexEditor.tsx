@@ -8,12 +8,7 @@ import {ImageMagickUrl} from "../utils/consts";
 import useFileHandlers from "../hooks/fileHanlder";
 import {SelectPathToSave} from "../../wailsjs/go/main/App";
 import {useDarkMode} from "../hooks/themeSwitch";
-import {
-    TexEditorCompressKey,
-    TexEditorDefaultFormatKey,
-    TexEditorDirectConvertKey,
-    TexEditorForcePngKey
-} from "../utils/LocalStorageKeys";
+import {getSettings, updateSettings} from "../utils/Settings";
 import {COM3D2} from "../../wailsjs/go/models";
 import FileInfo = COM3D2.FileInfo;
 
@@ -39,23 +34,12 @@ const TexEditor = forwardRef<TexEditorRef, TexEditorProps>((props, ref) => {
     // 预览数据
     const [imageData, setImageData] = useState<string | null>(null);
 
-    // 持久化选项
-    const [forcePng, setForcePng] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorForcePngKey);
-        return saved ? JSON.parse(saved) : true;
-    });
-    const [directConvert, setDirectConvert] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorDirectConvertKey);
-        return saved ? JSON.parse(saved) : false;
-    });
-    const [compress, setCompress] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorCompressKey);
-        return saved ? JSON.parse(saved) : false;
-    });
-    const [defaultFormat, setDefaultFormat] = useState<string>(() => {
-        const saved = localStorage.getItem(TexEditorDefaultFormatKey);
-        return saved ? JSON.parse(saved) : ".png";
-    })
+    // 持久化选项，保存在后端的设置中
+    const [forcePng, setForcePng] = useState<boolean>(() => getSettings().TexForcePng);
+    const [directConvert, setDirectConvert] = useState<boolean>(() => getSettings().TexDirectConvert);
+    const [compress, setCompress] = useState<boolean>(() => getSettings().TexCompress);
+    // 设置中的格式不带点，这里显示为 .png
+    const [defaultFormat, setDefaultFormat] = useState<string>(() => "." + getSettings().TexDefaultFormat)
 
 
     // 加载状态
@@ -72,20 +56,36 @@ const TexEditor = forwardRef<TexEditorRef, TexEditorProps>((props, ref) => {
         setLoading(false);
     }
 
+    /** 保存选项，失败时提示 */
+    const saveSettings = (changes: Parameters<typeof updateSettings>[0]) => {
+        updateSettings(changes).catch(err => {
+            message.error(t('Errors.save_file_failed_colon') + err);
+        });
+    }
+
     /** 切换选项 */
     const toggleForcePng = (value: boolean) => {
         setForcePng(value);
-        localStorage.setItem(TexEditorForcePngKey, JSON.stringify(value));
+        saveSettings({TexForcePng: value});
     };
 
     const toggleCompress = (value: boolean) => {
         setCompress(value);
-        localStorage.setItem(TexEditorCompressKey, JSON.stringify(value));
+        saveSettings({TexCompress: value});
     };
 
     const toggleDirectConvert = (value: boolean) => {
         setDirectConvert(value);
-        localStorage.setItem(TexEditorDirectConvertKey, JSON.stringify(value));
+        saveSettings({TexDirectConvert: value});
+    }
+
+    const changeDefaultFormat = (value: string) => {
+        const format = value.replace(/[^a-zA-Z.0-9]/g, '').toLowerCase();
+        setDefaultFormat(format);
+        // 空的格式无效，不保存
+        if (format.replace(/^\.+/, '')) {
+            saveSettings({TexDefaultFormat: format.replace(/^\.+/, '')});
+        }
     }
 
     useEffect(() => {
@@ -214,7 +214,7 @@ const TexEditor = forwardRef<TexEditorRef, TexEditorProps>((props, ref) => {
                             addonBefore={t('TexEditor.default_format')}
                             defaultValue=".png"
                             value={defaultFormat}
-                            onChange={e => setDefaultFormat(e.target.value.replace(/[^a-zA-Z.0-9]/g, '').toLowerCase())}
+                            onChange={e => changeDefaultFormat(e.target.value)}
                             style={{width: '180px'}}
                         />
                     </Tooltip>
@@ -7,7 +7,7 @@ import Style3MateProperties from "../mate/Style3MateProperties";
 import Style2MateProperties from "../mate/Style2MateProperties";
 import Style1MateProperties from "../mate/Style1MateProperties";
 import Style1MatePropertiesVirtualized from "../mate/Style1MatePropertiesVirtualized";
-import {MateEditorViewModeKey} from "../../utils/LocalStorageKeys";
+import {getEditorViewMode, setEditorViewMode} from "../../utils/Settings";
 import {QuestionCircleOutlined} from "@ant-design/icons";
 import Mate = COM3D2.Mate;
 import Material = COM3D2.Material;
@@ -48,10 +48,7 @@ const MaterialEditor = forwardRef<MaterialEditorRef, MaterialEditorProps>((props
     const [form] = Form.useForm();
 
     // 用来切换视图模式
-    const [viewMode, setViewMode] = useState<1 | 2 | 3>(() => {
-        const saved = localStorage.getItem(MateEditorViewModeKey);
-        return saved ? Number(saved) as 1 | 2 | 3 : 1;
-    });
+    const [viewMode, setViewMode] = useState<1 | 2 | 3>(() => getEditorViewMode<1 | 2 | 3>("mate", 1));
 
     // 当外部 material 变化时，更新表单
     useEffect(() => {
@@ -484,7 +481,7 @@ const MaterialEditor = forwardRef<MaterialEditorRef, MaterialEditorProps>((props
                                 }
 
                                 setViewMode(e.target.value);
-                                localStorage.setItem(MateEditorViewModeKey, e.target.value.toString());
+                                setEditorViewMode("mate", e.target.value);
                             }}
                             options={[
                                 {label: t('MateEditor.style1'), value: 1},
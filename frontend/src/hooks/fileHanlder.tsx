@@ -6,50 +6,36 @@ import {message} from "antd";
 import React, {useState} from "react";
 import {ConvertAnyToAnyAndWrite} from "../../wailsjs/go/COM3D2/TexService";
 import {Quit} from "../../wailsjs/runtime";
-import {
-    FileTypeStrictModeKey,
-    TexEditorCompressKey,
-    TexEditorDefaultFormatKey,
-    TexEditorDirectConvertKey,
-    TexEditorForcePngKey
-} from "../utils/LocalStorageKeys";
+import {getSettings, updateSettings} from "../utils/Settings";
 import {FileTypeDetermine} from "../../wailsjs/go/COM3D2/CommonService";
 import {AllSupportedFileTypesSet} from "../utils/consts";
+import {addOpenedFile, editorRoute} from "../utils/OpenedFiles";
 import {COM3D2} from "../../wailsjs/go/models";
 import FileInfo = COM3D2.FileInfo;
 
+// OpenedFile 对应后端的 main.OpenedFile，随 files-opened、files-forwarded 和 files-dropped 事件发送
+// 后端已经按设置中的严格模式识别了文件类型，Info 为识别结果，识别失败时 Error 不为空
+export interface OpenedFile {
+    Path: string;
+    FileType: string;
+    Info: FileInfo;
+    Error: string;
+}
+
 const useFileHandlers = () => {
     const {t} = useTranslation();
     const navigate = useNavigate();
 
     // 文件类型判断的严格模式设置
-    const [strictMode, setStrictMode] = useState<boolean>(() => {
-        const saved = localStorage.getItem(FileTypeStrictModeKey);
-        return saved ? JSON.parse(saved) : false;
-    });
-
-    // TexEditor 相关持久化选项
-    const [forcePng] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorForcePngKey);
-        return saved ? JSON.parse(saved) : true;
-    });
-    const [directConvert] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorDirectConvertKey);
-        return saved ? JSON.parse(saved) : false;
-    });
-    const [compress] = useState<boolean>(() => {
-        const saved = localStorage.getItem(TexEditorCompressKey);
-        return saved ? JSON.parse(saved) : false;
-    });
-    const [defaultFormat] = useState<string>(() => {
-        const saved = localStorage.getItem(TexEditorDefaultFormatKey);
-        return saved ? JSON.parse(saved) : ".png";
-    })
+    const [strictMode, setStrictMode] = useState<boolean>(() => getSettings().FileTypeStrictMode);
 
     // 更新严格模式设置
     const updateStrictMode = (newStrictMode: boolean) => {
-        setStrictMode(newStrictMode);
-        localStorage.setItem(FileTypeStrictModeKey, JSON.stringify(newStrictMode));
+        updateSettings({FileTypeStrictMode: newStrictMode}).then(() => {
+            setStrictMode(newStrictMode);
+        }).catch(err => {
+            message.error(t('Errors.save_file_failed_colon') + err);
+        });
     }
 
 
@@ -70,6 +56,25 @@ const useFileHandlers = () => {
         await fileNavigateHandler(filePath)
     }
 
+    // handleOpenedFiles 依次打开后端已识别类型的多个文件（启动参数、其他实例转发、拖放），停留在最后一个打开的文件上
+    // quitAfterConvert 只在处理本进程的启动参数时为 true：直接转换模式下全部文件都转换完成后退出
+    // 转发和拖放的文件转换后程序继续运行，不能退出
+    const handleOpenedFiles = async (files: OpenedFile[], quitAfterConvert: boolean) => {
+        let allConverted = files.length > 0;
+        for (const file of files) {
+            let converted = false;
+            if (file.Error) {
+                message.error(t('Errors.read_file_failed_colon') + file.Path + ' ' + file.Error);
+            } else {
+                converted = await openFileInfo(FileInfo.createFrom(file.Info));
+            }
+            allConverted = allConverted && converted;
+        }
+        if (quitAfterConvert && allConverted) {
+            Quit(); // 退出程序
+        }
+    }
+
     // handleSaveFile 保存文件，如果有 ref，则调用 ref.current.handleSaveFile()，否则提示没有文件要保存
     const handleSaveFile = (ref: React.RefObject<any> | undefined) => {
         if (ref) {
@@ -98,52 +103,54 @@ const useFileHandlers = () => {
         message.warning(t('Errors.no_file_to_save'));
     }
 
-    const fileNavigateHandler = async (filePath: string) => {
-        try {
-            // 判断文件类型
-            const fileInfo = await FileTypeDetermine(filePath, strictMode);
-
-            // 如果是图片类型
-            if (fileInfo.FileType === "image") {
-                if (directConvert) {
-                    await exportTexOrImageAsAny(filePath, filePath.replace(/\.[^.]+$/, ".tex"));
-                    Quit(); // 退出程序
-                } else {
-                    navigate("/tex-editor", {state: {fileInfo}});
-                }
-                return;
-            }
+    // openFileInfo 打开已识别类型的文件：设置了直接转换时转换 tex 或图片，否则加入打开的文件列表并转跳到对应编辑器
+    // 直接转换了文件时返回 true
+    const openFileInfo = async (fileInfo: FileInfo): Promise<boolean> => {
+        // TexEditor 中修改的选项立即生效，因此每次都读取设置
+        const {TexDirectConvert: directConvert, TexDefaultFormat} = getSettings();
 
-            // 如果是支持的文件类型
-            if (fileInfo.FileType && AllSupportedFileTypesSet.has(fileInfo.FileType)) {
-                // 如果是 tex 文件且设置了直接转换
-                if (fileInfo.FileType === "tex" && directConvert) {
-                    await exportTexOrImageAsAny(filePath, filePath.replace(".tex", defaultFormat));
-                    Quit(); // 退出程序
-                    return;
-                }
-
-                // 否则跳转到相应编辑器，传递 fileInfo 而不是仅仅传递 filePath
-                navigate(`/${fileInfo.FileType}-editor`, {state: {fileInfo}});
-                return;
+        if (fileInfo.FileType === "image") {
+            if (directConvert) {
+                await exportTexOrImageAsAny(fileInfo.Path, fileInfo.Path.replace(/\.[^.]+$/, ".tex"));
+                return true;
             }
-
+        } else if (!fileInfo.FileType || !AllSupportedFileTypesSet.has(fileInfo.FileType)) {
             // 如果无法识别文件类型
-            message.error(t('Errors.file_type_not_supported'));
+            message.error(t('Errors.file_type_not_supported') + ' ' + fileInfo.Path);
+            return false;
+        } else if (fileInfo.FileType === "tex" && directConvert) {
+            await exportTexOrImageAsAny(fileInfo.Path, fileInfo.Path.replace(".tex", "." + TexDefaultFormat));
+            return true;
+        }
+
+        // 传递 fileInfo 而不是仅仅传递 filePath
+        addOpenedFile(fileInfo);
+        navigate(editorRoute(fileInfo), {state: {fileInfo}});
+        return false;
+    }
+
+    // fileNavigateHandler 判断通过对话框选择的文件的类型并转跳到对应页面，直接转换了文件时返回 true
+    const fileNavigateHandler = async (filePath: string): Promise<boolean> => {
+        let fileInfo: FileInfo;
+        try {
+            // 判断文件类型
+            fileInfo = await FileTypeDetermine(filePath, strictMode);
         } catch (err) {
             console.error("Error determining file type:", err);
 
             // 如果是严格模式，不继续处理错误
             if (strictMode) {
                 message.error(t('Errors.read_file_failed_colon') + ' ' + err);
-                return;
+                return false;
             }
 
-            let fileInfo = new FileInfo();
+            fileInfo = new FileInfo();
             fileInfo.Path = filePath;
             fileInfo.Game = "COM3D2";
             if (filePath.endsWith(".json")) {
                 fileInfo.StorageFormat = "json";
+            } else if (/\.ya?ml$/.test(filePath)) {
+                fileInfo.StorageFormat = "yaml";
             } else {
                 fileInfo.StorageFormat = "binary";
             }
@@ -158,36 +165,29 @@ const useFileHandlers = () => {
             const extension = getFileExtension(filePath);
             if (AllSupportedFileTypesSet.has(extension)) {
                 fileInfo.FileType = extension;
-                navigate(`/${extension}-editor`, {state: {fileInfo}});
-                return;
-            }
-
-            // 判断是否为图片
-            let isSupportedImage = false;
-            try {
-                isSupportedImage = await IsSupportedImageType(filePath);
-            } catch (imgErr: any) {
-                message.error(t('Errors.file_type_not_supported') + ' ' + imgErr);
-            }
-
-            if (isSupportedImage) {
-                fileInfo.FileType = "image";
-                if (directConvert) {
-                    await exportTexOrImageAsAny(filePath, filePath.replace(/\.[^.]+$/, ".tex"));
-                    Quit(); // 退出程序
-                } else {
-                    navigate("/tex-editor", {state: {fileInfo}});
-                }
             } else {
-                message.error(t('Errors.file_type_not_supported') + ' ' + err);
+                // 判断是否为图片
+                let isSupportedImage = false;
+                try {
+                    isSupportedImage = await IsSupportedImageType(filePath);
+                } catch (imgErr: any) {
+                    message.error(t('Errors.file_type_not_supported') + ' ' + imgErr);
+                }
+                if (!isSupportedImage) {
+                    message.error(t('Errors.file_type_not_supported') + ' ' + err);
+                    return false;
+                }
+                fileInfo.FileType = "image";
             }
         }
+        return openFileInfo(fileInfo);
     }
 
     // exportTexOrImageAsAny 导出 tex 或图片为任意格式
     const exportTexOrImageAsAny = async (filePath: string, outputPath: string) => {
         if (!outputPath) return;
 
+        const {TexCompress: compress, TexForcePng: forcePng} = getSettings();
         try {
             await ConvertAnyToAnyAndWrite(filePath, "", compress, forcePng, outputPath)
             message.success(t('Infos.success_export_file_colon') + outputPath);
@@ -201,6 +201,7 @@ const useFileHandlers = () => {
     return {
         handleSelectFile,
         handleOpenedFile,
+        handleOpenedFiles,
         handleSaveFile,
         handleSaveAsFile,
         exportTexOrImageAsAny,
@@ -3,13 +3,17 @@ import {createRoot} from 'react-dom/client'
 import './style.css'
 import './utils/i18n';
 import RouterContainer from "./RouterContainer";
+import {loadSettings} from "./utils/Settings";
 
 const container = document.getElementById('root')
 
 const root = createRoot(container!)
 
-root.render(
-    <React.StrictMode>
-        <RouterContainer/>
-    </React.StrictMode>
-)
+// 先读取设置，组件初始化时同步读取
+loadSettings().catch(err => console.error("Error loading settings:", err)).finally(() => {
+    root.render(
+        <React.StrictMode>
+            <RouterContainer/>
+        </React.StrictMode>
+    )
+})
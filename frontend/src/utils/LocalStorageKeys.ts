@@ -12,6 +12,9 @@ export const DisclaimerAgreedKey = "DisclaimerAgreedKey"; // 存储免责声明
 
 export const KeyframeEditorCanvasSizeKey = "KeyframeEditorCanvasSize"; // 存储画布大小的键
 
+export const SettingsMigratedKey = "SettingsMigrated"; // 存储旧设置是否已经迁移到后端的键，见 Settings.ts
+
+// 以下的键只用于从旧版本迁移设置，现在这些设置保存在后端，见 Settings.ts
 export const FileTypeStrictModeKey = "FileTypeStrictMode"; // 存储文件类型判断的严格模式设置
 
 // MenuEditor
@@ -0,0 +1,41 @@
+// 本次运行中打开过的文件，编辑器一次只显示一个文件，通过导航栏的列表切换
+import {useSyncExternalStore} from "react";
+import {COM3D2} from "../../wailsjs/go/models";
+import FileInfo = COM3D2.FileInfo;
+
+let openedFiles: FileInfo[] = [];
+const listeners = new Set<() => void>();
+
+const notify = () => listeners.forEach(listener => listener());
+
+const subscribe = (listener: () => void) => {
+    listeners.add(listener);
+    return () => {
+        listeners.delete(listener);
+    };
+}
+
+// editorRoute 返回打开该文件的编辑器页面，图片在 Tex 编辑器中打开
+export const editorRoute = (fileInfo: FileInfo) => {
+    return fileInfo.FileType === "image" ? "/tex-editor" : `/${fileInfo.FileType}-editor`;
+}
+
+// addOpenedFile 添加到列表末尾，已经在列表中时更新文件信息并保留原来的位置
+export const addOpenedFile = (fileInfo: FileInfo) => {
+    const index = openedFiles.findIndex(f => f.Path === fileInfo.Path);
+    if (index >= 0) {
+        openedFiles = openedFiles.map((f, i) => i === index ? fileInfo : f);
+    } else {
+        openedFiles = [...openedFiles, fileInfo];
+    }
+    notify();
+}
+
+// clearOpenedFiles 清空列表，不影响当前显示的文件
+export const clearOpenedFiles = () => {
+    openedFiles = [];
+    notify();
+}
+
+// useOpenedFiles 订阅打开的文件列表
+export const useOpenedFiles = () => useSyncExternalStore(subscribe, () => openedFiles);
@@ -0,0 +1,132 @@
+// 设置保存在后端的 settings.json（见 SettingsService），GUI 和命令行共用
+// 前端在启动时读取一次并缓存，组件同步读取缓存，修改时同时写回后端
+// 旧版本保存在 localStorage 中的设置在第一次启动时迁移到后端，迁移成功后从 localStorage 删除
+import {GetSettings, SetSettings} from "../../wailsjs/go/COM3D2/SettingsService";
+import {COM3D2} from "../../wailsjs/go/models";
+import {
+    colEditorViewModeKey,
+    FileTypeStrictModeKey,
+    MateEditorViewModeKey,
+    MenuEditorViewModeKey,
+    ModelEditorViewModeKey,
+    PhyEditorViewModeKey,
+    PskEditorViewModeKey,
+    SettingsMigratedKey,
+    TexEditorCompressKey,
+    TexEditorDefaultFormatKey,
+    TexEditorDirectConvertKey,
+    TexEditorForcePngKey
+} from "./LocalStorageKeys";
+import Settings = COM3D2.Settings;
+
+// 旧版本各编辑器保存显示方式的键 → EditorViewModes 中的文件类型
+const legacyViewModeKeys: Record<string, string> = {
+    [MenuEditorViewModeKey]: "menu",
+    [MateEditorViewModeKey]: "mate",
+    [colEditorViewModeKey]: "col",
+    [PhyEditorViewModeKey]: "phy",
+    [PskEditorViewModeKey]: "psk",
+    [ModelEditorViewModeKey]: "model",
+};
+
+// 旧版本以 JSON 保存的布尔值设置 → Settings 中的字段
+const legacyBooleanKeys: Record<string, "FileTypeStrictMode" | "TexForcePng" | "TexDirectConvert" | "TexCompress"> = {
+    [FileTypeStrictModeKey]: "FileTypeStrictMode",
+    [TexEditorForcePngKey]: "TexForcePng",
+    [TexEditorDirectConvertKey]: "TexDirectConvert",
+    [TexEditorCompressKey]: "TexCompress",
+};
+
+// 后端读取失败时使用的设置，与后端的 defaultSettings 一致
+let cachedSettings: Settings = Settings.createFrom({
+    FileTypeStrictMode: false,
+    TexForcePng: true,
+    TexDirectConvert: false,
+    TexCompress: false,
+    TexDefaultFormat: "png",
+    EditorViewModes: {},
+    ArchiveOutputDir: "",
+    BackupCount: 0,
+    Json: {Indent: 0, SortKeys: false, FloatPrecision: 0},
+    RecentFilesLimit: 20,
+});
+
+// loadSettings 迁移旧设置并读取后端的设置，应用启动时在渲染之前调用一次
+export const loadSettings = async () => {
+    try {
+        await migrateLocalStorageSettings();
+    } catch (err) {
+        // 迁移失败时保留 localStorage 中的设置，下次启动再试
+        console.error("Error migrating settings from localStorage:", err);
+    }
+    cachedSettings = await GetSettings();
+}
+
+// getSettings 返回缓存的设置，调用方不应修改返回值
+export const getSettings = (): Settings => cachedSettings;
+
+// updateSettings 修改部分设置并写回后端，写入失败时缓存不变
+export const updateSettings = async (changes: Partial<Settings>) => {
+    const next = Settings.createFrom({...cachedSettings, ...changes});
+    await SetSettings(next);
+    cachedSettings = next;
+}
+
+// getEditorViewMode 返回编辑器上次使用的显示方式，没有保存过时返回 fallback
+export const getEditorViewMode = <T extends string | number>(fileType: string, fallback: T): T => {
+    const saved = cachedSettings.EditorViewModes?.[fileType];
+    if (!saved) {
+        return fallback;
+    }
+    return (typeof fallback === "number" ? Number(saved) : saved) as T;
+}
+
+// setEditorViewMode 保存编辑器的显示方式
+export const setEditorViewMode = (fileType: string, viewMode: string | number) => {
+    updateSettings({
+        EditorViewModes: {...cachedSettings.EditorViewModes, [fileType]: viewMode.toString()}
+    }).catch(err => console.error("Error saving settings:", err));
+}
+
+// migrateLocalStorageSettings 将旧版本保存在 localStorage 中的设置写入后端，只执行一次
+const migrateLocalStorageSettings = async () => {
+    if (localStorage.getItem(SettingsMigratedKey) === 'true') {
+        return;
+    }
+
+    const settings = await GetSettings();
+    const migratedKeys: string[] = [];
+    for (const [key, field] of Object.entries(legacyBooleanKeys)) {
+        const saved = localStorage.getItem(key);
+        if (saved !== null) {
+            settings[field] = JSON.parse(saved) === true;
+            migratedKeys.push(key);
+        }
+    }
+    const defaultFormat = localStorage.getItem(TexEditorDefaultFormatKey);
+    if (defaultFormat !== null) {
+        // 旧版本保存的格式带点，例如 ".png"
+        const format = String(JSON.parse(defaultFormat)).replace(/^\.+/, '');
+        if (format) {
+            settings.TexDefaultFormat = format;
+        }
+        migratedKeys.push(TexEditorDefaultFormatKey);
+    }
+    const viewModes = {...settings.EditorViewModes};
+    for (const [key, fileType] of Object.entries(legacyViewModeKeys)) {
+        const saved = localStorage.getItem(key);
+        if (saved !== null) {
+            viewModes[fileType] = saved;
+            migratedKeys.push(key);
+        }
+    }
+    settings.EditorViewModes = viewModes;
+
+    if (migratedKeys.length > 0) {
+        await SetSettings(settings);
+    }
+    for (const key of migratedKeys) {
+        localStorage.removeItem(key);
+    }
+    localStorage.setItem(SettingsMigratedKey, 'true');
+}
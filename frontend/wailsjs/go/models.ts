@@ -541,6 +541,22 @@ export namespace COM3D2 {
 	    }
 	}
 	
+	export class JsonOptions {
+	    Indent: number;
+	    SortKeys: boolean;
+	    FloatPrecision: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new JsonOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Indent = source["Indent"];
+	        this.SortKeys = source["SortKeys"];
+	        this.FloatPrecision = source["FloatPrecision"];
+	    }
+	}
 	export class Keyword {
 	    Key: string;
 	    Value: boolean;
@@ -1261,6 +1277,89 @@ export namespace COM3D2 {
 	        this.Number = source["Number"];
 	    }
 	}
+	export class RecentFile {
+	    Path: string;
+	    FileType: string;
+	    // Go type: time
+	    OpenedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecentFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.Path = source["Path"];
+	        this.FileType = source["FileType"];
+	        this.OpenedAt = this.convertValues(source["OpenedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Settings {
+	    FileTypeStrictMode: boolean;
+	    TexForcePng: boolean;
+	    TexDirectConvert: boolean;
+	    TexCompress: boolean;
+	    TexDefaultFormat: string;
+	    EditorViewModes: Record<string, string>;
+	    ArchiveOutputDir: string;
+	    BackupCount: number;
+	    Json: JsonOptions;
+	    RecentFilesLimit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.FileTypeStrictMode = source["FileTypeStrictMode"];
+	        this.TexForcePng = source["TexForcePng"];
+	        this.TexDirectConvert = source["TexDirectConvert"];
+	        this.TexCompress = source["TexCompress"];
+	        this.TexDefaultFormat = source["TexDefaultFormat"];
+	        this.EditorViewModes = source["EditorViewModes"];
+	        this.ArchiveOutputDir = source["ArchiveOutputDir"];
+	        this.BackupCount = source["BackupCount"];
+	        this.Json = this.convertValues(source["Json"], JsonOptions);
+	        this.RecentFilesLimit = source["RecentFilesLimit"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	
 	export class Tex {
 	    Signature: string;
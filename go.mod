@@ -7,6 +7,7 @@ require (
 	github.com/MeidoPromotionAssociation/MeidoSerialization v1.0.5
 	github.com/emmansun/base64 v0.7.0
 	github.com/wailsapp/wails/v2 v2.10.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// 单实例：第一个启动的实例监听本地 socket，之后启动的实例把要打开的文件转发给它然后退出
+// Windows 10 1803 之后同样支持 unix socket
+
+// instanceDialTimeout 连接已运行实例的超时时间
+const instanceDialTimeout = time.Second
+
+// instanceMessage 转发给已运行实例的消息
+type instanceMessage struct {
+	Paths []string `json:"Paths"`
+}
+
+// instanceSocketName socket 文件名，所在目录已经区分用户
+const instanceSocketName = "COM3D2_MOD_EDITOR_V2.sock"
+
+// instanceSocketPath 每个用户一个 socket，放在只有当前用户可以访问的目录中
+// 不能放在所有用户共用的临时目录，否则其他用户可以抢先创建同名 socket 接收转发的文件
+func instanceSocketPath() (string, error) {
+	dir, err := instanceSocketDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, instanceSocketName), nil
+}
+
+// instanceSocketDir 优先使用 $XDG_RUNTIME_DIR，没有设置或权限不安全时使用用户缓存目录下权限为 0700 的子目录
+// Windows 上没有 $XDG_RUNTIME_DIR 的约定，总是使用用户缓存目录
+func instanceSocketDir() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && runtime.GOOS != "windows" && checkPrivateDir(dir) == nil {
+		return dir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find a directory for the instance socket: %w", err)
+	}
+	dir := filepath.Join(cacheDir, "COM3D2_MOD_EDITOR_V2")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("cannot create %s: %w", dir, err)
+	}
+	// 旧版本或其他程序可能以更宽的权限创建了目录，属于当前用户时收紧权限
+	os.Chmod(dir, 0700)
+	if err := checkPrivateDir(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// dialInstance 连接已运行实例的 socket，连接前检查 socket 属于当前用户
+func dialInstance(socketPath string) (net.Conn, error) {
+	if err := checkSocketOwner(socketPath); err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", socketPath, instanceDialTimeout)
+}
+
+// forwardToRunningInstance 尝试将文件转发给已运行的实例，成功时返回 true，调用方应直接退出
+// 路径会转换为绝对路径，因为两个实例的工作目录可能不同
+func forwardToRunningInstance(paths []string) bool {
+	socketPath, err := instanceSocketPath()
+	if err != nil {
+		return false
+	}
+	conn, err := dialInstance(socketPath)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		absPaths = append(absPaths, path)
+	}
+	conn.SetDeadline(time.Now().Add(instanceDialTimeout))
+	if err := json.NewEncoder(conn).Encode(instanceMessage{Paths: absPaths}); err != nil {
+		return false
+	}
+	// 等待对方确认收到，避免对方还没读取就退出
+	var ack [1]byte
+	_, err = conn.Read(ack[:])
+	return err == nil
+}
+
+// listenForInstances 监听之后启动的实例，收到的文件交给 onOpen
+// 返回的 listener 需要在程序退出时关闭，关闭时会删除 socket 文件
+func listenForInstances(onOpen func(paths []string)) (net.Listener, error) {
+	socketPath, err := instanceSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	// 先尝试连接，能连接上说明有实例在运行（例如两个实例同时启动），不能删除它的 socket
+	// 连接不上才是上次异常退出留下的 socket 文件，删除后重新监听
+	if conn, err := dialInstance(socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another instance is already listening on %s", socketPath)
+	}
+	os.Remove(socketPath)
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %w", socketPath, err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(instanceDialTimeout))
+				var message instanceMessage
+				if err := json.NewDecoder(conn).Decode(&message); err != nil {
+					return
+				}
+				conn.Write([]byte{1})
+				onOpen(message.Paths)
+			}(conn)
+		}
+	}()
+	return listener, nil
+}
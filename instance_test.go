@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// isolateInstance socket 放到临时目录，不影响正在运行的编辑器
+func isolateInstance(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	// 与 $XDG_RUNTIME_DIR 的约定一致，只有当前用户可以访问
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("LocalAppData", t.TempDir())
+	return dir
+}
+
+// listenForTest 监听转发的文件，收到的文件发送到返回的通道
+func listenForTest(t *testing.T) (net.Listener, chan []string) {
+	t.Helper()
+	received := make(chan []string, 1)
+	listener, err := listenForInstances(func(paths []string) { received <- paths })
+	if err != nil {
+		t.Fatalf("listenForInstances: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return listener, received
+}
+
+func TestForwardToRunningInstance(t *testing.T) {
+	isolateInstance(t)
+	_, received := listenForTest(t)
+
+	// 相对路径转换为绝对路径，因为两个实例的工作目录可能不同
+	abs, err := filepath.Abs("item.menu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(t.TempDir(), "item.tex")
+	if !forwardToRunningInstance([]string{"item.menu", other}) {
+		t.Fatal("forwardToRunningInstance returned false")
+	}
+	select {
+	case paths := <-received:
+		if want := []string{abs, other}; !reflect.DeepEqual(paths, want) {
+			t.Errorf("paths = %v, want %v", paths, want)
+		}
+	case <-time.After(instanceDialTimeout):
+		t.Fatal("the running instance did not receive the paths")
+	}
+
+	// 没有文件时也转发，已运行的实例只切换窗口
+	if !forwardToRunningInstance(nil) {
+		t.Error("forwarding without files returned false")
+	}
+	if paths := <-received; len(paths) != 0 {
+		t.Errorf("paths = %v", paths)
+	}
+}
+
+func TestForwardWithoutRunningInstance(t *testing.T) {
+	isolateInstance(t)
+	if forwardToRunningInstance([]string{"item.menu"}) {
+		t.Error("forwardToRunningInstance returned true without a running instance")
+	}
+}
+
+// 已有实例在监听时不能删除它的 socket，关闭后可以重新监听
+func TestListenForInstancesOnce(t *testing.T) {
+	isolateInstance(t)
+	listener, _ := listenForTest(t)
+	if second, err := listenForInstances(func([]string) {}); err == nil {
+		second.Close()
+		t.Fatal("a second instance listened on the same socket")
+	}
+	if !forwardToRunningInstance(nil) {
+		t.Error("the first instance stopped listening")
+	}
+
+	listener.Close()
+	listenForTest(t)
+}
+
+// 上次异常退出留下的 socket 文件连接不上，删除后重新监听
+func TestListenForInstancesStaleSocket(t *testing.T) {
+	isolateInstance(t)
+	socketPath, err := instanceSocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	if _, err := os.Lstat(socketPath); err != nil {
+		t.Fatalf("stale socket: %v", err)
+	}
+
+	_, received := listenForTest(t)
+	if !forwardToRunningInstance([]string{socketPath}) {
+		t.Fatal("forwardToRunningInstance returned false")
+	}
+	if paths := <-received; !reflect.DeepEqual(paths, []string{socketPath}) {
+		t.Errorf("paths = %v", paths)
+	}
+}
+
+func TestStartupFilePaths(t *testing.T) {
+	got := startupFilePaths([]string{"--debug", "a.menu", "", "-x", "b.tex"})
+	if want := []string{"a.menu", "b.tex"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("startupFilePaths = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPrivateDir 检查目录属于当前用户，并且其他用户没有任何权限
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := checkOwner(dir, info); err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s is accessible by other users (mode %v)", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// checkSocketOwner 检查 path 是属于当前用户的 socket，避免把文件路径发给其他用户的程序
+func checkSocketOwner(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%s is not a socket", path)
+	}
+	return checkOwner(path, info)
+}
+
+// checkOwner 检查文件的所有者是当前用户
+func checkOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot get the owner of %s", path)
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not the current user", path, stat.Uid)
+	}
+	return nil
+}
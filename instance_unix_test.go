@@ -0,0 +1,116 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstanceSocketInRuntimeDir(t *testing.T) {
+	dir := isolateInstance(t)
+	got, err := instanceSocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, instanceSocketName); got != want {
+		t.Errorf("instanceSocketPath = %q, want %q", got, want)
+	}
+}
+
+// $XDG_RUNTIME_DIR 其他用户可以访问或没有设置时使用缓存目录下只有当前用户可以访问的目录
+func TestInstanceSocketFallsBackToCacheDir(t *testing.T) {
+	runtimeDir := isolateInstance(t)
+	if err := os.Chmod(runtimeDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+
+	// 已经存在的目录权限过宽时收紧
+	want := filepath.Join(cacheDir, "COM3D2_MOD_EDITOR_V2")
+	if err := os.Mkdir(want, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, runtimeDir := range []string{runtimeDir, ""} {
+		t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+		got, err := instanceSocketDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("XDG_RUNTIME_DIR=%q: instanceSocketDir = %q, want %q", runtimeDir, got, want)
+		}
+		if info, err := os.Stat(got); err != nil || info.Mode().Perm() != 0700 {
+			t.Errorf("mode = %v, %v", info.Mode().Perm(), err)
+		}
+	}
+}
+
+func TestCheckPrivateDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivateDir(dir); err != nil {
+		t.Errorf("checkPrivateDir(0700) = %v", err)
+	}
+	if err := os.Chmod(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivateDir(dir); err == nil {
+		t.Error("checkPrivateDir accepted a directory readable by the group")
+	}
+
+	// 符号链接指向的目录可能属于其他用户，不跟随
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPrivateDir(link); err == nil {
+		t.Error("checkPrivateDir accepted a symlink")
+	}
+}
+
+// 只连接 socket，不把路径写给同名的普通文件
+func TestCheckSocketOwner(t *testing.T) {
+	isolateInstance(t)
+	socketPath, err := instanceSocketPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(socketPath, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketOwner(socketPath); err == nil {
+		t.Error("checkSocketOwner accepted a regular file")
+	}
+	if forwardToRunningInstance(nil) {
+		t.Error("forwarded to a regular file")
+	}
+
+	listener, _ := listenForTest(t)
+	if err := checkSocketOwner(listener.Addr().String()); err != nil {
+		t.Errorf("checkSocketOwner = %v", err)
+	}
+}
+
+// 其他用户创建的 socket 不连接，需要 root 才能修改所有者
+func TestCheckSocketOwnerOtherUser(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+	isolateInstance(t)
+	listener, _ := listenForTest(t)
+	socketPath := listener.Addr().String()
+	if err := os.Chown(socketPath, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSocketOwner(socketPath); err == nil {
+		t.Error("checkSocketOwner accepted a socket owned by another user")
+	}
+	if forwardToRunningInstance(nil) {
+		t.Error("forwarded to a socket owned by another user")
+	}
+}
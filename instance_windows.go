@@ -0,0 +1,26 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkPrivateDir Windows 上使用用户缓存目录（%LocalAppData%），其权限已经只允许当前用户访问，只检查是目录
+func checkPrivateDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+// checkSocketOwner Windows 上 socket 所在的目录只有当前用户可以访问，只检查文件存在
+func checkSocketOwner(path string) error {
+	_, err := os.Lstat(path)
+	return err
+}
@@ -0,0 +1,801 @@
+package cli
+
+import (
+	"COM3D2_MOD_EDITOR_V2/internal/service/COM3D2"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 退出码，供构建脚本判断执行结果
+const (
+	ExitOK          = 0 // 成功
+	ExitFailure     = 1 // 读取、解析或写出失败
+	ExitUsage       = 2 // 参数错误
+	ExitUnsupported = 3 // 不支持的文件类型或转换方向
+)
+
+// errUnsupported 表示文件类型无法处理，对应 ExitUnsupported
+var errUnsupported = COM3D2.ErrUnsupportedFileType
+
+// errUsage 表示参数错误，对应 ExitUsage
+var errUsage = errors.New("invalid arguments")
+
+// command 描述一个子命令
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string, stdout io.Writer, stderr io.Writer) error
+}
+
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{}
+	for _, c := range []*command{
+		{name: "info", usage: "info [--strict] <file>...", summary: "print the detected file type, signature and version as JSON", run: runInfo},
+		{name: "to-json", usage: "to-json [--strict] [--yaml] [json options] <input> [output]", summary: "convert a binary file to .json, or .yaml with --yaml or a .yaml output", run: runToJson},
+		{name: "from-json", usage: "from-json <input> [output]", summary: "convert a .json or .yaml file back to binary", run: runFromJson},
+		{name: "batch", usage: "batch [--from-json] [--strict] [--yaml] [--workers <n>] [--output <dir>] [json options] <dir>", summary: "convert every recognised file in a directory tree", run: runBatch},
+		{name: "deps", usage: "deps [--json] [--search <dir>]... <dir>", summary: "report missing references, unused files and name collisions in a mod folder", run: runDeps},
+		{name: "verify", usage: "verify [--json] <file or dir>...", summary: "check that files re-save byte-identically, directly and through JSON", run: runVerify},
+		{name: "schema", usage: "schema <type> | schema --out <dir>", summary: "print the JSON Schema of a .json format, or write all schemas and a VS Code mapping", run: runSchema},
+		{name: "diff", usage: "diff [--json] <old> <new>", summary: "show the semantic differences between two files of the same format", run: runDiff},
+		{name: "merge", usage: "merge [--output <file>] <base> <ours> <theirs>", summary: "three-way merge two edited versions of a menu, mate, pmat, phy, psk or col file", run: runMerge},
+		{name: "patch", usage: "patch [--output <file>] <file> <patch.json | ->", summary: "apply an RFC 6902 JSON Patch to a file, in place unless --output is given", run: runPatch},
+		{name: "settings", usage: "settings", summary: "print the settings file location and the saved settings shared with the GUI", run: runSettings},
+		{name: "lint", usage: "lint [--json] [--config <file>] [--rule <name>=<severity>]... <file or dir>...", summary: "check files for problems the game cares about, fails when any error is found", run: runLint},
+		{name: "save-items", usage: "save-items <file.save>", summary: "list the .menu files equipped by the maids in a save", run: runSaveItems},
+		{name: "save-presets", usage: "save-presets <file.save> <output dir>", summary: "export every maid in a save as a .preset file", run: runSavePresets},
+		{name: "tex2img", usage: "tex2img [--force-png] <input.tex> [output]", summary: "convert a .tex file to an image (requires ImageMagick)", run: runTexToImage},
+		{name: "img2tex", usage: "img2tex [--compress] [--force-png] [--name <texName>] <input> [output.tex]", summary: "convert an image to a .tex file (requires ImageMagick)", run: runImageToTex},
+	} {
+		commands[c.name] = c
+	}
+}
+
+// IsCommand 判断命令行参数是否为子命令调用，是则应以无界面模式运行，不启动 GUI
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "--help", "-h":
+		return true
+	}
+	_, ok := commands[args[0]]
+	return ok
+}
+
+// Run 执行子命令并返回退出码
+func Run(args []string) int {
+	attachConsole()
+	return runCommand(args, os.Stdout, os.Stderr)
+}
+
+// runCommand 执行子命令，结果写到 stdout，警告和错误写到 stderr
+func runCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	// 参数的默认值来自设置文件，与 GUI 共用
+	if err := COM3D2.LoadSettings(); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	err := c.run(args[1:], stdout, stderr)
+	if err == nil {
+		return ExitOK
+	}
+
+	fmt.Fprintf(stderr, "%s: %v\n", c.name, err)
+	var parseErr *COM3D2.ParseError
+	if errors.As(err, &parseErr) {
+		printParseError(stderr, parseErr)
+	}
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "usage: %s\n", c.usage)
+		return ExitUsage
+	case errors.Is(err, errUnsupported):
+		return ExitUnsupported
+	default:
+		return ExitFailure
+	}
+}
+
+// printParseError 分行输出解析错误的位置
+func printParseError(w io.Writer, err *COM3D2.ParseError) {
+	if err.Path != "" {
+		fmt.Fprintf(w, "  file:      %s (%d bytes)\n", err.Path, err.Size)
+	}
+	if err.Signature != "" {
+		fmt.Fprintf(w, "  signature: %s, version %d\n", err.Signature, err.Version)
+	}
+	fmt.Fprintf(w, "  offset:    0x%x (%d)\n", err.Offset, err.Offset)
+	if err.Section != "" {
+		fmt.Fprintf(w, "  section:   %s\n", err.Section)
+	}
+}
+
+// printUsage 打印所有子命令的用法
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [options] [arguments]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := commands[name]
+		fmt.Fprintf(w, "  %-70s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintf(w, "\nJSON options: --indent <n> (0 = compact), --sort-keys, --float-precision <n> (0 = lossless)\n")
+	fmt.Fprintf(w, "\nExit codes: %d ok, %d failure, %d usage error, %d unsupported file type\n", ExitOK, ExitFailure, ExitUsage, ExitUnsupported)
+}
+
+// newFlagSet 创建子命令的参数解析器，解析错误由 Run 统一输出
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags 解析参数，并检查位置参数数量是否在 [min, max] 范围内，max 为 -1 时不限制
+func parseFlags(fs *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	rest := fs.Args()
+	if len(rest) < min || (max >= 0 && len(rest) > max) {
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+// addJsonFlags 注册 JSON 输出格式参数，返回的函数在解析参数后调用，将格式应用到所有 .json 输出
+// 默认值为设置中保存的格式
+func addJsonFlags(fs *flag.FlagSet) func() error {
+	defaults := COM3D2.CurrentSettings().Json
+	indent := fs.Int("indent", defaults.Indent, "indent JSON output by this many spaces, 0 writes a single line")
+	sortKeys := fs.Bool("sort-keys", defaults.SortKeys, "sort object keys alphabetically")
+	floatPrecision := fs.Int("float-precision", defaults.FloatPrecision, "round floats to this many decimals, 0 keeps the lossless representation")
+	return func() error {
+		err := (&COM3D2.CommonService{}).SetJsonOptions(COM3D2.JsonOptions{
+			Indent:         *indent,
+			SortKeys:       *sortKeys,
+			FloatPrecision: *floatPrecision,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		return nil
+	}
+}
+
+// runInfo 输出文件类型信息
+func runInfo(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("info")
+	strict := fs.Bool("strict", COM3D2.CurrentSettings().FileTypeStrictMode, "determine the file type by content only")
+	paths, err := parseFlags(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	commonService := &COM3D2.CommonService{}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+
+	var firstErr error
+	for _, path := range paths {
+		fileInfo, err := commonService.FileTypeDetermine(path, *strict)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err := encoder.Encode(fileInfo); err != nil {
+			return err
+		}
+	}
+	return firstErr
+}
+
+// runToJson 将二进制文件转换为 .json 或 .yaml 文件
+func runToJson(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("to-json")
+	strict := fs.Bool("strict", COM3D2.CurrentSettings().FileTypeStrictMode, "determine the file type by content only")
+	asYaml := fs.Bool("yaml", false, "write .yaml instead of .json when no output is given")
+	applyJsonFlags := addJsonFlags(fs)
+	rest, err := parseFlags(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	if err := applyJsonFlags(); err != nil {
+		return err
+	}
+
+	inputPath := rest[0]
+	fileInfo, err := (&COM3D2.CommonService{}).FileTypeDetermine(inputPath, *strict)
+	if err != nil {
+		return err
+	}
+	if fileInfo.StorageFormat != COM3D2.FormatBinary {
+		return fmt.Errorf("%w: %s is not a binary file", errUnsupported, inputPath)
+	}
+
+	outputPath := inputPath + ".json"
+	if *asYaml {
+		outputPath = inputPath + ".yaml"
+	}
+	if len(rest) == 2 {
+		outputPath = rest[1]
+	}
+
+	if err := (&COM3D2.CommonService{}).ConvertToJson(fileInfo.FileType, inputPath, outputPath); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, outputPath)
+	return nil
+}
+
+// runFromJson 将 .json 或 .yaml 文件转换回二进制文件
+func runFromJson(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("from-json")
+	rest, err := parseFlags(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	inputPath := rest[0]
+	fileInfo, err := (&COM3D2.CommonService{}).FileTypeDetermine(inputPath, false)
+	if err != nil {
+		return err
+	}
+	if fileInfo.StorageFormat != COM3D2.FormatJSON && fileInfo.StorageFormat != COM3D2.FormatYAML {
+		return fmt.Errorf("%w: %s is not a .json or .yaml file", errUnsupported, inputPath)
+	}
+
+	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+	if len(rest) == 2 {
+		outputPath = rest[1]
+	}
+	if outputPath == inputPath || filepath.Ext(outputPath) == "" {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "." + fileInfo.FileType
+	}
+
+	if err := (&COM3D2.CommonService{}).ConvertFromJson(fileInfo.FileType, inputPath, outputPath); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, outputPath)
+	return nil
+}
+
+// runBatch 批量转换整个目录，任一文件失败时返回错误
+func runBatch(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("batch")
+	fromJson := fs.Bool("from-json", false, "convert .json and .yaml files back to binary instead of binary to .json")
+	asYaml := fs.Bool("yaml", false, "convert binary files to .yaml instead of .json")
+	strict := fs.Bool("strict", COM3D2.CurrentSettings().FileTypeStrictMode, "determine the file type by content only")
+	workers := fs.Int("workers", 0, "number of files converted concurrently, 0 uses the number of CPUs")
+	outputDir := fs.String("output", "", "write results into this directory, keeping the relative layout")
+	applyJsonFlags := addJsonFlags(fs)
+	rest, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if err := applyJsonFlags(); err != nil {
+		return err
+	}
+
+	options := COM3D2.BatchOptions{
+		Direction:  COM3D2.BatchToJson,
+		StrictMode: *strict,
+		Workers:    *workers,
+		OutputDir:  *outputDir,
+		Yaml:       *asYaml,
+	}
+	if *fromJson {
+		options.Direction = COM3D2.BatchFromJson
+	}
+
+	batchService := &COM3D2.BatchService{
+		Listener: func(event string, data interface{}) {
+			switch event {
+			case COM3D2.BatchProgressEvent:
+				progress := data.(COM3D2.BatchProgress)
+				fmt.Fprintf(stderr, "[%d/%d] %s\n", progress.Done, progress.Total, progress.Path)
+			case COM3D2.BatchFileFailedEvent:
+				fileErr := data.(COM3D2.BatchFileError)
+				fmt.Fprintf(stderr, "failed: %s: %s\n", fileErr.Path, fileErr.Error)
+			}
+		},
+	}
+	result, err := batchService.ConvertDirectory(rest[0], options)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "total %d, succeeded %d, failed %d, skipped %d\n", result.Total, result.Succeeded, result.Failed, result.Skipped)
+	if result.Failed > 0 {
+		return fmt.Errorf("%d file(s) failed to convert", result.Failed)
+	}
+	return nil
+}
+
+// runSaveItems 输出存档中所有女仆穿戴的 .menu 文件，一行一个
+func runSaveItems(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("save-items")
+	rest, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	files, err := (&COM3D2.SaveService{}).ReadSaveMenuFiles(rest[0])
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Fprintln(stdout, file)
+	}
+	return nil
+}
+
+// runSavePresets 将存档中的每个女仆导出为 .preset 文件
+func runSavePresets(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("save-presets")
+	rest, err := parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	written, err := (&COM3D2.SaveService{}).ExportAllMaidPresets(rest[0], rest[1])
+	for _, path := range written {
+		fmt.Fprintln(stdout, path)
+	}
+	return err
+}
+
+// runTexToImage 将 .tex 文件转换为图片
+func runTexToImage(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("tex2img")
+	forcePng := fs.Bool("force-png", COM3D2.CurrentSettings().TexForcePng, "always write PNG regardless of the texture format")
+	rest, err := parseFlags(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	inputPath := rest[0]
+	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".png"
+	if len(rest) == 2 {
+		outputPath = rest[1]
+	}
+
+	texService := &COM3D2.TexService{}
+	if !texService.CheckImageMagick() {
+		return errors.New("ImageMagick is not installed or not in PATH")
+	}
+	tex, err := texService.ReadTexFile(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := texService.ConvertTexToImageAndWrite(tex, outputPath, *forcePng); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, outputPath)
+	return nil
+}
+
+// runImageToTex 将图片转换为 .tex 文件
+func runImageToTex(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("img2tex")
+	compress := fs.Bool("compress", COM3D2.CurrentSettings().TexCompress, "compress the texture with DXT1/DXT5")
+	forcePng := fs.Bool("force-png", false, "store the image data as PNG")
+	texName := fs.String("name", "", "texture name stored in the .tex file")
+	rest, err := parseFlags(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	inputPath := rest[0]
+	outputPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".tex"
+	if len(rest) == 2 {
+		outputPath = rest[1]
+	}
+
+	texService := &COM3D2.TexService{}
+	if !texService.CheckImageMagick() {
+		return errors.New("ImageMagick is not installed or not in PATH")
+	}
+	if err := texService.ConvertImageToTexAndWrite(inputPath, *texName, *compress, *forcePng, outputPath); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, outputPath)
+	return nil
+}
+
+// stringList 可以重复指定的字符串参数
+type stringList []string
+
+func (s *stringList) String() string     { return strings.Join(*s, ",") }
+func (s *stringList) Set(v string) error { *s = append(*s, v); return nil }
+
+// runDeps 分析 MOD 目录的引用关系，有缺失引用时返回错误
+func runDeps(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("deps")
+	asJson := fs.Bool("json", false, "print the full report as JSON")
+	var searchDirs stringList
+	fs.Var(&searchDirs, "search", "additional directory used to resolve references, can be repeated")
+	rest, err := parseFlags(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	report, err := (&COM3D2.DependencyService{}).AnalyzeDependencies(rest[0], COM3D2.DependencyOptions{SearchDirs: searchDirs})
+	if err != nil {
+		return err
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(stdout, "missing (%d):\n", len(report.Missing))
+		for _, edge := range report.Missing {
+			fmt.Fprintf(stdout, "  %s -> %s (%s)\n", edge.From, edge.Name, edge.Kind)
+		}
+		fmt.Fprintf(stdout, "unused (%d):\n", len(report.Unused))
+		for _, path := range report.Unused {
+			fmt.Fprintf(stdout, "  %s\n", path)
+		}
+		fmt.Fprintf(stdout, "collisions (%d):\n", len(report.Collisions))
+		for _, collision := range report.Collisions {
+			fmt.Fprintf(stdout, "  %s: %s\n", collision.Name, strings.Join(collision.Paths, ", "))
+		}
+		for _, fileErr := range report.Errors {
+			fmt.Fprintf(stderr, "failed to parse %s: %s\n", fileErr.Path, fileErr.Error)
+		}
+	}
+
+	if len(report.Missing) > 0 {
+		return fmt.Errorf("%d missing reference(s)", len(report.Missing))
+	}
+	return nil
+}
+
+// runVerify 对文件或目录进行往返校验，任一文件不一致时返回错误
+func runVerify(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("verify")
+	asJson := fs.Bool("json", false, "print the results as JSON")
+	paths, err := parseFlags(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	commonService := &COM3D2.CommonService{}
+	var results []COM3D2.RoundTripResult
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			dirResults, err := commonService.VerifyRoundTripDirectory(path)
+			results = append(results, dirResults...)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		result, err := commonService.VerifyRoundTrip(path)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.OK() {
+			failed++
+		}
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			if result.OK() {
+				fmt.Fprintf(stdout, "ok    %s\n", result.Path)
+				continue
+			}
+			fmt.Fprintf(stdout, "FAIL  %s\n", result.Path)
+			if result.BinaryDiff != nil {
+				printRoundTripDiff(stdout, "binary", result.BinaryDiff)
+			}
+			if result.JsonDiff != nil {
+				printRoundTripDiff(stdout, "json", result.JsonDiff)
+			}
+			if result.YamlDiff != nil {
+				printRoundTripDiff(stdout, "yaml", result.YamlDiff)
+			}
+			if result.Error != "" {
+				fmt.Fprintf(stdout, "      error: %s\n", result.Error)
+			}
+		}
+		fmt.Fprintf(stdout, "total %d, failed %d\n", len(results), failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) failed round-trip verification", failed)
+	}
+	return nil
+}
+
+// printRoundTripDiff 输出一个往返差异
+func printRoundTripDiff(w io.Writer, stage string, diff *COM3D2.RoundTripDiff) {
+	field := diff.Field
+	if field == "" {
+		field = "unknown field"
+	}
+	fmt.Fprintf(w, "      %s: first difference at offset 0x%x (%s), size %d -> %d\n", stage, diff.Offset, field, diff.OriginalSize, diff.RewrittenSize)
+	fmt.Fprintf(w, "        original:  %s\n", diff.Original)
+	fmt.Fprintf(w, "        rewritten: %s\n", diff.Rewritten)
+}
+
+// runSchema 输出单个格式的 JSON Schema，或将所有 Schema 写入目录并输出 VS Code 的 json.schemas 配置
+func runSchema(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("schema")
+	outputDir := fs.String("out", "", "write every schema into this directory")
+	rest, err := parseFlags(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	commonService := &COM3D2.CommonService{}
+	if *outputDir == "" {
+		if len(rest) != 1 {
+			return errUsage
+		}
+		schema, err := commonService.GetJsonSchema(rest[0])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, schema)
+		return nil
+	}
+
+	written, err := commonService.WriteJsonSchemas(*outputDir)
+	if err != nil {
+		return err
+	}
+	type schemaMapping struct {
+		FileMatch []string `json:"fileMatch"`
+		URL       string   `json:"url"`
+	}
+	var mappings []schemaMapping
+	for _, path := range written {
+		fileType := strings.TrimSuffix(filepath.Base(path), ".schema.json")
+		mappings = append(mappings, schemaMapping{
+			FileMatch: []string{"*." + fileType + ".json"},
+			URL:       filepath.ToSlash(path),
+		})
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{"json.schemas": mappings})
+}
+
+// runDiff 比较两个文件，有差异时退出码为 ExitFailure，方便脚本判断
+func runDiff(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("diff")
+	asJson := fs.Bool("json", false, "print the differences as JSON")
+	rest, err := parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	result, err := (&COM3D2.DiffService{}).DiffFiles(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(stdout, COM3D2.FormatDiffText(result))
+	}
+
+	if !result.Identical {
+		return fmt.Errorf("%d difference(s)", len(result.Changes))
+	}
+	return nil
+}
+
+// runMerge 三方合并，没有冲突时写出结果（未指定输出时以 JSON 输出到标准输出），有冲突时输出冲突列表并返回错误
+func runMerge(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("merge")
+	outputPath := fs.String("output", "", "write the merged file here, binary or .json depending on the extension")
+	rest, err := parseFlags(fs, args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	mergeService := &COM3D2.MergeService{}
+	var result *COM3D2.MergeResult
+	if *outputPath != "" {
+		result, err = mergeService.MergeFilesAndWrite(rest[0], rest[1], rest[2], *outputPath)
+	} else {
+		result, err = mergeService.MergeFiles(rest[0], rest[1], rest[2])
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if !result.Clean {
+		if err := encoder.Encode(result.Conflicts); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d conflict(s), nothing was written", len(result.Conflicts))
+	}
+	if *outputPath != "" {
+		fmt.Fprintln(stdout, *outputPath)
+		return nil
+	}
+	return encoder.Encode(result.Merged)
+}
+
+// runPatch 对文件应用 JSON Patch，补丁文件为 - 时从标准输入读取
+func runPatch(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("patch")
+	outputPath := fs.String("output", "", "write the result here instead of overwriting the input")
+	rest, err := parseFlags(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	var raw []byte
+	if rest[1] == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(rest[1])
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read patch: %w", err)
+	}
+	patch, err := COM3D2.ParsePatch(raw)
+	if err != nil {
+		return err
+	}
+
+	output := rest[0]
+	if *outputPath != "" {
+		output = *outputPath
+	}
+	if err := (&COM3D2.PatchService{}).ApplyPatchToFile(rest[0], output, patch); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, output)
+	return nil
+}
+
+// runSettings 输出设置文件路径和当前设置
+func runSettings(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("settings")
+	if _, err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	settingsService := &COM3D2.SettingsService{}
+	path, err := settingsService.GetSettingsPath()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"Path":        path,
+		"Portable":    settingsService.IsPortableMode(),
+		"Settings":    settingsService.GetSettings(),
+		"RecentFiles": settingsService.GetRecentFiles(),
+	})
+}
+
+// runLint 检查文件，有 error 级别的问题时返回错误
+func runLint(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs := newFlagSet("lint")
+	asJson := fs.Bool("json", false, "print the results as JSON")
+	configPath := fs.String("config", "", "lint config file, defaults to the nearest "+COM3D2.LintConfigFileName)
+	var rules stringList
+	fs.Var(&rules, "rule", "override a rule, e.g. --rule menu/required-commands=off (repeatable)")
+	paths, err := parseFlags(fs, args, 1, -1)
+	if err != nil {
+		return err
+	}
+
+	options := COM3D2.LintOptions{ConfigPath: *configPath, Rules: map[string]string{}}
+	for _, rule := range rules {
+		name, severity, ok := strings.Cut(rule, "=")
+		if !ok {
+			return fmt.Errorf("%w: --rule must be <name>=<severity>", errUsage)
+		}
+		options.Rules[name] = severity
+	}
+
+	lintService := &COM3D2.LintService{}
+	var results []COM3D2.LintResult
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			dirResults, err := lintService.LintDirectory(path, options)
+			if err != nil {
+				return err
+			}
+			results = append(results, dirResults...)
+			continue
+		}
+		result, err := lintService.LintFile(path, options)
+		if err != nil {
+			return err
+		}
+		results = append(results, *result)
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, result := range results {
+		errorCount += result.Errors
+		warningCount += result.Warnings
+	}
+
+	if *asJson {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			for _, d := range result.Diagnostics {
+				fmt.Fprintf(stdout, "%s:%s: %s: %s [%s]\n", result.Path, d.Path, d.Severity, d.Message, d.Rule)
+			}
+		}
+		fmt.Fprintf(stdout, "%d file(s), %d error(s), %d warning(s)\n", len(results), errorCount, warningCount)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) found", errorCount)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBinaryString 按 C# BinaryWriter 的格式写出字符串（7 位编码的长度 + UTF-8）
+func writeBinaryString(buf *bytes.Buffer, s string) {
+	length := uint32(len(s))
+	for length >= 0x80 {
+		buf.WriteByte(byte(length) | 0x80)
+		length >>= 7
+	}
+	buf.WriteByte(byte(length))
+	buf.WriteString(s)
+}
+
+// fixtureMenu 构造一个只有 name、category 和 icons 命令的 .menu
+func fixtureMenu() []byte {
+	var body bytes.Buffer
+	for _, args := range [][]string{{"name", "test"}, {"category", "wear"}, {"icons", "test_i_.tex"}} {
+		body.WriteByte(byte(len(args)))
+		for _, arg := range args {
+			writeBinaryString(&body, arg)
+		}
+	}
+	body.WriteByte(0)
+
+	var buf bytes.Buffer
+	writeBinaryString(&buf, "CM3D2_MENU")
+	binary.Write(&buf, binary.LittleEndian, int32(1000))
+	for _, s := range []string{"test", "test", "wear", "info"} {
+		writeBinaryString(&buf, s)
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// 缺少 icon 或 icons 命令，menu/required-commands 报错
+const incompleteMenuJson = `{"Signature": "CM3D2_MENU", "Version": 1000, "SrcFileName": "test", "ItemName": "test", "Category": "wear", "InfoText": "", "BodySize": 0,
+	"Commands": [{"ArgCount": 2, "Args": ["name", "test"]}, {"ArgCount": 2, "Args": ["category", "wear"]}]}`
+
+const completeMenuJson = `{"Signature": "CM3D2_MENU", "Version": 1000, "SrcFileName": "test", "ItemName": "test", "Category": "wear", "InfoText": "", "BodySize": 0,
+	"Commands": [{"ArgCount": 2, "Args": ["name", "test"]}, {"ArgCount": 2, "Args": ["category", "wear"]}, {"ArgCount": 2, "Args": ["icons", "test_i_.tex"]}]}`
+
+// 使用 icon 而不是 icons，同样满足 menu/required-commands
+const iconMenuJson = `{"Signature": "CM3D2_MENU", "Version": 1000, "SrcFileName": "test", "ItemName": "test", "Category": "wear", "InfoText": "", "BodySize": 0,
+	"Commands": [{"ArgCount": 2, "Args": ["name", "test"]}, {"ArgCount": 2, "Args": ["category", "wear"]}, {"ArgCount": 2, "Args": ["icon", "test_i_.tex"]}]}`
+
+func TestRunExitCodes(t *testing.T) {
+	// 设置文件写到临时目录，不读取用户的设置
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"body.menu":            fixtureMenu(),
+		"body.menu.json":       []byte(completeMenuJson),
+		"incomplete.menu.json": []byte(incompleteMenuJson),
+		"broken.menu.json":     []byte(`{"Signature": "CM3D2_MENU", "Version": 1000, "Commands": [`),
+		"unknown.json":         []byte(`{"Signature": "UNKNOWN", "Version": 1}`),
+		"icon.menu.json":       []byte(iconMenuJson),
+		"warning.json":         []byte(`{"Rules": {"menu/required-commands": "warning"}}`),
+		"lint/ok.menu.json":    []byte(completeMenuJson),
+		"lint/bad.menu.json":   []byte(incompleteMenuJson),
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lint"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStdout string // stdout 应包含的内容，为空时 stdout 必须为空
+		wantStderr string // stderr 应包含的内容，为空时 stderr 必须为空
+	}{
+		{"help", []string{"help"}, ExitOK, "Exit codes:", ""},
+		{"unknown command", []string{"unpack"}, ExitUsage, "", "unknown command: unpack"},
+
+		{"info", []string{"info", path("body.menu")}, ExitOK, `"Signature": "CM3D2_MENU"`, ""},
+		{"info json", []string{"info", path("body.menu.json")}, ExitOK, `"StorageFormat": "json"`, ""},
+		{"info missing argument", []string{"info"}, ExitUsage, "", "usage: info"},
+		{"info missing file", []string{"info", path("missing.menu")}, ExitFailure, "", "missing.menu"},
+		{"info unknown signature", []string{"info", path("unknown.json")}, ExitUnsupported, "", "unknown file type"},
+
+		{"to-json", []string{"to-json", path("body.menu"), path("out.menu.json")}, ExitOK, path("out.menu.json"), ""},
+		{"to-json bad option", []string{"to-json", "--indent", "-1", path("body.menu")}, ExitUsage, "", "usage: to-json"},
+		{"to-json too many arguments", []string{"to-json", "a", "b", "c"}, ExitUsage, "", "usage: to-json"},
+		{"to-json missing file", []string{"to-json", path("missing.menu")}, ExitFailure, "", "missing.menu"},
+		{"to-json of json", []string{"to-json", path("body.menu.json")}, ExitUnsupported, "", "is not a binary file"},
+
+		{"from-json", []string{"from-json", path("body.menu.json"), path("out.menu")}, ExitOK, path("out.menu"), ""},
+		{"from-json missing argument", []string{"from-json"}, ExitUsage, "", "usage: from-json"},
+		{"from-json broken", []string{"from-json", path("broken.menu.json"), path("broken.menu")}, ExitFailure, "", "from-json:"},
+		{"from-json of binary", []string{"from-json", path("body.menu")}, ExitUnsupported, "", "is not a .json or .yaml file"},
+
+		{"lint", []string{"lint", path("body.menu.json")}, ExitOK, "1 file(s), 0 error(s), 0 warning(s)", ""},
+		{"lint error", []string{"lint", path("incomplete.menu.json")}, ExitFailure, "menu/required-commands", "1 error(s) found"},
+		{"lint rule off", []string{"lint", "--rule", "menu/required-commands=off", path("incomplete.menu.json")}, ExitOK, "0 error(s)", ""},
+		{"lint invalid rule", []string{"lint", "--rule", "menu/required-commands", path("body.menu.json")}, ExitUsage, "", "usage: lint"},
+		{"lint icon", []string{"lint", path("icon.menu.json")}, ExitOK, "0 error(s)", ""},
+		{"lint warning only", []string{"lint", "--config", path("warning.json"), path("incomplete.menu.json")}, ExitOK, "0 error(s), 1 warning(s)", ""},
+		{"lint rule overrides config", []string{"lint", "--config", path("warning.json"), "--rule", "menu/required-commands=error", path("incomplete.menu.json")}, ExitFailure, "1 error(s)", "1 error(s) found"},
+		{"lint missing config", []string{"lint", "--config", path("missing.json"), path("body.menu.json")}, ExitFailure, "", "cannot read lint config"},
+		{"lint directory", []string{"lint", path("lint")}, ExitFailure, "2 file(s), 1 error(s)", "1 error(s) found"},
+		{"lint json", []string{"lint", "--json", path("incomplete.menu.json")}, ExitFailure, `"Rule": "menu/required-commands"`, "1 error(s) found"},
+		{"lint parse error", []string{"lint", path("broken.menu.json")}, ExitFailure, "[parse]", "1 error(s) found"},
+		{"lint missing argument", []string{"lint"}, ExitUsage, "", "usage: lint"},
+		{"lint unknown rule", []string{"lint", "--rule", "menu/unknown=off", path("body.menu.json")}, ExitFailure, "", `unknown lint rule "menu/unknown"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := runCommand(tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("exit code = %d, want %d\nstdout: %s\nstderr: %s", got, tt.want, stdout.String(), stderr.String())
+			}
+			checkOutput(t, "stdout", stdout.String(), tt.wantStdout)
+			checkOutput(t, "stderr", stderr.String(), tt.wantStderr)
+		})
+	}
+}
+
+// checkOutput 检查输出包含 want，want 为空时输出也必须为空
+func checkOutput(t *testing.T, name string, got string, want string) {
+	t.Helper()
+	if want == "" && got != "" {
+		t.Errorf("%s = %q, want nothing", name, got)
+	}
+	if !strings.Contains(got, want) {
+		t.Errorf("%s = %q, want it to contain %q", name, got, want)
+	}
+}
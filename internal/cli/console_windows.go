@@ -0,0 +1,29 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachParentProcess 对应 Win32 API 的 ATTACH_PARENT_PROCESS
+const attachParentProcess = ^uintptr(0)
+
+// attachConsole 程序以 GUI 子系统编译，没有自己的控制台
+// 从命令行调用时附加到父进程的控制台，否则子命令的输出会丢失
+func attachConsole() {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	r, _, _ := kernel32.NewProc("AttachConsole").Call(attachParentProcess)
+	if r == 0 {
+		return
+	}
+
+	if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = f
+		os.Stderr = f
+	}
+	if f, err := os.OpenFile("CONIN$", os.O_RDONLY, 0); err == nil {
+		os.Stdin = f
+	}
+}
@@ -0,0 +1,197 @@
+package COM3D2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 所有写出都先写入同目录下的临时文件，fsync 后再重命名覆盖目标文件
+// 这样编码中途失败或程序崩溃时，原文件不会被截断
+// 覆盖已有文件前可以保留最近 N 份备份：foo.menu.bak 为最新，foo.menu.bak.1、foo.menu.bak.2 依次更旧
+
+var (
+	backupCountMu sync.RWMutex
+	backupCount   int // 覆盖文件时保留的备份数量，0 表示不备份
+)
+
+// SetBackupCount 设置覆盖文件时保留的备份数量，0 表示不备份
+func (m *CommonService) SetBackupCount(count int) {
+	if count < 0 {
+		count = 0
+	}
+	backupCountMu.Lock()
+	defer backupCountMu.Unlock()
+	backupCount = count
+}
+
+// GetBackupCount 获取覆盖文件时保留的备份数量
+func (m *CommonService) GetBackupCount() int {
+	backupCountMu.RLock()
+	defer backupCountMu.RUnlock()
+	return backupCount
+}
+
+// writeFileAtomic 将 write 的输出原子地写入 path，覆盖已有文件前按 SetBackupCount 的设置保留备份
+// write 返回错误时目标文件保持不变，临时文件会被删除
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	backupCountMu.RLock()
+	count := backupCount
+	backupCountMu.RUnlock()
+	return replaceFile(path, count, write)
+}
+
+// replaceFile writeFileAtomic 的实现，backups 为覆盖已有文件前保留的备份数量，程序自身的文件（例如设置）不需要备份时为 0
+func replaceFile(path string, backups int, write func(w io.Writer) error) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	bw := bufio.NewWriter(tmp)
+	if err = write(bw); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return fmt.Errorf("an error occurred while flush bufio: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error closing temporary file: %w", err)
+	}
+
+	// 保留原文件的权限，CreateTemp 创建的文件权限为 0600
+	mode := os.FileMode(0644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+		if err = rotateBackups(path, backups); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	if err = os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	syncDir(dir)
+	recordOwnWrite(path)
+	return nil
+}
+
+// writeFilesAtomicVia 用于只能写入到路径的外部函数（例如通过 ImageMagick 写出图片）
+// write 先写入临时目录中与 path 同名的文件，之后临时目录中的每个文件都通过 writeFileAtomic 写到 path 所在的目录
+// 外部函数可能自行修改扩展名或额外生成附属文件（例如 .uv.csv），因此按实际生成的文件名写出
+func writeFilesAtomicVia(path string, write func(tmpPath string) error) error {
+	tmpDir, err := os.MkdirTemp("", "com3d2-write-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := write(filepath.Join(tmpDir, filepath.Base(path))); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		src := filepath.Join(tmpDir, entry.Name())
+		err := writeFileAtomic(filepath.Join(filepath.Dir(path), entry.Name()), func(w io.Writer) error {
+			in, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			defer in.Close()
+			_, err = io.Copy(w, in)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateBackups 轮换备份并将当前文件备份为 path.bak，count 为 0 时不做任何事
+func rotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	backupName := func(i int) string {
+		if i == 0 {
+			return path + ".bak"
+		}
+		return fmt.Sprintf("%s.bak.%d", path, i)
+	}
+
+	// 删除最旧的备份，其余依次后移
+	if err := os.Remove(backupName(count - 1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := count - 2; i >= 0; i-- {
+		if err := os.Rename(backupName(i), backupName(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	// 优先使用硬链接，随后的重命名不会影响备份；文件系统不支持时复制
+	if err := os.Link(path, backupName(0)); err == nil {
+		return nil
+	}
+	return copyFile(path, backupName(0))
+}
+
+// copyFile 复制文件内容
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// syncDir 同步目录，确保重命名已落盘，部分平台（例如 Windows）不支持，忽略错误
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,155 @@
+package COM3D2
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// writeString 返回写出 s 的回调
+func writeString(s string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+// dirFiles 返回目录中的所有文件名和内容
+func dirFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
+
+func TestReplaceFileBackups(t *testing.T) {
+	tests := []struct {
+		name    string
+		backups int
+		writes  []string
+		want    map[string]string
+	}{
+		{
+			name:    "new file",
+			backups: 2,
+			writes:  []string{"v1"},
+			want:    map[string]string{"a.menu": "v1"},
+		},
+		{
+			name:    "no backups",
+			backups: 0,
+			writes:  []string{"v1", "v2", "v3"},
+			want:    map[string]string{"a.menu": "v3"},
+		},
+		{
+			name:    "rotate backups",
+			backups: 2,
+			writes:  []string{"v1", "v2", "v3", "v4"},
+			want:    map[string]string{"a.menu": "v4", "a.menu.bak": "v3", "a.menu.bak.1": "v2"},
+		},
+		{
+			name:    "single backup",
+			backups: 1,
+			writes:  []string{"v1", "v2", "v3"},
+			want:    map[string]string{"a.menu": "v3", "a.menu.bak": "v2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.menu")
+			for _, content := range tt.writes {
+				if err := replaceFile(path, tt.backups, writeString(content)); err != nil {
+					t.Fatalf("replaceFile: %v", err)
+				}
+			}
+			if got := dirFiles(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 写出失败时原文件保持不变，也不留下临时文件
+func TestReplaceFileFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.menu")
+	if err := replaceFile(path, 0, writeString("original")); err != nil {
+		t.Fatal(err)
+	}
+
+	errWrite := errors.New("encode failed")
+	err := replaceFile(path, 1, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errWrite
+	})
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("replaceFile error = %v, want %v", err, errWrite)
+	}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, map[string]string{"a.menu": "original"}) {
+		t.Errorf("files = %v", got)
+	}
+}
+
+func TestReplaceFileKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "a.menu")
+	if err := os.WriteFile(path, []byte("original"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFile(path, 0, writeString("new")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+}
+
+// 外部函数额外生成的文件同样写到目标目录
+func TestWriteFilesAtomicVia(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.png")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := writeFilesAtomicVia(path, func(tmpPath string) error {
+		if err := os.WriteFile(tmpPath, []byte("image"), 0644); err != nil {
+			return err
+		}
+		return os.WriteFile(tmpPath+".uv.csv", []byte("rects"), 0644)
+	})
+	if err != nil {
+		t.Fatalf("writeFilesAtomicVia: %v", err)
+	}
+	if got := dirFiles(t, dir); !reflect.DeepEqual(got, map[string]string{"a.png": "image", "a.png.uv.csv": "rects"}) {
+		t.Errorf("files = %v", got)
+	}
+
+	errWrite := errors.New("convert failed")
+	if err := writeFilesAtomicVia(path, func(tmpPath string) error { return errWrite }); !errors.Is(err, errWrite) {
+		t.Errorf("writeFilesAtomicVia error = %v, want %v", err, errWrite)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "image" {
+		t.Errorf("failed write changed the file to %q", got)
+	}
+}
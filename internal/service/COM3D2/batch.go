@@ -0,0 +1,277 @@
+package COM3D2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"io/fs"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"sync"
+)
+
+// 批量转换方向
+const (
+	BatchToJson   = "toJson"   // 二进制 → JSON
+	BatchFromJson = "fromJson" // JSON 或 YAML → 二进制
+)
+
+// 批量转换时发送给前端的事件名称
+const (
+	BatchProgressEvent   = "batch-progress"    // 每处理完一个文件发送一次，数据为 BatchProgress
+	BatchFileFailedEvent = "batch-file-failed" // 单个文件转换失败，数据为 BatchFileError
+	BatchFinishedEvent   = "batch-finished"    // 全部处理完成，数据为 BatchResult
+)
+
+// BatchService 批量转换整个目录中的文件
+type BatchService struct {
+	ctx context.Context
+
+	// Listener 没有 wails 上下文时（例如命令行模式）用于接收事件，可以为空
+	Listener func(event string, data interface{})
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// BatchOptions 批量转换选项
+type BatchOptions struct {
+	Direction  string `json:"Direction"`  // 转换方向 toJson/fromJson，见顶部常量定义
+	StrictMode bool   `json:"StrictMode"` // 传给 FileTypeDetermine 的严格模式
+	Workers    int    `json:"Workers"`    // 并发数，小于等于 0 时使用 CPU 核心数
+	OutputDir  string `json:"OutputDir"`  // 输出目录，保持相对目录结构，为空时写在原文件旁边
+	Yaml       bool   `json:"Yaml"`       // toJson 时输出 .yaml 而不是 .json
+}
+
+// BatchProgress 批量转换进度
+type BatchProgress struct {
+	Total    int    `json:"Total"`    // 需要处理的文件总数
+	Done     int    `json:"Done"`     // 已处理的文件数（包括失败和跳过）
+	Failed   int    `json:"Failed"`   // 失败的文件数
+	Path     string `json:"Path"`     // 刚处理完的文件
+	FileType string `json:"FileType"` // 刚处理完的文件类型
+}
+
+// BatchFileError 单个文件的转换错误
+type BatchFileError struct {
+	Path     string `json:"Path"`
+	FileType string `json:"FileType"`
+	Error    string `json:"Error"`
+}
+
+// BatchResult 批量转换结果
+type BatchResult struct {
+	Total     int              `json:"Total"`
+	Succeeded int              `json:"Succeeded"`
+	Failed    int              `json:"Failed"`
+	Skipped   int              `json:"Skipped"` // 无法识别或不支持该方向转换的文件
+	Canceled  bool             `json:"Canceled"`
+	Errors    []BatchFileError `json:"Errors"`
+}
+
+// Startup 保存 wails 上下文，用于发送进度事件
+func (b *BatchService) Startup(ctx context.Context) {
+	b.ctx = ctx
+}
+
+// errBatchSkipped 文件无需转换
+var errBatchSkipped = errors.New("skipped")
+
+// ConvertDirectory 递归遍历目录，使用 FileTypeDetermine 判断每个文件的类型，并按 options.Direction 转换所有可识别的文件
+// 进度和单个文件的错误通过事件发送，单个文件失败不会中断整个任务
+func (b *BatchService) ConvertDirectory(dir string, options BatchOptions) (result BatchResult, err error) {
+	if options.Direction != BatchToJson && options.Direction != BatchFromJson {
+		return result, fmt.Errorf("unknown batch direction: %q", options.Direction)
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = goruntime.NumCPU()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.mu.Lock()
+	if b.cancel != nil {
+		b.mu.Unlock()
+		cancel()
+		return result, errors.New("another batch job is already running")
+	}
+	b.cancel = cancel
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.cancel = nil
+		b.mu.Unlock()
+		cancel()
+	}()
+
+	// 先收集候选文件，这样才能报告总数
+	// 只按扩展名粗筛，避免对无关文件调用 ImageMagick 判断图片类型
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			// 不要把输出目录当作输入再处理一遍
+			if options.OutputDir != "" && path != dir && sameDir(path, options.OutputDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isBatchCandidate(path, options.Direction) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	result.Total = len(paths)
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	var resultMu sync.Mutex
+	done := 0
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				fileType, convertErr := b.convertOne(dir, path, options)
+
+				resultMu.Lock()
+				done++
+				switch {
+				case convertErr == nil:
+					result.Succeeded++
+				case errors.Is(convertErr, errBatchSkipped):
+					result.Skipped++
+				default:
+					result.Failed++
+					fileErr := BatchFileError{Path: path, FileType: fileType, Error: convertErr.Error()}
+					result.Errors = append(result.Errors, fileErr)
+					b.emit(BatchFileFailedEvent, fileErr)
+				}
+				b.emit(BatchProgressEvent, BatchProgress{
+					Total:    result.Total,
+					Done:     done,
+					Failed:   result.Failed,
+					Path:     path,
+					FileType: fileType,
+				})
+				resultMu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, path := range paths {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- path:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	result.Canceled = ctx.Err() != nil && done < result.Total
+	b.emit(BatchFinishedEvent, result)
+	return result, nil
+}
+
+// CancelBatch 取消正在进行的批量转换，已经开始的文件会继续完成
+func (b *BatchService) CancelBatch() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// convertOne 判断单个文件的类型并转换，返回文件类型
+func (b *BatchService) convertOne(root string, path string, options BatchOptions) (string, error) {
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(path, options.StrictMode)
+	if errors.Is(err, ErrUnsupportedFileType) {
+		return "", fmt.Errorf("%w: %v", errBatchSkipped, err)
+	}
+	// 文件截断或损坏时无法判断类型，算作失败
+	if err != nil {
+		return fileInfo.FileType, err
+	}
+
+	outputPath := path
+	if options.OutputDir != "" {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fileInfo.FileType, err
+		}
+		outputPath = filepath.Join(options.OutputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return fileInfo.FileType, fmt.Errorf("unable to create output directory: %w", err)
+		}
+	}
+
+	commonService := &CommonService{}
+	switch options.Direction {
+	case BatchToJson:
+		if fileInfo.StorageFormat != FormatBinary {
+			return fileInfo.FileType, errBatchSkipped
+		}
+		suffix := jsonSuffix
+		if options.Yaml {
+			suffix = yamlSuffix
+		}
+		err = commonService.ConvertToJson(fileInfo.FileType, path, outputPath+suffix)
+	case BatchFromJson:
+		if fileInfo.StorageFormat != FormatJSON && fileInfo.StorageFormat != FormatYAML {
+			return fileInfo.FileType, errBatchSkipped
+		}
+		outputPath = trimTextSuffix(outputPath)
+		// 按签名识别的 foo.json 去掉后缀后没有格式扩展名，补上对应格式的扩展名
+		if _, ok := FormatByPath(outputPath); !ok {
+			if f, ok := FormatByFileType(fileInfo.FileType); ok {
+				outputPath += f.Extension()
+			}
+		}
+		err = commonService.ConvertFromJson(fileInfo.FileType, path, outputPath)
+	}
+	if errors.Is(err, ErrUnsupportedFileType) {
+		return fileInfo.FileType, errBatchSkipped
+	}
+	return fileInfo.FileType, err
+}
+
+// emit 发送事件，有 wails 上下文时发给前端，否则交给 Listener
+func (b *BatchService) emit(event string, data interface{}) {
+	if b.ctx != nil {
+		runtime.EventsEmit(b.ctx, event, data)
+	}
+	if b.Listener != nil {
+		b.Listener(event, data)
+	}
+}
+
+// isBatchCandidate 根据扩展名判断文件是否可能需要转换
+func isBatchCandidate(path string, direction string) bool {
+	if direction == BatchFromJson {
+		return textSuffix(path) != ""
+	}
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	f, exists := FormatByFileType(ext)
+	return exists && f.SupportsJSON()
+}
+
+// sameDir 判断两个路径是否指向同一个目录
+func sameDir(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
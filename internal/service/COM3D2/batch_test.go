@@ -0,0 +1,99 @@
+package COM3D2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsBatchCandidate(t *testing.T) {
+	tests := []struct {
+		path      string
+		direction string
+		want      bool
+	}{
+		{"body.menu", BatchToJson, true},
+		{"body.MODEL", BatchToJson, true},
+		{"body.menu.json", BatchToJson, false},
+		{"readme.txt", BatchToJson, false},
+		{"body.menu.json", BatchFromJson, true},
+		{"body.mate.yaml", BatchFromJson, true},
+		{"body.menu", BatchFromJson, false},
+	}
+	for _, tt := range tests {
+		if got := isBatchCandidate(tt.path, tt.direction); got != tt.want {
+			t.Errorf("isBatchCandidate(%q, %q) = %v, want %v", tt.path, tt.direction, got, tt.want)
+		}
+	}
+}
+
+func TestSameDir(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{dir, dir, true},
+		{dir, dir + string(filepath.Separator), true},
+		{filepath.Join(dir, "a", ".."), dir, true},
+		{filepath.Join(dir, "a"), dir, false},
+	}
+	for _, tt := range tests {
+		if got := sameDir(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameDir(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConvertDirectoryOptions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a game file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &BatchService{}
+	if _, err := b.ConvertDirectory(dir, BatchOptions{Direction: "sideways"}); err == nil {
+		t.Error("unknown direction accepted")
+	}
+
+	// 没有候选文件时什么也不做
+	var events []string
+	b.Listener = func(event string, data interface{}) { events = append(events, event) }
+	result, err := b.ConvertDirectory(dir, BatchOptions{Direction: BatchToJson})
+	if err != nil {
+		t.Fatalf("ConvertDirectory: %v", err)
+	}
+	if result.Total != 0 || result.Succeeded != 0 || result.Failed != 0 || result.Canceled {
+		t.Errorf("result = %+v", result)
+	}
+	if len(events) != 1 || events[0] != BatchFinishedEvent {
+		t.Errorf("events = %v, want only %s", events, BatchFinishedEvent)
+	}
+}
+
+// 按签名识别的 foo.json 转换为 foo.menu，而不是没有扩展名的 foo
+func TestConvertDirectoryFromJsonExtension(t *testing.T) {
+	dir := t.TempDir()
+	const menuJson = `{"Signature": "CM3D2_MENU", "Version": 1000, "Commands": [{"ArgCount": 2, "Args": ["name", "test"]}]}`
+	for _, name := range []string{"foo.json", "bar.menu.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(menuJson), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := (&BatchService{}).ConvertDirectory(dir, BatchOptions{Direction: BatchFromJson, Workers: 1})
+	if err != nil {
+		t.Fatalf("ConvertDirectory: %v", err)
+	}
+	if result.Succeeded != 2 || result.Failed != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	for _, name := range []string{"foo.menu", "bar.menu"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "foo")); err == nil {
+		t.Error("foo.json was converted to foo without an extension")
+	}
+}
@@ -0,0 +1,399 @@
+package COM3D2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// 按文件结构扫描二进制数据，只读取结构本身需要的长度和计数，其余数据直接跳过，数据源支持 Seek 时大块数据通过 Seek 跳过
+// 结构与 MeidoSerialization 中对应的 Read 函数一致，字符串为 C# BinaryWriter 的格式（7 位编码的长度 + UTF-8）
+// 目前支持 .menu、.mate、.tex 和 .model（见 modellayout.go），其他格式解析失败时只报告偏移
+
+// errScanDone 已经到达定位的目标位置，停止扫描
+var errScanDone = errors.New("scan reached the target offset")
+
+// maxStringLength 数据大小未知时字符串长度的上限，文件中的字符串都是名称和路径，远小于这个长度
+const maxStringLength = 16 << 20
+
+// binaryScanner 逐段读取二进制数据，记录当前所在的部分
+type binaryScanner struct {
+	r       *bufio.Reader
+	seeker  io.ReadSeeker // 数据源不支持 Seek 时为 nil
+	pos     int64
+	size    int64         // 数据大小，数据源不支持 Seek 时为 -1
+	target  int64         // 定位的目标位置，小于 0 时一直读取到结束
+	section string        // 当前所在部分的名称
+	record  *bytes.Buffer // 不为 nil 时记录读取和跳过的所有字节
+
+	signature string // scanHeader 读取到的签名和版本
+	version   int32
+}
+
+// newBinaryScanner 从 r 的当前位置开始扫描，target 小于 0 时不定位
+func newBinaryScanner(r io.Reader, target int64) *binaryScanner {
+	s := &binaryScanner{r: bufio.NewReader(r), size: -1, target: target}
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			if end, err := seeker.Seek(0, io.SeekEnd); err == nil {
+				s.size = end - start
+			}
+			if _, err := seeker.Seek(start, io.SeekStart); err == nil {
+				s.seeker = seeker
+			}
+		}
+	}
+	return s
+}
+
+// parseError 将扫描过程中的错误转换为 *ParseError，位置为扫描停止的位置
+func (s *binaryScanner) parseError(fileType string, path string, err error) *ParseError {
+	return &ParseError{
+		Path:      path,
+		FileType:  fileType,
+		Offset:    s.pos,
+		Size:      s.size,
+		Signature: s.signature,
+		Version:   s.version,
+		Section:   s.section,
+		Message:   err.Error(),
+		Err:       err,
+	}
+}
+
+// locateSection 从头扫描到 offset，返回 offset 所在的部分，无法定位时返回空字符串
+// 解析器通常在读完出错的字段之后才返回错误，因此取起始位置在 offset 之前的最后一段
+func locateSection(r io.Reader, offset int64, locate func(s *binaryScanner) error) string {
+	s := newBinaryScanner(r, offset)
+	locate(s)
+	return s.section
+}
+
+// enter 开始读取名为 label 的一段数据，定位时到达目标位置返回 errScanDone
+func (s *binaryScanner) enter(label string) error {
+	if s.target < 0 || s.pos < s.target {
+		s.section = label
+		return nil
+	}
+	// 解析器正好停在这一段的开头：如果文件在这里结束，说明出错时读取的就是这一段
+	if s.pos == s.target {
+		if _, err := s.r.Peek(1); err != nil {
+			s.section = label
+		}
+	}
+	return errScanDone
+}
+
+// errorf 生成带有当前位置和部分的错误
+func (s *binaryScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at offset 0x%x: %s", s.section, s.pos, fmt.Sprintf(format, args...))
+}
+
+// bytes 读取 n 个字节
+func (s *binaryScanner) bytes(label string, n int) ([]byte, error) {
+	if err := s.enter(label); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	read, err := io.ReadFull(s.r, buf)
+	s.pos += int64(read)
+	if s.record != nil {
+		s.record.Write(buf[:read])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// skip 跳过 n 个字节
+func (s *binaryScanner) skip(label string, n int64) error {
+	if err := s.enter(label); err != nil {
+		return err
+	}
+	if n < 0 {
+		return s.errorf("negative length %d", n)
+	}
+	if s.record == nil && s.seeker != nil && n > int64(s.r.Buffered()) {
+		return s.seek(n)
+	}
+	var w io.Writer = io.Discard
+	if s.record != nil {
+		w = s.record
+	}
+	skipped, err := io.CopyN(w, s.r, n)
+	s.pos += skipped
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// seek 通过 Seek 跳过 n 个字节，超出数据末尾时停在末尾
+func (s *binaryScanner) seek(n int64) error {
+	truncated := s.size >= 0 && s.pos+n > s.size
+	if truncated {
+		n = s.size - s.pos
+	}
+	buffered := int64(s.r.Buffered())
+	if _, err := s.seeker.Seek(n-buffered, io.SeekCurrent); err != nil {
+		return err
+	}
+	s.r.Reset(s.seeker)
+	s.pos += n
+	if truncated {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// byte 读取一个字节
+func (s *binaryScanner) byte(label string) (byte, error) {
+	buf, err := s.bytes(label, 1)
+	if err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+// int32 读取小端序的 int32
+func (s *binaryScanner) int32(label string) (int32, error) {
+	buf, err := s.bytes(label, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(buf)), nil
+}
+
+// float32 读取小端序的 float32
+func (s *binaryScanner) float32(label string) (float32, error) {
+	buf, err := s.bytes(label, 4)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(buf)), nil
+}
+
+// count 读取数量，负数视为文件损坏
+func (s *binaryScanner) count(label string) (int, error) {
+	n, err := s.int32(label)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, s.errorf("negative count %d", n)
+	}
+	return int(n), nil
+}
+
+// string 读取 7 位编码长度的字符串
+func (s *binaryScanner) string(label string) (string, error) {
+	if err := s.enter(label); err != nil {
+		return "", err
+	}
+	var length int64
+	for shift := 0; ; shift += 7 {
+		if shift >= 35 {
+			return "", s.errorf("invalid string length")
+		}
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return "", io.ErrUnexpectedEOF
+		}
+		s.pos++
+		if s.record != nil {
+			s.record.WriteByte(b)
+		}
+		length |= int64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	// 长度来自文件内容，文件损坏时可能非常大，先检查再分配
+	if s.size >= 0 && length > s.size-s.pos {
+		return "", s.errorf("string length %d exceeds the remaining %d bytes", length, s.size-s.pos)
+	}
+	if s.size < 0 && length > maxStringLength {
+		return "", s.errorf("string length %d exceeds the limit of %d bytes", length, maxStringLength)
+	}
+	buf := make([]byte, length)
+	read, err := io.ReadFull(s.r, buf)
+	s.pos += int64(read)
+	if s.record != nil {
+		s.record.Write(buf[:read])
+	}
+	if err != nil {
+		return "", io.ErrUnexpectedEOF
+	}
+	return string(buf), nil
+}
+
+// appendString 按 7 位编码长度的格式写出字符串
+func appendString(buf *bytes.Buffer, str string) {
+	length := uint32(len(str))
+	for length >= 0x80 {
+		buf.WriteByte(byte(length) | 0x80)
+		length >>= 7
+	}
+	buf.WriteByte(byte(length))
+	buf.WriteString(str)
+}
+
+// scanHeader 读取所有格式开头的签名和版本
+func scanHeader(s *binaryScanner) (signature string, version int32, err error) {
+	if signature, err = s.string("signature"); err != nil {
+		return
+	}
+	s.signature = signature
+	if version, err = s.int32("version"); err != nil {
+		return
+	}
+	s.version = version
+	return
+}
+
+// scanMenu .menu 的结构：文件头、命令列表，参数数量为 0 的命令表示结束
+func scanMenu(s *binaryScanner) error {
+	if _, _, err := scanHeader(s); err != nil {
+		return err
+	}
+	for _, label := range []string{"source file name", "item name", "category", "info text"} {
+		if _, err := s.string(label); err != nil {
+			return err
+		}
+	}
+	if _, err := s.int32("body size"); err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		argCount, err := s.byte(fmt.Sprintf("command %d argument count", i))
+		if err != nil {
+			return err
+		}
+		if argCount == 0 {
+			return nil
+		}
+		for j := 0; j < int(argCount); j++ {
+			if _, err := s.string(fmt.Sprintf("command %d argument %d", i, j)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scanMate .mate 的结构：文件头、名称、材质
+func scanMate(s *binaryScanner) error {
+	if _, _, err := scanHeader(s); err != nil {
+		return err
+	}
+	if _, err := s.string("name"); err != nil {
+		return err
+	}
+	return scanMaterial(s, "material")
+}
+
+// 材质属性的类型标签，二进制中每个属性以标签开头，JSON 中作为 TypeName，两者相同
+// polymorphicFields 和 scanPropertyValue 共用这些常量，避免两边拼写不一致
+const (
+	propertyTagTex       = "tex"
+	propertyTagCol       = "col"
+	propertyTagVec       = "vec"
+	propertyTagF         = "f"
+	propertyTagRange     = "range"
+	propertyTagTexOffset = "tex_offset"
+	propertyTagTexScale  = "tex_scale"
+	propertyTagKeyword   = "keyword"
+	propertyTagEnd       = "end" // 属性列表结束
+)
+
+// tex 属性的纹理类型标签
+const (
+	texSubTagNull = "null"
+	texSubTag2D   = "tex2d"
+	texSubTagCube = "cube"
+	texSubTagRT   = "texRT"
+)
+
+// scanMaterial 材质的结构：名称、着色器、属性列表，属性以类型名称开头，end 表示结束
+// prefix 为部分名称的前缀，例如 material 2
+func scanMaterial(s *binaryScanner, prefix string) error {
+	for _, label := range []string{"name", "shader name", "shader filename"} {
+		if _, err := s.string(prefix + " " + label); err != nil {
+			return err
+		}
+	}
+	for i := 0; ; i++ {
+		property := fmt.Sprintf("%s property %d", prefix, i)
+		typeName, err := s.string(property + " type")
+		if err != nil {
+			return err
+		}
+		if typeName == propertyTagEnd {
+			return nil
+		}
+		if _, err := s.string(property + " name"); err != nil {
+			return err
+		}
+		if err := scanPropertyValue(s, property, typeName); err != nil {
+			return err
+		}
+	}
+}
+
+// scanPropertyValue 跳过属性名称之后的值
+func scanPropertyValue(s *binaryScanner, property string, typeName string) error {
+	switch typeName {
+	case propertyTagTex:
+		subTag, err := s.string(property + " texture type")
+		if err != nil {
+			return err
+		}
+		switch subTag {
+		case texSubTagNull:
+			return nil
+		case texSubTag2D, texSubTagCube:
+			for _, label := range []string{" texture name", " texture path"} {
+				if _, err := s.string(property + label); err != nil {
+					return err
+				}
+			}
+			return s.skip(property+" texture offset and scale", 16)
+		case texSubTagRT:
+			for _, label := range []string{" render texture", " render texture format"} {
+				if _, err := s.string(property + label); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return s.errorf("unknown texture type %q", subTag)
+		}
+	case propertyTagCol, propertyTagVec:
+		return s.skip(property+" value", 16)
+	case propertyTagF, propertyTagRange:
+		return s.skip(property+" value", 4)
+	case propertyTagTexOffset, propertyTagTexScale:
+		return s.skip(property+" value", 8)
+	case propertyTagKeyword:
+		n, err := s.count(property + " keyword count")
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if _, err := s.string(fmt.Sprintf("%s keyword %d name", property, i)); err != nil {
+				return err
+			}
+			if err := s.skip(fmt.Sprintf("%s keyword %d value", property, i), 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return s.errorf("unknown property type %q", typeName)
+	}
+}
@@ -0,0 +1,188 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// 测试用的二进制数据构造函数，格式与 MeidoSerialization 写出的一致
+
+// fixtureFloats 写入若干个 float32
+func fixtureFloats(buf *bytes.Buffer, values ...float32) {
+	binary.Write(buf, binary.LittleEndian, values)
+}
+
+// fixtureProperty 构造一个材质属性，value 写入属性名称之后的值
+func fixtureProperty(typeName string, name string, value func(buf *bytes.Buffer)) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, typeName)
+	appendString(&buf, name)
+	value(&buf)
+	return buf.Bytes()
+}
+
+// fixtureAllProperties 每种属性类型和每种纹理类型各一个
+func fixtureAllProperties() map[string][]byte {
+	return map[string][]byte{
+		"tex null": fixtureProperty(propertyTagTex, "_ToonRamp", func(buf *bytes.Buffer) {
+			appendString(buf, texSubTagNull)
+		}),
+		"tex tex2d": fixtureProperty(propertyTagTex, "_MainTex", func(buf *bytes.Buffer) {
+			appendString(buf, texSubTag2D)
+			appendString(buf, "body")
+			appendString(buf, "Assets/texture/body.png")
+			fixtureFloats(buf, 0, 0, 1, 1)
+		}),
+		"tex cube": fixtureProperty(propertyTagTex, "_Cube", func(buf *bytes.Buffer) {
+			appendString(buf, texSubTagCube)
+			appendString(buf, "cube")
+			appendString(buf, "Assets/texture/cube.png")
+			fixtureFloats(buf, 0.5, 0.5, 2, 2)
+		}),
+		"tex texRT": fixtureProperty(propertyTagTex, "_RenderTex", func(buf *bytes.Buffer) {
+			appendString(buf, texSubTagRT)
+			appendString(buf, "")
+			appendString(buf, "")
+		}),
+		"col": fixtureProperty(propertyTagCol, "_Color", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 1, 0.5, 0.25, 1)
+		}),
+		"vec": fixtureProperty(propertyTagVec, "_Vector", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 1, 2, 3, 4)
+		}),
+		"f": fixtureProperty(propertyTagF, "_Shininess", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 0.75)
+		}),
+		"range": fixtureProperty(propertyTagRange, "_Cutoff", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 0.5)
+		}),
+		"tex_offset": fixtureProperty(propertyTagTexOffset, "_MainTex", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 0.25, 0.5)
+		}),
+		"tex_scale": fixtureProperty(propertyTagTexScale, "_MainTex", func(buf *bytes.Buffer) {
+			fixtureFloats(buf, 2, 3)
+		}),
+		"keyword": fixtureProperty(propertyTagKeyword, "_Keywords", func(buf *bytes.Buffer) {
+			binary.Write(buf, binary.LittleEndian, int32(2))
+			appendString(buf, "_ALPHATEST_ON")
+			buf.WriteByte(1)
+			appendString(buf, "_NORMALMAP")
+			buf.WriteByte(0)
+		}),
+	}
+}
+
+// fixtureMaterial 构造材质：名称、着色器、属性列表和结束标记
+func fixtureMaterial(name string, properties ...[]byte) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, name)
+	appendString(&buf, "CM3D2/Toony_Lighted_Trans")
+	appendString(&buf, "toony_lighted_trans")
+	for _, property := range properties {
+		buf.Write(property)
+	}
+	appendString(&buf, propertyTagEnd)
+	return buf.Bytes()
+}
+
+func TestScanMaterialPropertyTypes(t *testing.T) {
+	for name, property := range fixtureAllProperties() {
+		t.Run(name, func(t *testing.T) {
+			data := fixtureMaterial("test", property)
+			s := newBinaryScanner(bytes.NewReader(data), -1)
+			if err := scanMaterial(s, "material"); err != nil {
+				t.Fatalf("scanMaterial: %v", err)
+			}
+			if s.pos != int64(len(data)) {
+				t.Errorf("scanned %d bytes, want %d", s.pos, len(data))
+			}
+		})
+	}
+}
+
+func TestScanMaterialErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		property []byte
+		want     string
+	}{
+		{
+			name:     "unknown property type",
+			property: fixtureProperty("texoffset", "_MainTex", func(buf *bytes.Buffer) { fixtureFloats(buf, 0, 0) }),
+			want:     `unknown property type "texoffset"`,
+		},
+		{
+			name: "unknown texture type",
+			property: fixtureProperty(propertyTagTex, "_MainTex", func(buf *bytes.Buffer) {
+				appendString(buf, "tex3d")
+			}),
+			want: `unknown texture type "tex3d"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := fixtureMaterial("test", tt.property)
+			err := scanMaterial(newBinaryScanner(bytes.NewReader(data), -1), "material")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("scanMaterial error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanMaterialTruncated(t *testing.T) {
+	var properties [][]byte
+	for _, property := range fixtureAllProperties() {
+		properties = append(properties, property)
+	}
+	data := fixtureMaterial("test", properties...)
+	for _, n := range []int{0, 1, len(data) / 2, len(data) - 1} {
+		s := newBinaryScanner(bytes.NewReader(data[:n]), -1)
+		if err := scanMaterial(s, "material"); err == nil {
+			t.Errorf("scanMaterial of %d/%d bytes succeeded", n, len(data))
+		}
+	}
+}
+
+// onlyReader 隐藏 Seek，数据大小未知
+type onlyReader struct{ r *bytes.Reader }
+
+func (r onlyReader) Read(p []byte) (int, error) { return r.r.Read(p) }
+
+// 损坏的字符串长度不能导致按该长度分配内存
+func TestScanStringLength(t *testing.T) {
+	// 长度 2^34，7 位编码为 5 个字节
+	huge := []byte{0x80, 0x80, 0x80, 0x80, 0x40, 'a', 'b'}
+	var short bytes.Buffer
+	appendString(&short, "abc")
+	truncated := short.Bytes()[:3]
+
+	tests := []struct {
+		name string
+		r    io.Reader
+		want string
+	}{
+		{"huge known size", bytes.NewReader(huge), "string length 17179869184 exceeds the remaining 2 bytes"},
+		{"huge unknown size", onlyReader{bytes.NewReader(huge)}, "string length 17179869184 exceeds the limit"},
+		{"truncated known size", bytes.NewReader(truncated), "string length 3 exceeds the remaining 2 bytes"},
+		{"truncated unknown size", onlyReader{bytes.NewReader(truncated)}, io.ErrUnexpectedEOF.Error()},
+		{"invalid length", bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x01}), "invalid string length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newBinaryScanner(tt.r, -1).string("name")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("string error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	for _, r := range []io.Reader{bytes.NewReader(short.Bytes()), onlyReader{bytes.NewReader(short.Bytes())}} {
+		if got, err := newBinaryScanner(r, -1).string("name"); err != nil || got != "abc" {
+			t.Errorf("string = %q, %v", got, err)
+		}
+	}
+}
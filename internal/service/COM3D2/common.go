@@ -3,8 +3,8 @@ package COM3D2
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
 	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/utilities"
 	"github.com/MeidoPromotionAssociation/MeidoSerialization/tools"
 	"io"
@@ -23,42 +23,18 @@ const (
 const (
 	FormatBinary = "binary"
 	FormatJSON   = "json"
+	FormatYAML   = "yaml"
 )
 
-// 文件类型集合，用于判断文件类型
-var fileTypeSet = map[string]struct{}{
-	"menu":  {},
-	"mate":  {},
-	"pmat":  {},
-	"col":   {},
-	"phy":   {},
-	"psk":   {},
-	"tex":   {},
-	"anm":   {},
-	"model": {},
-}
-
-// 文件签名映射，用于判断文件类型
-var fileSignatureMap = map[string]string{
-	COM3D2.MenuSignature:   "menu",
-	COM3D2.MateSignature:   "mate",
-	COM3D2.PMatSignature:   "pmat",
-	COM3D2.ColSignature:    "col",
-	COM3D2.PhySignature:    "phy",
-	COM3D2.PskSignature:    "psk",
-	COM3D2.TexSignature:    "tex",
-	COM3D2.ModelSignature:  "model",
-	COM3D2.AnmSignature:    "anm",
-	COM3D2.PresetSignature: "preset",
-	COM3D2.SaveSignature:   "save",
-}
+// ErrUnsupportedFileType 文件类型无法识别，或该类型不支持请求的操作
+var ErrUnsupportedFileType = errors.New("unsupported file type")
 
 type CommonService struct{}
 
 // FileInfo 用于表示文件类型的结构
 type FileInfo struct {
 	FileType      string `json:"FileType"`      // 文件类型名称
-	StorageFormat string `json:"StorageFormat"` // 用于区分二进制和 JSON 格式 binary/json，见顶部常量定义
+	StorageFormat string `json:"StorageFormat"` // 用于区分二进制、JSON 和 YAML 格式 binary/json/yaml，见顶部常量定义
 	Game          string `json:"Game"`          // 游戏名称 COM3D2/KCES，见顶部常量定义
 	Signature     string `json:"Signature"`     // 文件签名
 	Version       int32  `json:"Version"`       // 文件版本
@@ -72,14 +48,14 @@ type FileHeader struct {
 	Version   int32  `json:"Version"`
 }
 
-// FileTypeDetermine 判断文件类型，支持二进制和 JSON 格式
+// FileTypeDetermine 判断文件类型，支持二进制、JSON 和 YAML 格式
 // strictMode 为 true 时，严格按照文件内容判断文件类型
 // strictMode 为 false 时，优先根据文件后缀判断文件类型，如果无法判断再根据文件内容判断
 func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInfo FileInfo, err error) {
 	fileInfo.Path = path
 
-	// 打开文件
-	f, err := os.Open(path)
+	// 打开文件，支持压缩包内的虚拟路径
+	f, err := openFile(path)
 	if err != nil {
 		return fileInfo, err
 	}
@@ -94,16 +70,19 @@ func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInf
 
 	// 非严格模式下，优先根据文件后缀判断文件类型
 	ext := strings.ToLower(filepath.Ext(path))
-	// 去掉开头的点
-	ext = ext[1:]
+	// 去掉开头的点，没有扩展名时为空字符串
+	ext = strings.TrimPrefix(ext, ".")
 	if !strictMode {
 		if ext != "" {
 			if ext == "json" {
 				return parseJSONFileType(f, fileInfo)
 			}
+			if ext == "yaml" || ext == "yml" {
+				return parseYAMLFileType(f, fileInfo)
+			}
 
 			// 检查是否是已知的文件类型
-			_, exists := fileTypeSet[ext]
+			_, exists := FormatByFileType(ext)
 			if exists {
 				// 根据扩展名设置文件类型信息
 				fileInfo.FileType = ext
@@ -113,13 +92,13 @@ func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInf
 				// 尝试打开文件获取实际签名和版本
 				signature, readErr := utilities.ReadString(f)
 				if readErr != nil {
-					fmt.Printf("Warning: Failed to read signature from file %s: %v\n", path, readErr)
+					fmt.Fprintf(os.Stderr, "Warning: Failed to read signature from file %s: %v\n", path, readErr)
 					return fileInfo, nil //读取失败也不返回错误，因为是非严格模式
 				}
 				fileInfo.Signature = signature
 				version, readErr := utilities.ReadInt32(f)
 				if readErr != nil {
-					fmt.Printf("Warning: Failed to read version from file %s: %v\n", path, readErr)
+					fmt.Fprintf(os.Stderr, "Warning: Failed to read version from file %s: %v\n", path, readErr)
 					return fileInfo, nil
 				}
 				fileInfo.Version = version
@@ -130,9 +109,8 @@ func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInf
 
 	// 严格模式或者通过扩展名无法判断时，根据文件内容判断
 
-	// 检查是否为支持的图片类型
-	imageErr := tools.IsSupportedImageType(path)
-	if imageErr == nil {
+	// 检查是否为支持的图片类型，ImageMagick 无法读取压缩包内的文件
+	if !IsArchivePath(path) && tools.IsSupportedImageType(path) == nil {
 		// 设置为图片类型
 		fileInfo.FileType = "image"
 		fileInfo.StorageFormat = FormatBinary
@@ -151,12 +129,15 @@ func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInf
 	_, err = f.Seek(0, 0)
 	if err != nil {
 		// 如果重置失败，回退到使用已读取的数据创建 Reader
-		fmt.Printf("Warning: Failed to seek file %s to beginning: %v. Using buffer instead.\n", path, err)
+		fmt.Fprintf(os.Stderr, "Warning: Failed to seek file %s to beginning: %v. Using buffer instead.\n", path, err)
 		// 先检查是否为 JSON 格式
 		if bytes.HasPrefix(bytes.TrimSpace(headerBytes), []byte{'{'}) {
 			var r io.Reader = bytes.NewReader(headerBytes)
 			return parseJSONFileType(r, fileInfo)
 		}
+		if looksLikeYAML(headerBytes) {
+			return parseYAMLFileType(bytes.NewReader(headerBytes), fileInfo)
+		}
 		// 如果不是 JSON，按二进制格式处理
 		var rs io.ReadSeeker = bytes.NewReader(headerBytes)
 		return readBinaryFileType(rs, fileInfo)
@@ -164,10 +145,14 @@ func (m *CommonService) FileTypeDetermine(path string, strictMode bool) (fileInf
 
 	// 检查文件是否为 JSON 格式 (简单判断是否以'{'开头)
 	if bytes.HasPrefix(bytes.TrimSpace(headerBytes), []byte{'{'}) {
-		fmt.Printf("File %s is detected as JSON format.\n", path)
 		return parseJSONFileType(f, fileInfo)
 	}
 
+	// 检查文件是否为 YAML 格式
+	if looksLikeYAML(headerBytes) {
+		return parseYAMLFileType(f, fileInfo)
+	}
+
 	// 使用重置后的文件指针读取
 	return readBinaryFileType(f, fileInfo)
 }
@@ -274,6 +259,38 @@ func parseJSONFileType(f io.Reader, fileInfo FileInfo) (FileInfo, error) {
 	return fileInfo, nil
 }
 
+// looksLikeYAML 简单判断是否为 YAML 格式：以文档开始标记 --- 或 Signature 键开头
+// 二进制文件以签名长度开头，不会出现这两种情况
+func looksLikeYAML(header []byte) bool {
+	trimmed := bytes.TrimSpace(header)
+	return bytes.HasPrefix(trimmed, []byte("---")) || bytes.HasPrefix(trimmed, []byte("Signature:"))
+}
+
+// parseYAMLFileType 解析 YAML 格式的文件类型
+// YAML 无法像 JSON 那样只读取开头的字段，需要解析整个文件
+func parseYAMLFileType(f io.Reader, fileInfo FileInfo) (FileInfo, error) {
+	fileInfo.StorageFormat = FormatYAML
+
+	raw, err := io.ReadAll(f)
+	if err != nil {
+		return fileInfo, err
+	}
+	raw, err = yamlToJson(raw)
+	if err != nil {
+		return fileInfo, fmt.Errorf("file mark as yaml, but unable to parse it: %v", err)
+	}
+	var header FileHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return fileInfo, fmt.Errorf("failed to parse the YAML file header: %v", err)
+	}
+
+	fileInfo.Game = GameCOM3D2
+	if header.Signature == "" {
+		return fileInfo, nil
+	}
+	return mapJSONToFileType(header, fileInfo)
+}
+
 // skipValue 跳过当前 JSON 值，无论它是对象、数组还是基本类型
 func skipValue(decoder *json.Decoder) error {
 	// 使用 RawMessage 来有效地跳过当前值
@@ -283,10 +300,10 @@ func skipValue(decoder *json.Decoder) error {
 
 // fileTypeMapping 根据文件签名返回对应的文件类型
 func fileTypeMapping(signature string) (string, error) {
-	if fileType, exists := fileSignatureMap[signature]; exists {
-		return fileType, nil
+	if f, exists := FormatBySignature(signature); exists {
+		return f.FileType(), nil
 	}
-	return "", fmt.Errorf("unknown file type with signature: %s", signature)
+	return "", fmt.Errorf("%w: unknown file type with signature: %s", ErrUnsupportedFileType, signature)
 }
 
 // mapJSONToFileType 根据 JSON 头信息映射到对应的文件类型
@@ -302,3 +319,39 @@ func mapJSONToFileType(header FileHeader, fileInfo FileInfo) (FileInfo, error) {
 
 	return fileInfo, nil
 }
+
+// ConvertToJson 根据文件类型将二进制文件转换为 .json 文件，outputPath 以 .yaml 或 .yml 结尾时转换为 YAML
+// fileType 为 FileTypeDetermine 返回的 FileType
+func (m *CommonService) ConvertToJson(fileType string, inputPath string, outputPath string) error {
+	f, ok := FormatByFileType(fileType)
+	if !ok {
+		return fmt.Errorf("%w: %q cannot be converted to JSON", ErrUnsupportedFileType, fileType)
+	}
+	return f.ConvertToJson(inputPath, outputPath)
+}
+
+// ConvertFromJson 根据文件类型将 .json 或 .yaml 文件转换回二进制文件
+// fileType 为 FileTypeDetermine 返回的 FileType
+func (m *CommonService) ConvertFromJson(fileType string, inputPath string, outputPath string) error {
+	f, ok := FormatByFileType(fileType)
+	if !ok {
+		return fmt.Errorf("%w: %q cannot be converted from JSON", ErrUnsupportedFileType, fileType)
+	}
+	return f.ConvertFromJson(inputPath, outputPath)
+}
+
+// GetSupportedFormats 返回所有已注册的文件格式
+func (m *CommonService) GetSupportedFormats() []FormatInfo {
+	handlers := Formats()
+	infos := make([]FormatInfo, 0, len(handlers))
+	for _, f := range handlers {
+		infos = append(infos, FormatInfo{
+			FileType:      f.FileType(),
+			Signature:     f.Signature(),
+			Extension:     f.Extension(),
+			SupportsJSON:  f.SupportsJSON(),
+			SupportsWrite: f.SupportsWrite(),
+		})
+	}
+	return infos
+}
@@ -0,0 +1,323 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 游戏按文件名（不区分大小写，不含目录）加载文件，因此依赖分析也按文件名解析引用
+// .menu 通过命令参数引用其他文件，例如 additem 引用 .model，マテリアル変更 引用 .mate，tex/icon 引用 .tex
+// .mate 和 .model 中的材质通过 Tex2D.Name 引用 .tex
+// .pmat 没有被显式引用，游戏根据材质名称匹配 .pmat 的 MaterialName
+
+// DependencyService 分析 MOD 目录中文件之间的引用关系
+type DependencyService struct{}
+
+// DependencyOptions 依赖分析选项
+type DependencyOptions struct {
+	// SearchDirs 额外的查找目录，例如解包后的游戏文件或其他 MOD
+	// 其中的文件只用于解析引用，不会被解析，也不参与未使用文件检查
+	SearchDirs []string `json:"SearchDirs"`
+}
+
+// DependencyNode 参与分析的一个文件
+type DependencyNode struct {
+	Path     string `json:"Path"`
+	Name     string `json:"Name"`     // 小写文件名，游戏按此查找文件
+	FileType string `json:"FileType"` // 根据扩展名判断的文件类型
+	External bool   `json:"External"` // 是否来自 SearchDirs
+}
+
+// DependencyEdge 一条引用
+type DependencyEdge struct {
+	From     string `json:"From"`     // 引用方文件路径
+	Name     string `json:"Name"`     // 被引用的文件名，保留原文中的写法
+	Target   string `json:"Target"`   // 解析到的文件路径，找不到时为空
+	Kind     string `json:"Kind"`     // 引用来源，.menu 中为命令名称，材质中为 texture 或 pmat
+	Implicit bool   `json:"Implicit"` // 隐式引用（例如按材质名称匹配 .pmat），找不到时不视为缺失
+}
+
+// NameCollision 多个文件使用同一个文件名，游戏只会加载其中一个
+type NameCollision struct {
+	Name  string   `json:"Name"`
+	Paths []string `json:"Paths"`
+}
+
+// DependencyReport 依赖分析结果
+type DependencyReport struct {
+	Root       string           `json:"Root"`
+	Files      []DependencyNode `json:"Files"`
+	Edges      []DependencyEdge `json:"Edges"`
+	Missing    []DependencyEdge `json:"Missing"`    // 找不到目标文件的显式引用
+	Unused     []string         `json:"Unused"`     // Root 中没有被任何文件引用的文件，.menu 是入口，不会出现在这里
+	Collisions []NameCollision  `json:"Collisions"` // 至少有一个文件在 Root 中的同名文件
+	Errors     []BatchFileError `json:"Errors"`     // 解析失败的文件，这些文件中的引用不会出现在结果中
+}
+
+// 引用文件时会在命令参数中省略扩展名的 .menu 命令，值为参数位置
+var menuTexCommands = map[string]int{
+	"tex":     4,
+	"テクスチャ変更": 4,
+	"icon":    1,
+	"icons":   1,
+}
+
+// dependencyIgnoredTypes 不会被其他文件引用的类型
+var dependencyIgnoredTypes = map[string]bool{
+	"preset": true,
+	"save":   true,
+}
+
+// AnalyzeDependencies 递归分析目录中所有 .menu、.mate、.model 的引用，返回引用图、缺失引用、未使用文件和同名文件
+func (d *DependencyService) AnalyzeDependencies(dir string, options DependencyOptions) (*DependencyReport, error) {
+	report := &DependencyReport{Root: dir}
+
+	if err := collectDependencyNodes(dir, false, &report.Files); err != nil {
+		return nil, err
+	}
+	for _, searchDir := range options.SearchDirs {
+		if err := collectDependencyNodes(searchDir, true, &report.Files); err != nil {
+			return nil, err
+		}
+	}
+
+	byName := map[string][]DependencyNode{}
+	for _, node := range report.Files {
+		byName[node.Name] = append(byName[node.Name], node)
+	}
+
+	// 先解析所有 .pmat，用于按材质名称匹配
+	pmatByMaterial := map[string]string{}
+	for _, node := range report.Files {
+		if node.External || node.FileType != "pmat" {
+			continue
+		}
+		pmat, err := pmatFormat.ReadFile(node.Path)
+		if err != nil {
+			report.Errors = append(report.Errors, BatchFileError{Path: node.Path, FileType: node.FileType, Error: err.Error()})
+			continue
+		}
+		pmatByMaterial[strings.ToLower(pmat.MaterialName)] = node.Path
+	}
+
+	resolve := func(name string) string {
+		candidates := byName[referenceName(name)]
+		if len(candidates) == 0 {
+			return ""
+		}
+		// 优先使用 Root 中的文件
+		for _, c := range candidates {
+			if !c.External {
+				return c.Path
+			}
+		}
+		return candidates[0].Path
+	}
+	addEdge := func(from string, name string, kind string) {
+		report.Edges = append(report.Edges, DependencyEdge{From: from, Name: name, Target: resolve(name), Kind: kind})
+	}
+	addMaterial := func(from string, material *COM3D2.Material) {
+		if material == nil {
+			return
+		}
+		for _, texName := range materialTextureNames(material) {
+			addEdge(from, texName, "texture")
+		}
+		if target, ok := pmatByMaterial[strings.ToLower(material.Name)]; ok {
+			report.Edges = append(report.Edges, DependencyEdge{From: from, Name: filepath.Base(target), Target: target, Kind: "pmat", Implicit: true})
+		}
+	}
+
+	for _, node := range report.Files {
+		if node.External {
+			continue
+		}
+		var err error
+		switch node.FileType {
+		case "menu":
+			var menu *COM3D2.Menu
+			if menu, err = menuFormat.ReadFile(node.Path); err == nil {
+				for _, ref := range menuReferences(menu) {
+					addEdge(node.Path, ref.name, ref.kind)
+				}
+			}
+		case "mate":
+			var mate *COM3D2.Mate
+			if mate, err = mateFormat.ReadFile(node.Path); err == nil {
+				addMaterial(node.Path, mate.Material)
+			}
+		case "model":
+			// 只需要材质，二进制文件跳过网格数据
+			var materials []*COM3D2.Material
+			if materials, err = (&ModelService{}).ReadModelMaterial(node.Path); err == nil {
+				for _, material := range materials {
+					addMaterial(node.Path, material)
+				}
+			}
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, BatchFileError{Path: node.Path, FileType: node.FileType, Error: err.Error()})
+		}
+	}
+
+	// 缺失和未使用
+	referenced := map[string]bool{}
+	for _, edge := range report.Edges {
+		if edge.Target != "" {
+			referenced[edge.Target] = true
+		} else if !edge.Implicit {
+			report.Missing = append(report.Missing, edge)
+		}
+	}
+	for _, node := range report.Files {
+		if !node.External && node.FileType != "menu" && !referenced[node.Path] {
+			report.Unused = append(report.Unused, node.Path)
+		}
+	}
+
+	// 同名文件
+	for name, nodes := range byName {
+		if len(nodes) < 2 {
+			continue
+		}
+		collision := NameCollision{Name: name}
+		inRoot := false
+		for _, node := range nodes {
+			collision.Paths = append(collision.Paths, node.Path)
+			inRoot = inRoot || !node.External
+		}
+		if inRoot {
+			report.Collisions = append(report.Collisions, collision)
+		}
+	}
+
+	sort.Slice(report.Collisions, func(i, j int) bool {
+		return report.Collisions[i].Name < report.Collisions[j].Name
+	})
+	sort.Strings(report.Unused)
+	return report, nil
+}
+
+// collectDependencyNodes 递归收集目录中已知类型的二进制文件，按路径排序
+func collectDependencyNodes(dir string, external bool, nodes *[]DependencyNode) error {
+	var collected []DependencyNode
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() || textSuffix(p) != "" {
+			return nil
+		}
+		f, ok := FormatByPath(p)
+		if !ok || dependencyIgnoredTypes[f.FileType()] {
+			return nil
+		}
+		collected = append(collected, DependencyNode{
+			Path:     p,
+			Name:     strings.ToLower(d.Name()),
+			FileType: f.FileType(),
+			External: external,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk directory %s: %w", dir, err)
+	}
+	sort.Slice(collected, func(i, j int) bool {
+		return collected[i].Path < collected[j].Path
+	})
+	*nodes = append(*nodes, collected...)
+	return nil
+}
+
+// menuReference .menu 中的一条引用
+type menuReference struct {
+	name string
+	kind string
+}
+
+// menuReferences 返回 .menu 命令参数中引用的文件
+// 带有已知扩展名的参数都视为引用，tex/icon 等命令的参数省略扩展名时补上 .tex
+func menuReferences(menu *COM3D2.Menu) []menuReference {
+	var refs []menuReference
+	for _, cmd := range menu.Commands {
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		kind := cmd.Args[0]
+		for i, arg := range cmd.Args[1:] {
+			arg = strings.TrimSpace(arg)
+			if arg == "" {
+				continue
+			}
+			if f, ok := FormatByPath(arg); ok && !dependencyIgnoredTypes[f.FileType()] && textSuffix(arg) == "" {
+				refs = append(refs, menuReference{name: arg, kind: kind})
+				continue
+			}
+			if texArg, ok := menuTexCommands[strings.ToLower(kind)]; ok && texArg == i+1 && path.Ext(arg) == "" {
+				refs = append(refs, menuReference{name: arg + ".tex", kind: kind})
+			}
+		}
+	}
+	return refs
+}
+
+// materialTextureNames 返回材质中引用的 .tex 文件名
+// 材质属性是多种类型的接口切片，这里通过 JSON 遍历所有 Tex2D 子属性，避免依赖具体类型
+func materialTextureNames(material *COM3D2.Material) []string {
+	raw, err := json.Marshal(material.Properties)
+	if err != nil {
+		return nil
+	}
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil
+	}
+
+	var names []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case []interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		case map[string]interface{}:
+			if tex2D, ok := value["Tex2D"].(map[string]interface{}); ok {
+				name, _ := tex2D["Name"].(string)
+				if name == "" {
+					if p, _ := tex2D["Path"].(string); p != "" {
+						base := path.Base(strings.ReplaceAll(p, "\\", "/"))
+						name = strings.TrimSuffix(base, path.Ext(base))
+					}
+				}
+				if name != "" {
+					names = append(names, name+".tex")
+				}
+			}
+			// map 的遍历顺序不固定，按键排序以保证报告可以复现
+			keys := make([]string, 0, len(value))
+			for key := range value {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				walk(value[key])
+			}
+		}
+	}
+	walk(tree)
+	return names
+}
+
+// referenceName 将引用转换为游戏查找文件时使用的名称：去掉目录，转为小写
+func referenceName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.ToLower(path.Base(name))
+}
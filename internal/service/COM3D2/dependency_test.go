@@ -0,0 +1,212 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixtureMenu 构造 .menu，每个命令为参数列表
+func fixtureMenu(commands ...[]string) []byte {
+	var body bytes.Buffer
+	for _, args := range commands {
+		body.WriteByte(byte(len(args)))
+		for _, arg := range args {
+			appendString(&body, arg)
+		}
+	}
+	body.WriteByte(0)
+
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.MenuSignature)
+	binary.Write(&buf, binary.LittleEndian, int32(1000))
+	for _, s := range []string{"test", "test", "wear", ""} {
+		appendString(&buf, s)
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(body.Len()))
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+// fixtureMate 构造 .mate，material 为 fixtureMaterial 的结果
+func fixtureMate(material []byte) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.MateSignature)
+	binary.Write(&buf, binary.LittleEndian, int32(1000))
+	appendString(&buf, "test")
+	buf.Write(material)
+	return buf.Bytes()
+}
+
+// fixturePMat 构造 .pmat
+func fixturePMat(materialName string) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.PMatSignature)
+	binary.Write(&buf, binary.LittleEndian, int32(1000))
+	binary.Write(&buf, binary.LittleEndian, int32(0))
+	appendString(&buf, materialName)
+	fixtureFloats(&buf, 3000)
+	appendString(&buf, "CM3D2/Toony_Lighted_Trans")
+	return buf.Bytes()
+}
+
+// fixtureTexProperty 构造引用 texName 的 tex2d 属性
+func fixtureTexProperty(name string, texName string) []byte {
+	return fixtureProperty(propertyTagTex, name, func(buf *bytes.Buffer) {
+		appendString(buf, texSubTag2D)
+		appendString(buf, texName)
+		appendString(buf, "Assets/texture/"+texName+".png")
+		fixtureFloats(buf, 0, 0, 1, 1)
+	})
+}
+
+// writeFiles 将 files 写入 dir，键为相对路径
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMenuReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []menuReference
+	}{
+		{"additem", []string{"additem", "body.model", "body"}, []menuReference{{"body.model", "additem"}}},
+		{"material change", []string{"マテリアル変更", "body", "0", "body_skin.mate"}, []menuReference{{"body_skin.mate", "マテリアル変更"}}},
+		{"tex without extension", []string{"tex", "body", "0", "_MainTex", "body_tex"}, []menuReference{{"body_tex.tex", "tex"}}},
+		{"tex with extension", []string{"テクスチャ変更", "body", "0", "_MainTex", "skin.tex"}, []menuReference{{"skin.tex", "テクスチャ変更"}}},
+		{"tex extra arguments", []string{"tex", "body", "0", "_MainTex", "body_tex", "pal"}, []menuReference{{"body_tex.tex", "tex"}}},
+		{"tex only argument 4", []string{"tex", "body", "0", "_MainTex"}, nil},
+		{"icon", []string{"icon", "icon_a"}, []menuReference{{"icon_a.tex", "icon"}}},
+		{"icons", []string{"icons", "icon_b.tex"}, []menuReference{{"icon_b.tex", "icons"}}},
+		{"icons upper case", []string{"ICONS", "icon_c"}, []menuReference{{"icon_c.tex", "ICONS"}}},
+		{"icon only argument 1", []string{"icon", "a", "b"}, []menuReference{{"a.tex", "icon"}}},
+		{"trimmed", []string{"additem", " body.model "}, []menuReference{{"body.model", "additem"}}},
+		{"no file", []string{"name", "body"}, nil},
+		{"blank", []string{"additem", "  "}, nil},
+		{"text file", []string{"additem", "body.model.json"}, nil},
+		{"ignored type", []string{"preset", "a.preset"}, nil},
+		{"empty command", []string{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menu := &COM3D2.Menu{Commands: []COM3D2.Command{{ArgCount: uint8(len(tt.args)), Args: tt.args}}}
+			if got := menuReferences(menu); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("menuReferences(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaterialTextureNames(t *testing.T) {
+	const materialJson = `{"Name": "body", "ShaderName": "CM3D2/Toony_Lighted", "ShaderFilename": "toony_lighted", "Properties": [
+		{"TypeName": "tex", "PropName": "_MainTex", "SubTag": "tex2d", "Tex2D": {"Name": "body_tex", "Path": "Assets/texture/body_tex.png", "Offset": [0, 0], "Scale": [1, 1]}},
+		{"TypeName": "col", "PropName": "_Color", "Color": [1, 1, 1, 1]},
+		{"TypeName": "tex", "PropName": "_ToonRamp", "SubTag": "tex2d", "Tex2D": {"Name": "", "Path": "Assets\\texture\\toon\\ramp.png", "Offset": [0, 0], "Scale": [1, 1]}},
+		{"TypeName": "tex", "PropName": "_Cube", "SubTag": "cube", "Tex2D": {"Name": "cube_tex", "Path": "", "Offset": [0, 0], "Scale": [1, 1]}},
+		{"TypeName": "tex", "PropName": "_Empty", "SubTag": "null"},
+		{"TypeName": "tex", "PropName": "_RenderTex", "SubTag": "texRT", "TexRT": {"DiscardedStr1": "", "DiscardedStr2": ""}}
+	]}`
+	var material COM3D2.Material
+	if err := json.Unmarshal([]byte(materialJson), &material); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"body_tex.tex", "ramp.tex", "cube_tex.tex"}
+	if got := materialTextureNames(&material); !reflect.DeepEqual(got, want) {
+		t.Errorf("materialTextureNames = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzeDependencies(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string][]byte{
+		"item.menu": fixtureMenu(
+			[]string{"name", "item"},
+			[]string{"additem", "item.model", "body"},
+			[]string{"マテリアル変更", "body", "0", "item.mate"},
+			[]string{"icons", "item_icon"},
+			[]string{"tex", "body", "0", "_MainTex", "shared"},
+			[]string{"tex", "body", "0", "_MainTex", "missing_tex"},
+			[]string{"additem", "Missing.model"},
+		),
+		"item.mate":        fixtureMate(fixtureMaterial("item_mat", fixtureTexProperty("_MainTex", "item_tex"))),
+		"item.model":       fixtureModel(fixtureMaterial("model_mat", fixtureTexProperty("_MainTex", "model_tex"))),
+		"item.pmat":        fixturePMat("item_mat"),
+		"item_tex.tex":     []byte("tex"),
+		"item_icon.tex":    []byte("tex"),
+		"model_tex.tex":    []byte("tex"),
+		"unused.tex":       []byte("tex"),
+		"sub/item_tex.tex": []byte("tex"),
+		"broken.mate":      []byte("broken"),
+		"item.menu.json":   []byte("{}"),
+	})
+	search := t.TempDir()
+	writeFiles(t, search, map[string][]byte{
+		"shared.tex": []byte("tex"),
+		"other.tex":  []byte("tex"),
+	})
+
+	report, err := (&DependencyService{}).AnalyzeDependencies(root, DependencyOptions{SearchDirs: []string{search}})
+	if err != nil {
+		t.Fatalf("AnalyzeDependencies: %v", err)
+	}
+	path := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+
+	var missing []string
+	for _, edge := range report.Missing {
+		if edge.From != path("item.menu") {
+			t.Errorf("missing reference from %s", edge.From)
+		}
+		missing = append(missing, edge.Name)
+	}
+	if want := []string{"missing_tex.tex", "Missing.model"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("Missing = %v, want %v", missing, want)
+	}
+
+	// .menu 是入口，SearchDirs 中的文件不参与未使用检查，同名文件只有第一个被引用
+	if want := []string{path("broken.mate"), path("sub/item_tex.tex"), path("unused.tex")}; !reflect.DeepEqual(report.Unused, want) {
+		t.Errorf("Unused = %v, want %v", report.Unused, want)
+	}
+
+	wantCollisions := []NameCollision{{Name: "item_tex.tex", Paths: []string{path("item_tex.tex"), path("sub/item_tex.tex")}}}
+	if !reflect.DeepEqual(report.Collisions, wantCollisions) {
+		t.Errorf("Collisions = %+v, want %+v", report.Collisions, wantCollisions)
+	}
+
+	if len(report.Errors) != 1 || report.Errors[0].Path != path("broken.mate") {
+		t.Errorf("Errors = %+v", report.Errors)
+	}
+
+	targets := map[string]string{}
+	for _, edge := range report.Edges {
+		targets[edge.Name] = edge.Target
+	}
+	wantTargets := map[string]string{
+		"item.model":      path("item.model"),
+		"item.mate":       path("item.mate"),
+		"item_icon.tex":   path("item_icon.tex"),
+		"shared.tex":      filepath.Join(search, "shared.tex"),
+		"missing_tex.tex": "",
+		"Missing.model":   "",
+		"item_tex.tex":    path("item_tex.tex"),
+		"model_tex.tex":   path("model_tex.tex"),
+		"item.pmat":       path("item.pmat"),
+	}
+	if !reflect.DeepEqual(targets, wantTargets) {
+		t.Errorf("edge targets = %v, want %v", targets, wantTargets)
+	}
+}
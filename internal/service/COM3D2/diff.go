@@ -0,0 +1,302 @@
+package COM3D2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 差异类型
+const (
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+	DiffChanged   = "changed"
+	DiffReordered = "reordered" // 元素相同但顺序不同，.menu 命令的顺序会影响结果
+)
+
+// diffValueMaxLength 文本输出中单个值的最大长度
+const diffValueMaxLength = 120
+
+// DiffService 比较两个同类型文件的内容
+type DiffService struct{}
+
+// DiffChange 一处差异
+type DiffChange struct {
+	Path string      `json:"Path"` // 字段路径，例如 Commands[additem].Args[1]、Materials[body].Properties[_Color]
+	Kind string      `json:"Kind"` // added/removed/changed/reordered，见顶部常量定义
+	Old  interface{} `json:"Old"`  // 旧值，added 时为空
+	New  interface{} `json:"New"`  // 新值，removed 时为空
+}
+
+// DiffResult 比较结果
+type DiffResult struct {
+	FileType  string       `json:"FileType"`
+	OldPath   string       `json:"OldPath"`
+	NewPath   string       `json:"NewPath"`
+	Identical bool         `json:"Identical"`
+	Changes   []DiffChange `json:"Changes"`
+}
+
+// DiffFiles 读取两个同类型的文件（二进制或 .json 均可）并返回结构化的差异
+// .model 的顶点数据只比较数量和包围盒，.tex 的图像数据只比较大小
+func (d *DiffService) DiffFiles(oldPath string, newPath string) (*DiffResult, error) {
+	f, oldTree, err := readDiffTree(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newFormat, newTree, err := readDiffTree(newPath)
+	if err != nil {
+		return nil, err
+	}
+	if f.FileType() != newFormat.FileType() {
+		return nil, fmt.Errorf("cannot compare a %s file with a %s file", f.Extension(), newFormat.Extension())
+	}
+
+	result := &DiffResult{FileType: f.FileType(), OldPath: oldPath, NewPath: newPath}
+	diffTree("", "", oldTree, newTree, &result.Changes)
+	result.Identical = len(result.Changes) == 0
+	return result, nil
+}
+
+// DiffFilesText 比较两个文件并返回可读的文本，供命令行和日志使用
+func (d *DiffService) DiffFilesText(oldPath string, newPath string) (string, error) {
+	result, err := d.DiffFiles(oldPath, newPath)
+	if err != nil {
+		return "", err
+	}
+	return FormatDiffText(result), nil
+}
+
+// FormatDiffText 将比较结果格式化为文本，每处差异一行
+// + 新增，- 删除，~ 修改，* 顺序变化
+func FormatDiffText(result *DiffResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", result.OldPath, result.NewPath)
+	if result.Identical {
+		sb.WriteString("(no differences)\n")
+		return sb.String()
+	}
+	for _, change := range result.Changes {
+		switch change.Kind {
+		case DiffAdded:
+			fmt.Fprintf(&sb, "+ %s: %s\n", change.Path, diffValueText(change.New))
+		case DiffRemoved:
+			fmt.Fprintf(&sb, "- %s: %s\n", change.Path, diffValueText(change.Old))
+		case DiffReordered:
+			fmt.Fprintf(&sb, "* %s: order changed\n", change.Path)
+		default:
+			fmt.Fprintf(&sb, "~ %s: %s -> %s\n", change.Path, diffValueText(change.Old), diffValueText(change.New))
+		}
+	}
+	fmt.Fprintf(&sb, "%d change(s)\n", len(result.Changes))
+	return sb.String()
+}
+
+// readDiffTree 读取文件并转换为用于比较的 JSON 树
+func readDiffTree(path string) (FormatHandler, interface{}, error) {
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(path, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, path)
+	}
+	data, err := f.Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := toTree(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal %s data: %w", f.FileType(), err)
+	}
+	summarizeForDiff(f.FileType(), tree)
+	return f, tree, nil
+}
+
+// diffTree 递归比较两个 JSON 树，field 为当前值所在的字段名称，用于查找数组的匹配方式
+func diffTree(path string, field string, a interface{}, b interface{}, changes *[]DiffChange) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, exists := av[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := joinTreePath(path, k)
+			oldValue, inOld := av[k]
+			newValue, inNew := bv[k]
+			switch {
+			case !inOld:
+				*changes = append(*changes, DiffChange{Path: childPath, Kind: DiffAdded, New: newValue})
+			case !inNew:
+				*changes = append(*changes, DiffChange{Path: childPath, Kind: DiffRemoved, Old: oldValue})
+			default:
+				diffTree(childPath, k, oldValue, newValue, changes)
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		diffArray(path, field, av, bv, changes)
+		return
+	}
+
+	if !treeEqual(a, b) {
+		*changes = append(*changes, DiffChange{Path: path, Kind: DiffChanged, Old: a, New: b})
+	}
+}
+
+// diffArray 比较数组，登记了匹配方式的数组按键对应，否则按下标对应
+func diffArray(path string, field string, a []interface{}, b []interface{}, changes *[]DiffChange) {
+	keyFunc := elementKeyFuncs[field]
+	if keyFunc == nil {
+		n := len(a)
+		if len(b) < n {
+			n = len(b)
+		}
+		for i := 0; i < n; i++ {
+			diffTree(fmt.Sprintf("%s[%d]", path, i), field, a[i], b[i], changes)
+		}
+		for i := n; i < len(a); i++ {
+			*changes = append(*changes, DiffChange{Path: fmt.Sprintf("%s[%d]", path, i), Kind: DiffRemoved, Old: a[i]})
+		}
+		for i := n; i < len(b); i++ {
+			*changes = append(*changes, DiffChange{Path: fmt.Sprintf("%s[%d]", path, i), Kind: DiffAdded, New: b[i]})
+		}
+		return
+	}
+
+	oldElements := keyElements(a, keyFunc)
+	newElements := keyElements(b, keyFunc)
+	newByKey := map[string]interface{}{}
+	for _, e := range newElements {
+		newByKey[e.key] = e.value
+	}
+	oldByKey := map[string]bool{}
+	var oldOrder, newOrder []string
+	for _, e := range oldElements {
+		oldByKey[e.key] = true
+		newValue, exists := newByKey[e.key]
+		elementPath := fmt.Sprintf("%s[%s]", path, e.key)
+		if !exists {
+			*changes = append(*changes, DiffChange{Path: elementPath, Kind: DiffRemoved, Old: e.value})
+			continue
+		}
+		oldOrder = append(oldOrder, e.key)
+		diffTree(elementPath, field, e.value, newValue, changes)
+	}
+	for _, e := range newElements {
+		if !oldByKey[e.key] {
+			*changes = append(*changes, DiffChange{Path: fmt.Sprintf("%s[%s]", path, e.key), Kind: DiffAdded, New: e.value})
+			continue
+		}
+		newOrder = append(newOrder, e.key)
+	}
+	if strings.Join(oldOrder, "\x00") != strings.Join(newOrder, "\x00") {
+		*changes = append(*changes, DiffChange{Path: path, Kind: DiffReordered, Old: oldOrder, New: newOrder})
+	}
+}
+
+// summarizeForDiff 将大块数据替换为摘要，避免逐顶点比较
+func summarizeForDiff(fileType string, tree interface{}) {
+	root, ok := tree.(map[string]interface{})
+	if !ok {
+		return
+	}
+	switch fileType {
+	case "model":
+		if vertices, ok := root["Vertices"].([]interface{}); ok {
+			root["Vertices"] = summarizeVertices(vertices)
+		}
+		for _, key := range []string{"Tangents", "BoneWeights", "BindPoses"} {
+			if values, ok := root[key].([]interface{}); ok {
+				root[key] = map[string]interface{}{"Count": len(values)}
+			}
+		}
+		if subMeshes, ok := root["SubMeshes"].([]interface{}); ok {
+			summary := make([]interface{}, 0, len(subMeshes))
+			for _, subMesh := range subMeshes {
+				indices, _ := subMesh.([]interface{})
+				summary = append(summary, map[string]interface{}{"TriangleCount": len(indices) / 3})
+			}
+			root["SubMeshes"] = summary
+		}
+		if morphs, ok := root["MorphData"].([]interface{}); ok {
+			for i, morph := range morphs {
+				m, ok := morph.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				indices, _ := m["Indices"].([]interface{})
+				morphs[i] = map[string]interface{}{"Name": m["Name"], "VertexCount": len(indices)}
+			}
+		}
+	case "tex":
+		if data, ok := root["Data"].(string); ok {
+			root["Data"] = map[string]interface{}{"Size": base64.StdEncoding.DecodedLen(len(data))}
+		}
+	}
+}
+
+// summarizeVertices 顶点数量和位置的包围盒
+func summarizeVertices(vertices []interface{}) map[string]interface{} {
+	minPos := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	maxPos := []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, vertex := range vertices {
+		v, _ := vertex.(map[string]interface{})
+		position, _ := v["Position"].(map[string]interface{})
+		for i, axis := range []string{"X", "Y", "Z"} {
+			n, ok := position[axis].(json.Number)
+			if !ok {
+				continue
+			}
+			f, err := n.Float64()
+			if err != nil {
+				continue
+			}
+			minPos[i] = math.Min(minPos[i], f)
+			maxPos[i] = math.Max(maxPos[i], f)
+		}
+	}
+	summary := map[string]interface{}{"Count": len(vertices)}
+	if len(vertices) > 0 {
+		summary["BoundsMin"] = minPos
+		summary["BoundsMax"] = maxPos
+	}
+	return summary
+}
+
+// diffValueText 将值格式化为单行 JSON，过长时截断
+func diffValueText(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	text := string(raw)
+	if len(text) > diffValueMaxLength {
+		cut := diffValueMaxLength
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "..."
+	}
+	return text
+}
@@ -0,0 +1,137 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseTree 将 JSON 解析为通用 JSON 树，与 toTree 的结果相同
+func parseTree(t *testing.T, raw string) interface{} {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		t.Fatalf("invalid JSON %s: %v", raw, err)
+	}
+	return tree
+}
+
+func TestDiffTree(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []DiffChange // 只比较 Path 和 Kind
+	}{
+		{
+			name: "identical",
+			old:  `{"A": 1, "B": [1, 2]}`,
+			new:  `{"A": 1, "B": [1, 2]}`,
+		},
+		{
+			name: "fields",
+			old:  `{"A": 1, "B": 2, "C": {"D": "x"}}`,
+			new:  `{"A": 1, "C": {"D": "y"}, "E": true}`,
+			want: []DiffChange{{Path: "B", Kind: DiffRemoved}, {Path: "C.D", Kind: DiffChanged}, {Path: "E", Kind: DiffAdded}},
+		},
+		{
+			name: "type changed",
+			old:  `{"A": {"B": 1}}`,
+			new:  `{"A": [1]}`,
+			want: []DiffChange{{Path: "A", Kind: DiffChanged}},
+		},
+		{
+			name: "unkeyed array by index",
+			old:  `{"Values": [1, 2, 3]}`,
+			new:  `{"Values": [1, 5]}`,
+			want: []DiffChange{{Path: "Values[1]", Kind: DiffChanged}, {Path: "Values[2]", Kind: DiffRemoved}},
+		},
+		{
+			name: "keyed array",
+			old:  `{"Properties": [{"PropName": "_Color", "Value": 1}, {"PropName": "_Old"}]}`,
+			new:  `{"Properties": [{"PropName": "_Color", "Value": 2}, {"PropName": "_New"}]}`,
+			want: []DiffChange{
+				{Path: "Properties[_Color].Value", Kind: DiffChanged},
+				{Path: "Properties[_Old]", Kind: DiffRemoved},
+				{Path: "Properties[_New]", Kind: DiffAdded},
+			},
+		},
+		{
+			name: "reordered",
+			old:  `{"Properties": [{"PropName": "a"}, {"PropName": "b"}]}`,
+			new:  `{"Properties": [{"PropName": "b"}, {"PropName": "a"}]}`,
+			want: []DiffChange{{Path: "Properties", Kind: DiffReordered}},
+		},
+		{
+			name: "duplicate menu commands",
+			old:  `{"Commands": [{"Args": ["additem", "a.model"]}, {"Args": ["additem", "b.model"]}]}`,
+			new:  `{"Commands": [{"Args": ["additem", "a.model"]}, {"Args": ["additem", "c.model"]}]}`,
+			want: []DiffChange{{Path: "Commands[additem#2].Args[1]", Kind: DiffChanged}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []DiffChange
+			diffTree("", "", parseTree(t, tt.old), parseTree(t, tt.new), &changes)
+			var got []DiffChange
+			for _, change := range changes {
+				got = append(got, DiffChange{Path: change.Path, Kind: change.Kind})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffTree = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeForDiff(t *testing.T) {
+	tree := parseTree(t, `{
+		"Vertices": [{"Position": {"X": 1, "Y": -2, "Z": 3}}, {"Position": {"X": -1, "Y": 2, "Z": 0}}],
+		"BoneWeights": [{}, {}],
+		"SubMeshes": [[0, 1, 2, 0, 2, 3]],
+		"MorphData": [{"Name": "smile", "Indices": [1, 2, 3]}]
+	}`)
+	summarizeForDiff("model", tree)
+	want := parseTree(t, `{
+		"Vertices": {"Count": 2, "BoundsMin": [-1, -2, 0], "BoundsMax": [1, 2, 3]},
+		"BoneWeights": {"Count": 2},
+		"SubMeshes": [{"TriangleCount": 2}],
+		"MorphData": [{"Name": "smile", "VertexCount": 3}]
+	}`)
+	if !treeEqual(tree, want) {
+		t.Errorf("summarizeForDiff = %v, want %v", tree, want)
+	}
+}
+
+func TestFormatDiffText(t *testing.T) {
+	result := &DiffResult{
+		OldPath: "old.menu",
+		NewPath: "new.menu",
+		Changes: []DiffChange{
+			{Path: "A", Kind: DiffAdded, New: 1},
+			{Path: "B", Kind: DiffRemoved, Old: "x"},
+			{Path: "C", Kind: DiffChanged, Old: 1, New: 2},
+			{Path: "D", Kind: DiffReordered},
+			{Path: "E", Kind: DiffChanged, Old: strings.Repeat("あ", 100), New: ""},
+		},
+	}
+	got := FormatDiffText(result)
+	for _, line := range []string{"--- old.menu\n+++ new.menu\n", "+ A: 1\n", "- B: \"x\"\n", "~ C: 1 -> 2\n", "* D: order changed\n", "5 change(s)\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("FormatDiffText output missing %q:\n%s", line, got)
+		}
+	}
+	// 截断时不能切断多字节字符
+	if !strings.Contains(got, "~ E: \""+strings.Repeat("あ", 39)+"... -> \"\"\n") {
+		t.Errorf("long value not truncated at a character boundary:\n%s", got)
+	}
+
+	identical := FormatDiffText(&DiffResult{OldPath: "a", NewPath: "b", Identical: true})
+	if !strings.Contains(identical, "(no differences)") {
+		t.Errorf("identical result = %q", identical)
+	}
+}
@@ -0,0 +1,465 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 文档保存在后端，前端通过句柄编辑，不需要每次都传输整个结构体
+// 每次编辑由若干基本操作组成（见 structpath.go），同时记录逆操作用于撤销，撤销和重做的次数没有限制
+// 打开的文件会被监视，在磁盘上被其他程序修改时发送 FileChangedEvent 事件，可以用 ReloadDocument 重新读取
+
+// ErrDocumentNotFound 句柄不存在或文档已关闭
+var ErrDocumentNotFound = errors.New("document not found")
+
+// openDocuments 所有 DocumentService 打开的文档，按路径查找
+// 按路径读写的接口（例如 ModelService.ReadModelMaterial）在文件已经打开时直接使用文档，不再重新解析
+var openDocuments = &documentRegistry{byPath: map[string]*document{}}
+
+// documentRegistry 路径 → 最近打开或保存到该路径的文档
+type documentRegistry struct {
+	mu     sync.Mutex
+	byPath map[string]*document
+}
+
+// DocumentService 管理后端持有的文档
+type DocumentService struct {
+	mu        sync.Mutex
+	documents map[string]*document
+	nextId    int
+}
+
+// DocumentInfo 文档状态
+type DocumentInfo struct {
+	Handle   string `json:"Handle"`
+	Path     string `json:"Path"`
+	FileType string `json:"FileType"`
+	Dirty    bool   `json:"Dirty"` // 与上次打开或保存时相比有修改
+	CanUndo  bool   `json:"CanUndo"`
+	CanRedo  bool   `json:"CanRedo"`
+
+	// 本次操作中不影响结果的问题，例如无法监视文件、无法更新最近打开的文件，只在 OpenDocument 和保存时返回
+	Warnings []string `json:"Warnings"`
+}
+
+// ReloadResult 从磁盘重新读取的结果
+type ReloadResult struct {
+	Document *DocumentInfo `json:"Document"`
+	Changes  []DiffChange  `json:"Changes"` // 磁盘上的文件与重新读取前的文档相比的差异，格式同 DiffService
+}
+
+// document 一个打开的文档
+type document struct {
+	mu       sync.Mutex
+	handle   string
+	path     string
+	format   FormatHandler
+	data     interface{} // 结构体指针
+	undo     []documentEdit
+	redo     []documentEdit
+	nextEdit int  // 编辑编号，用于判断是否有修改
+	savedAt  int  // 保存时的编辑编号
+	closed   bool // 已经关闭，按路径找到的文档可能在加锁之前被关闭
+}
+
+// documentEdit 一次编辑
+type documentEdit struct {
+	id      int
+	ops     []pathOp
+	inverse []pathOp // 按执行顺序排列，撤销时倒序执行
+}
+
+// OpenDocument 读取文件并返回文档状态，之后通过 Handle 访问
+func (d *DocumentService) OpenDocument(path string) (*DocumentInfo, error) {
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(path, false)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, path)
+	}
+	data, err := f.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	if d.documents == nil {
+		d.documents = map[string]*document{}
+	}
+	d.nextId++
+	doc := &document{handle: strconv.Itoa(d.nextId), path: path, format: f, data: data}
+	info := doc.info()
+	d.documents[doc.handle] = doc
+	d.mu.Unlock()
+	openDocuments.add(doc)
+
+	// 监视文件和更新最近打开的文件都会访问磁盘，不持有 d.mu，避免阻塞其他文档的操作
+	if !IsArchivePath(path) {
+		if err := fileWatcher.watch(path); err != nil {
+			info.Warnings = append(info.Warnings, err.Error())
+		}
+	}
+	if err := addRecentFile(path); err != nil {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("failed to update recent files: %v", err))
+	}
+	return info, nil
+}
+
+// CloseDocument 关闭文档，未保存的修改会丢失
+func (d *DocumentService) CloseDocument(handle string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc, ok := d.documents[handle]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDocumentNotFound, handle)
+	}
+	delete(d.documents, handle)
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	doc.closed = true
+	openDocuments.remove(doc.path, doc)
+	fileWatcher.unwatch(doc.path)
+	return nil
+}
+
+// ListDocuments 返回所有打开的文档，按打开顺序排列
+func (d *DocumentService) ListDocuments() []DocumentInfo {
+	d.mu.Lock()
+	documents := make([]*document, 0, len(d.documents))
+	for _, doc := range d.documents {
+		documents = append(documents, doc)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(documents, func(i, j int) bool {
+		a, _ := strconv.Atoi(documents[i].handle)
+		b, _ := strconv.Atoi(documents[j].handle)
+		return a < b
+	})
+	infos := make([]DocumentInfo, 0, len(documents))
+	for _, doc := range documents {
+		doc.mu.Lock()
+		infos = append(infos, *doc.info())
+		doc.mu.Unlock()
+	}
+	return infos
+}
+
+// GetDocumentInfo 返回文档状态
+func (d *DocumentService) GetDocumentInfo(handle string) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	return doc.info(), nil
+}
+
+// GetDocument 返回整个文档的结构体
+func (d *DocumentService) GetDocument(handle string) (interface{}, error) {
+	return d.GetValue(handle, "")
+}
+
+// GetValue 返回文档中 path 处的值，path 为 JSON Pointer，例如 /Materials/0
+// 只需要部分数据时（例如 .model 的材质）使用，避免传输整个结构体
+// 返回的是编码后的副本，之后的编辑不会影响它
+func (d *DocumentService) GetValue(handle string, path string) (interface{}, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	value, err := getPath(doc.data, path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(raw), nil
+}
+
+// SetValue 将 path 处的值替换为 value，path 为空时替换整个文档
+func (d *DocumentService) SetValue(handle string, path string, value interface{}) (*DocumentInfo, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return d.edit(handle, []pathOp{{Op: pathOpReplace, Path: path, Value: raw}})
+}
+
+// InsertValue 在数组中插入元素（下标为 - 时追加到末尾）或向 map 添加键
+func (d *DocumentService) InsertValue(handle string, path string, value interface{}) (*DocumentInfo, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return d.edit(handle, []pathOp{{Op: pathOpAdd, Path: path, Value: raw}})
+}
+
+// RemoveValue 删除数组元素或 map 的键
+func (d *DocumentService) RemoveValue(handle string, path string) (*DocumentInfo, error) {
+	return d.edit(handle, []pathOp{{Op: pathOpRemove, Path: path}})
+}
+
+// Undo 撤销最近一次编辑
+func (d *DocumentService) Undo(handle string) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	if len(doc.undo) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	edit := doc.undo[len(doc.undo)-1]
+	if err := applyReversed(doc.data, edit.inverse); err != nil {
+		return nil, fmt.Errorf("undo failed: %w", err)
+	}
+	doc.undo = doc.undo[:len(doc.undo)-1]
+	doc.redo = append(doc.redo, edit)
+	return doc.info(), nil
+}
+
+// Redo 重做最近一次撤销的编辑
+func (d *DocumentService) Redo(handle string) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	if len(doc.redo) == 0 {
+		return nil, fmt.Errorf("nothing to redo")
+	}
+
+	edit := doc.redo[len(doc.redo)-1]
+	inverse, err := applyPathOps(doc.data, edit.ops)
+	if err != nil {
+		return nil, fmt.Errorf("redo failed: %w", err)
+	}
+	edit.inverse = inverse
+	doc.redo = doc.redo[:len(doc.redo)-1]
+	doc.undo = append(doc.undo, edit)
+	return doc.info(), nil
+}
+
+// Save 保存到打开时的路径
+func (d *DocumentService) Save(handle string) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	return doc.save(doc.path)
+}
+
+// SaveAs 保存到新路径，根据后缀写出二进制或 .json 文件，之后 Save 会写入新路径
+func (d *DocumentService) SaveAs(handle string, path string) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+	return doc.save(path)
+}
+
+// ReloadDocument 从磁盘重新读取文档，返回磁盘上的文件与当前文档的差异
+// 重新读取作为一次编辑记录，可以撤销以恢复未保存的修改
+// 是否替换文档按完整的 JSON 判断；Changes 与 DiffService 一样对顶点等大块数据只比较摘要，因此可能为空而文档仍然被替换
+func (d *DocumentService) ReloadDocument(handle string) (*ReloadResult, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	data, err := doc.format.Read(doc.path)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(doc.data)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReloadResult{}
+
+	if !bytes.Equal(current, raw) {
+		oldTree, err := toTree(doc.data)
+		if err != nil {
+			return nil, err
+		}
+		newTree, err := toTree(data)
+		if err != nil {
+			return nil, err
+		}
+		summarizeForDiff(doc.format.FileType(), oldTree)
+		summarizeForDiff(doc.format.FileType(), newTree)
+		diffTree("", "", oldTree, newTree, &result.Changes)
+
+		ops := []pathOp{{Op: pathOpReplace, Path: "", Value: raw}}
+		inverse, err := applyPathOps(doc.data, ops)
+		if err != nil {
+			return nil, err
+		}
+		doc.record(ops, inverse)
+	}
+	// 重新读取后与磁盘一致
+	doc.savedAt = doc.currentEdit()
+	result.Document = doc.info()
+	return result, nil
+}
+
+// document 按句柄查找文档
+func (d *DocumentService) document(handle string) (*document, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	doc, ok := d.documents[handle]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, handle)
+	}
+	return doc, nil
+}
+
+// edit 执行一次编辑并记录到撤销栈，任何一个操作失败时整个编辑不生效
+func (d *DocumentService) edit(handle string, ops []pathOp) (*DocumentInfo, error) {
+	return d.editWith(handle, func(data interface{}) ([]pathOp, []pathOp, error) {
+		inverse, err := applyPathOps(data, ops)
+		return ops, inverse, err
+	})
+}
+
+// editWith 执行 apply 并将其实际执行的基本操作作为一次编辑记录到撤销栈
+// apply 失败时需要自行回滚已执行的操作
+func (d *DocumentService) editWith(handle string, apply func(data interface{}) (ops []pathOp, inverse []pathOp, err error)) (*DocumentInfo, error) {
+	doc, err := d.document(handle)
+	if err != nil {
+		return nil, err
+	}
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	ops, inverse, err := apply(doc.data)
+	if err != nil {
+		return nil, err
+	}
+	doc.record(ops, inverse)
+	return doc.info(), nil
+}
+
+// record 将已经执行的编辑记录到撤销栈并清空重做栈，调用时需要持有 doc.mu
+func (doc *document) record(ops []pathOp, inverse []pathOp) {
+	doc.nextEdit++
+	doc.undo = append(doc.undo, documentEdit{id: doc.nextEdit, ops: ops, inverse: inverse})
+	doc.redo = nil
+}
+
+// save 写入文件并记录保存时的状态
+// path 为压缩包内的路径时写入 archiveOutputDir，文档之后使用实际写入的路径，见 resolveWritePath
+func (doc *document) save(path string) (*DocumentInfo, error) {
+	path, err := resolveWritePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.format.Write(path, doc.data); err != nil {
+		return nil, err
+	}
+	var warnings []string
+	if path != doc.path {
+		fileWatcher.unwatch(doc.path)
+		if err := fileWatcher.watch(path); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+		openDocuments.remove(doc.path, doc)
+		doc.path = path
+		openDocuments.add(doc)
+	}
+	doc.savedAt = doc.currentEdit()
+	info := doc.info()
+	info.Warnings = warnings
+	return info, nil
+}
+
+// currentEdit 当前状态对应的编辑编号，没有编辑时为 0
+func (doc *document) currentEdit() int {
+	if len(doc.undo) == 0 {
+		return 0
+	}
+	return doc.undo[len(doc.undo)-1].id
+}
+
+// info 返回文档状态，调用时需要持有 doc.mu
+func (doc *document) info() *DocumentInfo {
+	return &DocumentInfo{
+		Handle:   doc.handle,
+		Path:     doc.path,
+		FileType: doc.format.FileType(),
+		Dirty:    doc.currentEdit() != doc.savedAt,
+		CanUndo:  len(doc.undo) > 0,
+		CanRedo:  len(doc.redo) > 0,
+	}
+}
+
+// add 登记文档，同一路径已有文档时以后打开的为准
+func (r *documentRegistry) add(doc *document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byPath[documentKey(doc.path)] = doc
+}
+
+// remove 取消登记，path 已经登记为其他文档时不变
+func (r *documentRegistry) remove(path string, doc *document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := documentKey(path)
+	if r.byPath[key] == doc {
+		delete(r.byPath, key)
+	}
+}
+
+// lookup 按路径查找打开的文档，找到时返回已加锁的文档，调用者负责解锁
+func (r *documentRegistry) lookup(path string) *document {
+	r.mu.Lock()
+	doc := r.byPath[documentKey(path)]
+	r.mu.Unlock()
+	if doc == nil {
+		return nil
+	}
+	doc.mu.Lock()
+	if doc.closed || documentKey(doc.path) != documentKey(path) {
+		doc.mu.Unlock()
+		return nil
+	}
+	return doc
+}
+
+// documentKey 登记用的路径，普通路径转换为绝对路径
+func documentKey(path string) string {
+	if IsArchivePath(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
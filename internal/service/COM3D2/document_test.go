@@ -0,0 +1,389 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// isolateSettings 设置文件写到临时目录，不读取也不修改用户的设置
+func isolateSettings(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("AppData", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+}
+
+// openFixtureDocument 写出 data 并打开为文档，测试结束时关闭
+func openFixtureDocument(t *testing.T, service *DocumentService, name string, data []byte) *DocumentInfo {
+	t.Helper()
+	isolateSettings(t)
+	info, err := service.OpenDocument(writeFixture(t, name, data))
+	if err != nil {
+		t.Fatalf("OpenDocument: %v", err)
+	}
+	t.Cleanup(func() { service.CloseDocument(info.Handle) })
+	return info
+}
+
+// checkState 检查文档的修改和撤销状态
+func checkState(t *testing.T, info *DocumentInfo, err error, dirty bool, canUndo bool, canRedo bool) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Dirty != dirty || info.CanUndo != canUndo || info.CanRedo != canRedo {
+		t.Errorf("state = dirty %v, undo %v, redo %v, want %v, %v, %v", info.Dirty, info.CanUndo, info.CanRedo, dirty, canUndo, canRedo)
+	}
+}
+
+// getString 读取文档中 path 处的字符串
+func getString(t *testing.T, service *DocumentService, handle string, path string) string {
+	t.Helper()
+	value, err := service.GetValue(handle, path)
+	if err != nil {
+		t.Fatalf("GetValue(%s): %v", path, err)
+	}
+	var s string
+	if err := json.Unmarshal(value.(json.RawMessage), &s); err != nil {
+		t.Fatalf("GetValue(%s) = %s: %v", path, value, err)
+	}
+	return s
+}
+
+func TestOpenDocument(t *testing.T) {
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	if info.FileType != "menu" || filepath.Base(info.Path) != "item.menu" {
+		t.Errorf("info = %+v", info)
+	}
+	checkState(t, info, nil, false, false, false)
+	if got := getString(t, service, info.Handle, "/Commands/0/Args/1"); got != "item.model" {
+		t.Errorf("/Commands/0/Args/1 = %q", got)
+	}
+	if got := service.ListDocuments(); len(got) != 1 || got[0].Handle != info.Handle {
+		t.Errorf("ListDocuments = %+v", got)
+	}
+
+	if err := service.CloseDocument(info.Handle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetDocumentInfo(info.Handle); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("GetDocumentInfo after close error = %v", err)
+	}
+	if _, err := service.OpenDocument(filepath.Join(t.TempDir(), "missing.menu")); err == nil {
+		t.Error("OpenDocument of a missing file succeeded")
+	}
+}
+
+func TestDocumentUndoRedo(t *testing.T) {
+	service := &DocumentService{}
+	handle := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"})).Handle
+
+	info, err := service.SetValue(handle, "/ItemName", "renamed")
+	checkState(t, info, err, true, true, false)
+	info, err = service.InsertValue(handle, "/Commands/-", COM3D2.Command{ArgCount: 1, Args: []string{"end"}})
+	checkState(t, info, err, true, true, false)
+	if got := getString(t, service, handle, "/Commands/1/Args/0"); got != "end" {
+		t.Errorf("inserted command = %q", got)
+	}
+
+	info, err = service.Undo(handle)
+	checkState(t, info, err, true, true, true)
+	if _, err := service.GetValue(handle, "/Commands/1"); err == nil {
+		t.Error("undo did not remove the inserted command")
+	}
+	info, err = service.Undo(handle)
+	checkState(t, info, err, false, false, true)
+	if got := getString(t, service, handle, "/ItemName"); got != "test" {
+		t.Errorf("/ItemName after undo = %q", got)
+	}
+	if _, err := service.Undo(handle); err == nil {
+		t.Error("undo with an empty history succeeded")
+	}
+
+	info, err = service.Redo(handle)
+	checkState(t, info, err, true, true, true)
+	if got := getString(t, service, handle, "/ItemName"); got != "renamed" {
+		t.Errorf("/ItemName after redo = %q", got)
+	}
+
+	// 新的编辑清空重做栈
+	info, err = service.RemoveValue(handle, "/Commands/0")
+	checkState(t, info, err, true, true, false)
+	if _, err := service.Redo(handle); err == nil {
+		t.Error("redo after a new edit succeeded")
+	}
+
+	// 失败的编辑不记录
+	if _, err := service.SetValue(handle, "/Missing/0", 1); err == nil {
+		t.Error("SetValue of a missing path succeeded")
+	}
+	info, err = service.Undo(handle)
+	checkState(t, info, err, true, true, true)
+	if got := getString(t, service, handle, "/Commands/0/Args/0"); got != "additem" {
+		t.Errorf("/Commands/0/Args/0 after undo = %q", got)
+	}
+}
+
+func TestDocumentSave(t *testing.T) {
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	handle, path := info.Handle, info.Path
+
+	service.SetValue(handle, "/ItemName", "renamed")
+	info, err := service.Save(handle)
+	checkState(t, info, err, false, true, false)
+	saved, err := menuFormat.ReadFile(path)
+	if err != nil || saved.ItemName != "renamed" {
+		t.Errorf("saved file = %+v, %v", saved, err)
+	}
+
+	// 撤销到保存之前的状态也算修改
+	info, err = service.Undo(handle)
+	checkState(t, info, err, true, false, true)
+	info, err = service.Redo(handle)
+	checkState(t, info, err, false, true, false)
+}
+
+func TestDocumentSaveAs(t *testing.T) {
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	handle, originalPath := info.Handle, info.Path
+	original, err := os.ReadFile(originalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service.SetValue(handle, "/ItemName", "renamed")
+	jsonPath := filepath.Join(t.TempDir(), "renamed.menu.json")
+	info, err = service.SaveAs(handle, jsonPath)
+	checkState(t, info, err, false, true, false)
+	if info.Path != jsonPath {
+		t.Errorf("Path after SaveAs = %q, want %q", info.Path, jsonPath)
+	}
+	saved, err := menuFormat.ReadFile(jsonPath)
+	if err != nil || saved.ItemName != "renamed" {
+		t.Errorf("saved file = %+v, %v", saved, err)
+	}
+	if after, err := os.ReadFile(originalPath); err != nil || string(after) != string(original) {
+		t.Error("SaveAs modified the original file")
+	}
+
+	// 之后的 Save 写入新路径
+	service.SetValue(handle, "/Category", "acchat")
+	if _, err := service.Save(handle); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := menuFormat.ReadFile(jsonPath); err != nil || saved.Category != "acchat" {
+		t.Errorf("saved file = %+v, %v", saved, err)
+	}
+}
+
+// 保存到压缩包内的路径时写入输出目录，文档之后使用实际写入的路径
+func TestDocumentSaveAsArchivePath(t *testing.T) {
+	service := &DocumentService{}
+	handle := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"})).Handle
+
+	setArchiveOutputDir(t, "")
+	if _, err := service.SaveAs(handle, "mod.zip!/menu/item.menu"); !errors.Is(err, ErrArchiveReadOnly) {
+		t.Errorf("SaveAs into an archive error = %v, want %v", err, ErrArchiveReadOnly)
+	}
+
+	outputDir := t.TempDir()
+	setArchiveOutputDir(t, outputDir)
+	info, err := service.SaveAs(handle, "mod.zip!/menu/item.menu")
+	if err != nil {
+		t.Fatalf("SaveAs: %v", err)
+	}
+	want := filepath.Join(outputDir, "menu", "item.menu")
+	if info.Path != want {
+		t.Errorf("Path = %q, want %q", info.Path, want)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Error(err)
+	}
+	if len(info.Warnings) != 0 {
+		t.Errorf("Warnings = %v", info.Warnings)
+	}
+}
+
+const modelFixtureJson = `{"Signature": "CM3D2_MESH", "Version": 2000, "Name": "item", "RootBoneName": "Bip01", "Materials": [
+	{"Name": "item_mat", "ShaderName": "CM3D2/Toony_Lighted", "ShaderFilename": "toony_lighted", "Properties": [
+		{"TypeName": "f", "PropName": "_Shininess", "Number": 0.5}
+	]}
+]}`
+
+// 文件已经作为文档打开时，ReadModelMaterial 和 WriteModelMaterial 使用文档而不是重新读取文件
+func TestModelMaterialThroughDocument(t *testing.T) {
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.model.json", []byte(modelFixtureJson))
+	handle, path := info.Handle, info.Path
+
+	// 文件被删除后仍然可以读取文档中的材质，包括未保存的修改
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	service.SetValue(handle, "/Materials/0/Name", "edited")
+	modelService := &ModelService{}
+	materials, err := modelService.ReadModelMaterial(path)
+	if err != nil {
+		t.Fatalf("ReadModelMaterial: %v", err)
+	}
+	if len(materials) != 1 || materials[0].Name != "edited" {
+		t.Fatalf("materials = %+v", materials)
+	}
+
+	// 返回的是副本
+	materials[0].Name = "changed"
+	if got := getString(t, service, handle, "/Materials/0/Name"); got != "edited" {
+		t.Errorf("changing the result changed the document: %q", got)
+	}
+
+	materials[0].Name = "written"
+	if err := modelService.WriteModelMaterial(path, path, materials); err != nil {
+		t.Fatalf("WriteModelMaterial: %v", err)
+	}
+	info, err = service.GetDocumentInfo(handle)
+	checkState(t, info, err, false, true, false)
+	saved, err := modelFormat.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Name != "item" || len(saved.Materials) != 1 || saved.Materials[0].Name != "written" {
+		t.Errorf("saved model = %+v", saved)
+	}
+
+	// 写入作为一次编辑，可以撤销
+	if _, err := service.Undo(handle); err != nil {
+		t.Fatal(err)
+	}
+	if got := getString(t, service, handle, "/Materials/0/Name"); got != "edited" {
+		t.Errorf("/Materials/0/Name after undo = %q", got)
+	}
+
+	// 关闭后重新读取文件
+	service.CloseDocument(handle)
+	materials, err = modelService.ReadModelMaterial(path)
+	if err != nil || len(materials) != 1 || materials[0].Name != "written" {
+		t.Errorf("ReadModelMaterial after close = %+v, %v", materials, err)
+	}
+}
+
+func TestDocumentRegistry(t *testing.T) {
+	registry := &documentRegistry{byPath: map[string]*document{}}
+	path := filepath.Join(t.TempDir(), "item.menu")
+	first := &document{path: path, format: menuFormat}
+	second := &document{path: path, format: menuFormat}
+
+	registry.add(first)
+	registry.add(second)
+	// 相对路径按绝对路径查找
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(wd, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc := registry.lookup(relative); doc != second {
+		t.Errorf("lookup = %p, want the latest document %p", doc, second)
+	} else {
+		doc.mu.Unlock()
+	}
+
+	// 取消登记已被替换的文档不影响新文档
+	registry.remove(path, first)
+	if doc := registry.lookup(path); doc != second {
+		t.Error("removing a replaced document removed the current one")
+	} else {
+		doc.mu.Unlock()
+	}
+
+	second.closed = true
+	if doc := registry.lookup(path); doc != nil {
+		t.Error("lookup returned a closed document")
+	}
+	registry.remove(path, second)
+	if !reflect.DeepEqual(registry.byPath, map[string]*document{}) {
+		t.Errorf("byPath = %v", registry.byPath)
+	}
+}
+
+func TestReloadDocument(t *testing.T) {
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	handle, path := info.Handle, info.Path
+
+	// 文件没有变化时不记录编辑
+	result, err := service.ReloadDocument(handle)
+	if err != nil {
+		t.Fatalf("ReloadDocument: %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("Changes = %+v", result.Changes)
+	}
+	checkState(t, result.Document, nil, false, false, false)
+
+	service.SetValue(handle, "/ItemName", "unsaved")
+	changed, err := menuFormat.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed.Category = "acchat"
+	if err := menuFormat.WriteFile(path, changed); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = service.ReloadDocument(handle)
+	if err != nil {
+		t.Fatalf("ReloadDocument: %v", err)
+	}
+	var paths []string
+	for _, change := range result.Changes {
+		paths = append(paths, change.Path)
+	}
+	if want := []string{"Category", "ItemName"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("changed paths = %v, want %v", paths, want)
+	}
+	checkState(t, result.Document, nil, false, true, false)
+	if got := getString(t, service, handle, "/Category"); got != "acchat" {
+		t.Errorf("/Category after reload = %q", got)
+	}
+
+	// 撤销重新读取可以恢复未保存的修改
+	info, err = service.Undo(handle)
+	checkState(t, info, err, true, true, true)
+	if got := getString(t, service, handle, "/ItemName"); got != "unsaved" {
+		t.Errorf("/ItemName after undo = %q", got)
+	}
+}
+
+// 只有摘要相同的大块数据变化时 Changes 为空，但文档仍然替换为磁盘上的内容
+func TestReloadDocumentSummarizedData(t *testing.T) {
+	const tangentsJson = `{"Signature": "CM3D2_MESH", "Version": 2000, "Name": "item", "Tangents": [{"X": %s, "Y": 0, "Z": 0, "W": 1}]}`
+	service := &DocumentService{}
+	info := openFixtureDocument(t, service, "item.model.json", []byte(fmt.Sprintf(tangentsJson, "0")))
+	if err := os.WriteFile(info.Path, []byte(fmt.Sprintf(tangentsJson, "1")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.ReloadDocument(info.Handle)
+	if err != nil {
+		t.Fatalf("ReloadDocument: %v", err)
+	}
+	if len(result.Changes) != 0 {
+		t.Errorf("Changes = %+v", result.Changes)
+	}
+	checkState(t, result.Document, nil, false, true, false)
+	value, err := service.GetValue(info.Handle, "/Tangents/0/X")
+	if err != nil || string(value.(json.RawMessage)) != "1" {
+		t.Errorf("/Tangents/0/X after reload = %s, %v", value, err)
+	}
+}
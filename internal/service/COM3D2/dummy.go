@@ -58,3 +58,53 @@ type ModelModel struct{}
 // Dummy 用于让 wails 识别 model 对应结构体，需要在签名中使用所有结构体
 func (s *ModelModel) Dummy(COM3D2.Model, COM3D2.Bone, COM3D2.Vertex, COM3D2.Vertex, COM3D2.BoneWeight, COM3D2.Matrix4x4, COM3D2.MorphData, COM3D2.SkinThickness, COM3D2.ThickGroup, COM3D2.ThickPoint, COM3D2.ThickDefPerAngle, COM3D2.Vector2, COM3D2.Vector3, COM3D2.Quaternion, COM3D2.Material) {
 }
+
+// PresetModel 用于让 wails 识别 preset 对应结构体
+type PresetModel struct{}
+
+// Dummy 用于让 wails 识别 preset 对应结构体，需要在签名中使用所有结构体
+func (s *PresetModel) Dummy(COM3D2.Preset, COM3D2.MaidProp, PresetItem, PresetSummary) {}
+
+// SaveModel 用于让 wails 识别 save 对应结构体
+type SaveModel struct{}
+
+// Dummy 用于让 wails 识别 save 对应结构体，需要在签名中使用所有结构体
+func (s *SaveModel) Dummy(COM3D2.SaveData, COM3D2.SaveHeader, COM3D2.SaveMaid, SaveMaidInfo, SaveSummary) {
+}
+
+// BatchModel 用于让 wails 识别批量转换事件对应结构体
+type BatchModel struct{}
+
+// Dummy 用于让 wails 识别批量转换事件对应结构体，需要在签名中使用所有结构体
+func (s *BatchModel) Dummy(BatchOptions, BatchProgress, BatchFileError, BatchResult) {}
+
+// DependencyModel 用于让 wails 识别依赖分析对应结构体
+type DependencyModel struct{}
+
+// Dummy 用于让 wails 识别依赖分析对应结构体，需要在签名中使用所有结构体
+func (s *DependencyModel) Dummy(DependencyOptions, DependencyNode, DependencyEdge, NameCollision, DependencyReport) {
+}
+
+// DiffModel 用于让 wails 识别比较结果对应结构体
+type DiffModel struct{}
+
+// Dummy 用于让 wails 识别比较结果对应结构体，需要在签名中使用所有结构体
+func (s *DiffModel) Dummy(DiffChange, DiffResult) {}
+
+// MergeModel 用于让 wails 识别合并结果对应结构体
+type MergeModel struct{}
+
+// Dummy 用于让 wails 识别合并结果对应结构体，需要在签名中使用所有结构体
+func (s *MergeModel) Dummy(MergeConflict, MergeResult) {}
+
+// DocumentModel 用于让 wails 识别文档状态对应结构体
+type DocumentModel struct{}
+
+// Dummy 用于让 wails 识别文档状态对应结构体，需要在签名中使用所有结构体
+func (s *DocumentModel) Dummy(DocumentInfo) {}
+
+// WatcherModel 用于让 wails 识别文件变化事件对应结构体
+type WatcherModel struct{}
+
+// Dummy 用于让 wails 识别文件变化事件对应结构体，需要在签名中使用所有结构体
+func (s *WatcherModel) Dummy(FileChangeEvent) {}
@@ -0,0 +1,295 @@
+package COM3D2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FormatHandler 描述一种游戏文件格式，提供统一的读写和 JSON 转换
+// 所有格式都注册在 formatRegistry 中，可以按文件类型、签名或扩展名查找
+// 批量转换、比较、校验等通用功能只依赖此接口，新增格式时只需要注册一个 Format
+type FormatHandler interface {
+	FileType() string                                          // 文件类型名称，同 FileInfo.FileType，例如 menu
+	Signature() string                                         // 二进制文件签名，例如 CM3D2_MENU
+	Extension() string                                         // 二进制文件扩展名，例如 .menu
+	SupportsJSON() bool                                        // 是否支持 .json 格式
+	SupportsWrite() bool                                       // 是否支持写出二进制文件
+	New() interface{}                                          // 返回一个空的结构体指针，用于 JSON 解码
+	Decode(rs io.ReadSeeker) (interface{}, error)              // 从二进制数据解码
+	Encode(w io.Writer, data interface{}) error                // 编码为二进制数据
+	Read(path string) (interface{}, error)                     // 读取二进制或 .json 文件
+	Write(path string, data interface{}) error                 // 写入二进制或 .json 文件，根据后缀决定
+	ConvertToJson(inputPath string, outputPath string) error   // 将输入文件转换为 .json 文件
+	ConvertFromJson(inputPath string, outputPath string) error // 将 .json 文件转换为二进制文件
+	Locate(r io.Reader, offset int64) string                   // 二进制数据中 offset 处的字节所在的部分，例如 command 3 argument 1，无法定位时为空
+}
+
+// Format 是 FormatHandler 的通用实现，T 为对应的结构体类型
+// 各类型的 Service 只是对 Format 的简单包装
+type Format[T any] struct {
+	fileType  string
+	signature string
+	json      bool                               // 是否支持 .json 格式
+	read      func(rs io.ReadSeeker) (*T, error) // 二进制解码
+	dump      func(data *T, w io.Writer) error   // 二进制编码，为 nil 时该格式只读
+	locate    func(s *binaryScanner) error       // 按文件结构扫描，用于定位解析错误所在的部分，为 nil 时只报告偏移
+}
+
+// FormatInfo 前端使用的格式描述
+type FormatInfo struct {
+	FileType      string `json:"FileType"`
+	Signature     string `json:"Signature"`
+	Extension     string `json:"Extension"`
+	SupportsJSON  bool   `json:"SupportsJSON"`
+	SupportsWrite bool   `json:"SupportsWrite"`
+}
+
+var (
+	formatRegistry    = map[string]FormatHandler{} // 文件类型 → 格式
+	formatBySignature = map[string]FormatHandler{} // 签名 → 格式
+)
+
+// registerFormat 注册一种格式，返回原值以便声明为包级变量
+func registerFormat[T any](f *Format[T]) *Format[T] {
+	if _, exists := formatRegistry[f.fileType]; exists {
+		panic("duplicate format: " + f.fileType)
+	}
+	formatRegistry[f.fileType] = f
+	formatBySignature[f.signature] = f
+	return f
+}
+
+// readBuffered 将只接受 io.Reader 的解码函数包装为带缓冲的读取
+// 只有读取后续不需要 Seek 的格式才能使用
+func readBuffered[T any](read func(r io.Reader) (*T, error), size int) func(rs io.ReadSeeker) (*T, error) {
+	return func(rs io.ReadSeeker) (*T, error) {
+		// 缓冲放在计数之下，解析失败时的偏移才是解析器实际读取到的位置
+		if tracked, ok := rs.(*offsetReader); ok {
+			return read(tracked.buffered(size))
+		}
+		return read(bufio.NewReaderSize(rs, size))
+	}
+}
+
+// FormatByFileType 根据文件类型名称查找格式
+func FormatByFileType(fileType string) (FormatHandler, bool) {
+	f, ok := formatRegistry[fileType]
+	return f, ok
+}
+
+// FormatBySignature 根据二进制文件签名查找格式
+func FormatBySignature(signature string) (FormatHandler, bool) {
+	f, ok := formatBySignature[signature]
+	return f, ok
+}
+
+// FormatByPath 根据文件扩展名查找格式，.menu、.menu.json 和 .menu.yaml 都会返回 menu
+func FormatByPath(path string) (FormatHandler, bool) {
+	name := trimTextSuffix(strings.ToLower(filepath.Base(path)))
+	return FormatByFileType(strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// Formats 返回所有已注册的格式，按文件类型排序
+func Formats() []FormatHandler {
+	handlers := make([]FormatHandler, 0, len(formatRegistry))
+	for _, f := range formatRegistry {
+		handlers = append(handlers, f)
+	}
+	sort.Slice(handlers, func(i, j int) bool {
+		return handlers[i].FileType() < handlers[j].FileType()
+	})
+	return handlers
+}
+
+func (f *Format[T]) FileType() string    { return f.fileType }
+func (f *Format[T]) Signature() string   { return f.signature }
+func (f *Format[T]) Extension() string   { return "." + f.fileType }
+func (f *Format[T]) SupportsJSON() bool  { return f.json }
+func (f *Format[T]) SupportsWrite() bool { return f.dump != nil }
+func (f *Format[T]) New() interface{}    { return new(T) }
+
+func (f *Format[T]) Locate(r io.Reader, offset int64) string {
+	if f.locate == nil {
+		return ""
+	}
+	// locateSection 取起始位置在目标之前的最后一段，offset + 1 使从 offset 开始的一段也被计入
+	return locateSection(r, offset+1, f.locate)
+}
+
+// Decode 从二进制数据解码，失败时返回 *ParseError
+func (f *Format[T]) Decode(rs io.ReadSeeker) (interface{}, error) {
+	return f.decode(rs)
+}
+
+// decode 从 rs 的开头解码，失败时返回 *ParseError
+func (f *Format[T]) decode(rs io.ReadSeeker) (*T, error) {
+	tracked := newOffsetReader(rs)
+	data, err := f.read(tracked)
+	if err != nil {
+		return nil, newParseError(f.fileType, f.locate, rs, tracked.offset, err)
+	}
+	return data, nil
+}
+
+// Encode 编码为二进制数据
+func (f *Format[T]) Encode(w io.Writer, data interface{}) error {
+	typed, err := f.assert(data)
+	if err != nil {
+		return err
+	}
+	if f.dump == nil {
+		return fmt.Errorf("%s files are read-only", f.Extension())
+	}
+	return f.dump(typed, w)
+}
+
+// Read 读取二进制、.json 或 .yaml 文件
+func (f *Format[T]) Read(path string) (interface{}, error) {
+	return f.ReadFile(path)
+}
+
+// Write 写入二进制、.json 或 .yaml 文件
+func (f *Format[T]) Write(path string, data interface{}) error {
+	typed, err := f.assert(data)
+	if err != nil {
+		return err
+	}
+	return f.WriteFile(path, typed)
+}
+
+// assert 将任意数据转换为 *T
+func (f *Format[T]) assert(data interface{}) (*T, error) {
+	switch v := data.(type) {
+	case *T:
+		return v, nil
+	case T:
+		return &v, nil
+	default:
+		return nil, fmt.Errorf("unexpected data type %T for %s file", data, f.Extension())
+	}
+}
+
+// ReadFile 读取二进制、.json 或 .yaml 文件并返回对应结构体，支持压缩包内的虚拟路径
+func (f *Format[T]) ReadFile(path string) (*T, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open %s file: %w", f.Extension(), err)
+	}
+	defer file.Close()
+
+	if suffix := textSuffix(path); f.json && suffix != "" {
+		data := new(T)
+		if err := unmarshalText(path, file, data); err != nil {
+			return nil, fmt.Errorf("failed to read %s%s file: %w", f.Extension(), suffix, err)
+		}
+		return data, nil
+	}
+
+	data, err := f.decode(file)
+	if err != nil {
+		err.(*ParseError).Path = path
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteFile 接收结构体并写入二进制、.json 或 .yaml 文件
+// 写入压缩包内的虚拟路径时会被拒绝或重定向，见 resolveWritePath；写入是原子的，见 writeFileAtomic
+func (f *Format[T]) WriteFile(path string, data *T) error {
+	suffix := textSuffix(path)
+	isText := f.json && suffix != ""
+	if f.dump == nil && !isText {
+		return fmt.Errorf("%s files are read-only", f.Extension())
+	}
+
+	path, err := resolveWritePath(path)
+	if err != nil {
+		return err
+	}
+
+	// 先写入临时文件再替换，编码失败时不会破坏原文件
+	return writeFileAtomic(path, func(w io.Writer) error {
+		if isText {
+			marshal, err := marshalText(path, data)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(marshal); err != nil {
+				return fmt.Errorf("failed to write to %s%s file: %w", f.Extension(), suffix, err)
+			}
+			return nil
+		}
+
+		if err := f.dump(data, w); err != nil {
+			return fmt.Errorf("failed to write to %s file: %w", f.Extension(), err)
+		}
+		return nil
+	})
+}
+
+// ConvertToJson 接收输入文件路径和输出文件路径，将输入文件转换为 .json 文件
+// 输出路径以 .yaml 或 .yml 结尾时转换为 YAML
+func (f *Format[T]) ConvertToJson(inputPath string, outputPath string) error {
+	if !f.json {
+		return fmt.Errorf("%w: %s cannot be converted to JSON", ErrUnsupportedFileType, f.Extension())
+	}
+	if strings.HasSuffix(outputPath, f.Extension()) {
+		outputPath = outputPath + ".json"
+	}
+	outputPath, err := resolveWritePath(outputPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := f.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s file: %w", f.fileType, err)
+	}
+
+	jsonData, err := marshalText(outputPath, data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s data: %w", f.fileType, err)
+	}
+
+	return writeFileAtomic(outputPath, func(w io.Writer) error {
+		if _, err := w.Write(jsonData); err != nil {
+			return fmt.Errorf("failed to write to %s%s file: %w", f.fileType, textSuffix(outputPath), err)
+		}
+		return nil
+	})
+}
+
+// ConvertFromJson 接收输入文件路径和输出文件路径，将 .json 或 .yaml 文件转换为二进制文件
+func (f *Format[T]) ConvertFromJson(inputPath string, outputPath string) error {
+	if !f.json || f.dump == nil {
+		return fmt.Errorf("%w: %s cannot be converted from JSON", ErrUnsupportedFileType, f.Extension())
+	}
+	if textSuffix(outputPath) != "" {
+		outputPath = trimTextSuffix(outputPath)
+		if !strings.HasSuffix(outputPath, f.Extension()) {
+			outputPath = outputPath + f.Extension()
+		}
+	}
+
+	// 没有 .yaml/.yml 后缀的输入都按 JSON 读取
+	suffix := textSuffix(inputPath)
+	if suffix == "" {
+		suffix = jsonSuffix
+	}
+	file, err := openFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("cannot open %s%s file: %w", f.fileType, suffix, err)
+	}
+	defer file.Close()
+
+	data := new(T)
+	if err := unmarshalText(inputPath, file, data); err != nil {
+		return fmt.Errorf("parsing the %s%s file failed: %w", f.fileType, suffix, err)
+	}
+
+	return f.WriteFile(outputPath, data)
+}
@@ -0,0 +1,81 @@
+package COM3D2
+
+import (
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"sort"
+	"testing"
+)
+
+func TestFormatRegistry(t *testing.T) {
+	tests := []struct {
+		fileType  string
+		signature string
+	}{
+		{"anm", COM3D2.AnmSignature},
+		{"col", COM3D2.ColSignature},
+		{"mate", COM3D2.MateSignature},
+		{"menu", COM3D2.MenuSignature},
+		{"model", COM3D2.ModelSignature},
+		{"phy", COM3D2.PhySignature},
+		{"pmat", COM3D2.PMatSignature},
+		{"preset", COM3D2.PresetSignature},
+		{"psk", COM3D2.PskSignature},
+		{"save", COM3D2.SaveSignature},
+		{"tex", COM3D2.TexSignature},
+	}
+	for _, tt := range tests {
+		f, ok := FormatByFileType(tt.fileType)
+		if !ok {
+			t.Errorf("FormatByFileType(%q) not found", tt.fileType)
+			continue
+		}
+		if f.Signature() != tt.signature || f.Extension() != "."+tt.fileType {
+			t.Errorf("%s: signature %q extension %q", tt.fileType, f.Signature(), f.Extension())
+		}
+		if bySignature, ok := FormatBySignature(tt.signature); !ok || bySignature != f {
+			t.Errorf("FormatBySignature(%q) = %v, %v", tt.signature, bySignature, ok)
+		}
+	}
+
+	formats := Formats()
+	if len(formats) != len(tests) {
+		t.Errorf("Formats() returned %d formats, want %d", len(formats), len(tests))
+	}
+	if !sort.SliceIsSorted(formats, func(i, j int) bool { return formats[i].FileType() < formats[j].FileType() }) {
+		t.Errorf("Formats() is not sorted by file type")
+	}
+}
+
+func TestFormatByPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		fileType string // 为空表示找不到
+	}{
+		{"body.menu", "menu"},
+		{"dir/Body.MENU", "menu"},
+		{"body.menu.json", "menu"},
+		{"body.mate.yaml", "mate"},
+		{"body.model.yml", "model"},
+		{"body.json", ""},
+		{"body.png", ""},
+		{"menu", ""},
+	}
+	for _, tt := range tests {
+		f, ok := FormatByPath(tt.path)
+		switch {
+		case tt.fileType == "" && ok:
+			t.Errorf("FormatByPath(%q) = %s, want none", tt.path, f.FileType())
+		case tt.fileType != "" && (!ok || f.FileType() != tt.fileType):
+			t.Errorf("FormatByPath(%q) = %v, %v, want %s", tt.path, f, ok, tt.fileType)
+		}
+	}
+}
+
+func TestRegisterFormatDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate format did not panic")
+		}
+	}()
+	registerFormat(&Format[COM3D2.Menu]{fileType: "menu", signature: "DUPLICATE"})
+}
@@ -0,0 +1,246 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 所有 .json 输出都通过 marshalJson 生成，格式由 JsonOptions 控制
+// 相同的输入和选项总是产生相同的字节，对象的键默认按结构体字段顺序，map 的键由 encoding/json 排序
+
+// JsonOptions JSON 输出选项
+type JsonOptions struct {
+	Indent   int  `json:"Indent"`   // 缩进空格数，0 为紧凑的单行输出
+	SortKeys bool `json:"SortKeys"` // 对象的键按字母顺序排序，默认按结构体字段顺序
+	// FloatPrecision 小数最多保留的位数，0 为可以无损还原的最短表示
+	// 设置后会丢失精度，转换回二进制时可能无法与原文件逐字节相同
+	FloatPrecision int `json:"FloatPrecision"`
+}
+
+var (
+	jsonOptionsMu sync.RWMutex
+	jsonOptions   JsonOptions
+)
+
+// SetJsonOptions 设置所有 .json 输出使用的格式
+func (m *CommonService) SetJsonOptions(options JsonOptions) error {
+	if err := validateJsonOptions(options); err != nil {
+		return err
+	}
+	jsonOptionsMu.Lock()
+	defer jsonOptionsMu.Unlock()
+	jsonOptions = options
+	return nil
+}
+
+// validateJsonOptions 检查选项是否在允许的范围内
+func validateJsonOptions(options JsonOptions) error {
+	if options.Indent < 0 || options.Indent > 16 {
+		return fmt.Errorf("indent must be between 0 and 16, got %d", options.Indent)
+	}
+	if options.FloatPrecision < 0 || options.FloatPrecision > 17 {
+		return fmt.Errorf("float precision must be between 0 and 17, got %d", options.FloatPrecision)
+	}
+	return nil
+}
+
+// GetJsonOptions 获取当前的 .json 输出格式
+func (m *CommonService) GetJsonOptions() JsonOptions {
+	jsonOptionsMu.RLock()
+	defer jsonOptionsMu.RUnlock()
+	return jsonOptions
+}
+
+// marshalJson 按当前的 JsonOptions 编码数据
+func marshalJson(data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	jsonOptionsMu.RLock()
+	options := jsonOptions
+	jsonOptionsMu.RUnlock()
+	return formatJson(raw, options)
+}
+
+// formatJson 按选项重新格式化 JSON，数字保留原始写法，不会经过 float64 转换
+func formatJson(raw []byte, options JsonOptions) ([]byte, error) {
+	var out bytes.Buffer
+	switch {
+	case !options.SortKeys && options.FloatPrecision == 0 && options.Indent == 0:
+		return raw, nil
+	case !options.SortKeys && options.FloatPrecision == 0:
+		// 只需要缩进时使用标准库，速度更快
+		if err := json.Indent(&out, raw, "", strings.Repeat(" ", options.Indent)); err != nil {
+			return nil, err
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		f := &jsonFormatter{dec: dec, options: options, indent: strings.Repeat(" ", options.Indent)}
+		if err := f.writeValue(&out, 0); err != nil {
+			return nil, err
+		}
+	}
+	if options.Indent > 0 {
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// jsonFormatter 逐个读取 token 并重新输出
+type jsonFormatter struct {
+	dec     *json.Decoder
+	options JsonOptions
+	indent  string
+}
+
+// writeValue 读取并输出一个完整的值
+func (f *jsonFormatter) writeValue(out *bytes.Buffer, depth int) error {
+	token, err := f.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		switch value {
+		case '{':
+			return f.writeObject(out, depth)
+		case '[':
+			return f.writeArray(out, depth)
+		default:
+			return fmt.Errorf("unexpected delimiter %q", value)
+		}
+	case json.Number:
+		out.WriteString(f.formatNumber(value))
+	case string:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	case bool:
+		out.WriteString(strconv.FormatBool(value))
+	case nil:
+		out.WriteString("null")
+	default:
+		return fmt.Errorf("unexpected JSON token %v", token)
+	}
+	return nil
+}
+
+// writeObject 输出对象，需要排序时先缓存所有成员
+func (f *jsonFormatter) writeObject(out *bytes.Buffer, depth int) error {
+	type member struct {
+		key   []byte
+		value []byte
+	}
+	var members []member
+	for f.dec.More() {
+		token, err := f.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected object key %v", token)
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		var value bytes.Buffer
+		if err := f.writeValue(&value, depth+1); err != nil {
+			return err
+		}
+		members = append(members, member{key: encodedKey, value: value.Bytes()})
+	}
+	if _, err := f.dec.Token(); err != nil { // }
+		return err
+	}
+
+	if f.options.SortKeys {
+		sort.SliceStable(members, func(i, j int) bool {
+			return bytes.Compare(members[i].key, members[j].key) < 0
+		})
+	}
+
+	out.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		f.newline(out, depth+1)
+		out.Write(m.key)
+		out.WriteByte(':')
+		if f.indent != "" {
+			out.WriteByte(' ')
+		}
+		out.Write(m.value)
+	}
+	if len(members) > 0 {
+		f.newline(out, depth)
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// writeArray 输出数组
+func (f *jsonFormatter) writeArray(out *bytes.Buffer, depth int) error {
+	out.WriteByte('[')
+	n := 0
+	for f.dec.More() {
+		if n > 0 {
+			out.WriteByte(',')
+		}
+		f.newline(out, depth+1)
+		if err := f.writeValue(out, depth+1); err != nil {
+			return err
+		}
+		n++
+	}
+	if _, err := f.dec.Token(); err != nil { // ]
+		return err
+	}
+	if n > 0 {
+		f.newline(out, depth)
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+// newline 有缩进时换行并缩进到指定层级
+func (f *jsonFormatter) newline(out *bytes.Buffer, depth int) {
+	if f.indent == "" {
+		return
+	}
+	out.WriteByte('\n')
+	for i := 0; i < depth; i++ {
+		out.WriteString(f.indent)
+	}
+}
+
+// formatNumber 按 FloatPrecision 格式化小数，整数原样输出
+func (f *jsonFormatter) formatNumber(n json.Number) string {
+	s := n.String()
+	if f.options.FloatPrecision == 0 || !strings.ContainsAny(s, ".eE") {
+		return s
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	s = strconv.FormatFloat(v, 'f', f.options.FloatPrecision, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
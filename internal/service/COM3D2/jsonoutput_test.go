@@ -0,0 +1,83 @@
+package COM3D2
+
+import (
+	"testing"
+)
+
+func TestFormatJson(t *testing.T) {
+	const input = `{"b":1,"a":{"d":[1.23456,-0.0001,2e-3],"c":{}},"e":[]}`
+	tests := []struct {
+		name    string
+		options JsonOptions
+		want    string
+	}{
+		{
+			name:    "compact",
+			options: JsonOptions{},
+			want:    input,
+		},
+		{
+			name:    "indent",
+			options: JsonOptions{Indent: 2},
+			want:    "{\n  \"b\": 1,\n  \"a\": {\n    \"d\": [\n      1.23456,\n      -0.0001,\n      2e-3\n    ],\n    \"c\": {}\n  },\n  \"e\": []\n}\n",
+		},
+		{
+			name:    "sort keys",
+			options: JsonOptions{SortKeys: true},
+			want:    `{"a":{"c":{},"d":[1.23456,-0.0001,2e-3]},"b":1,"e":[]}`,
+		},
+		{
+			name:    "float precision",
+			options: JsonOptions{FloatPrecision: 3},
+			want:    `{"b":1,"a":{"d":[1.235,0,0.002],"c":{}},"e":[]}`,
+		},
+		{
+			name:    "sort keys and indent",
+			options: JsonOptions{SortKeys: true, Indent: 1},
+			want:    "{\n \"a\": {\n  \"c\": {},\n  \"d\": [\n   1.23456,\n   -0.0001,\n   2e-3\n  ]\n },\n \"b\": 1,\n \"e\": []\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatJson([]byte(input), tt.options)
+			if err != nil {
+				t.Fatalf("formatJson: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatJson:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 格式化不能改变整数和字符串，输入由 json.Marshal 生成，字符串中的 HTML 字符已经转义
+func TestFormatJsonPreservesValues(t *testing.T) {
+	const input = `{"big":12345678901234567890,"text":"\u003ca \u0026 b\u003e","flag":false,"none":null}`
+	got, err := formatJson([]byte(input), JsonOptions{SortKeys: true, FloatPrecision: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"big":12345678901234567890,"flag":false,"none":null,"text":"\u003ca \u0026 b\u003e"}`
+	if string(got) != want {
+		t.Errorf("formatJson:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestValidateJsonOptions(t *testing.T) {
+	tests := []struct {
+		options JsonOptions
+		wantErr bool
+	}{
+		{JsonOptions{}, false},
+		{JsonOptions{Indent: 16, FloatPrecision: 17}, false},
+		{JsonOptions{Indent: -1}, true},
+		{JsonOptions{Indent: 17}, true},
+		{JsonOptions{FloatPrecision: -1}, true},
+		{JsonOptions{FloatPrecision: 18}, true},
+	}
+	for _, tt := range tests {
+		if err := validateJsonOptions(tt.options); (err != nil) != tt.wantErr {
+			t.Errorf("validateJsonOptions(%+v) error = %v, wantErr %v", tt.options, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// 比较和合并都在通用 JSON 树上进行（map[string]interface{} / []interface{} / json.Number / string / bool / nil）
+// 这样不需要为每种格式编写比较代码，多态字段（例如 Material.Properties）也能统一处理
+// 数组元素默认按下标对应，elementKeyFuncs 中登记的数组按元素的键对应
+
+// elementKeyFunc 返回数组元素的匹配键，无法取得时返回 false，此时按下标匹配
+type elementKeyFunc func(element interface{}) (string, bool)
+
+// keyedElement 带匹配键的数组元素
+type keyedElement struct {
+	key   string
+	value interface{}
+}
+
+// elementKeyFuncs 按数组字段名称登记的元素匹配方式
+var elementKeyFuncs = map[string]elementKeyFunc{
+	"Commands":                  commandNameKey,            // .menu 命令，按命令名称
+	"Properties":                fieldKey("PropName"),      // 材质属性
+	"Materials":                 fieldKey("Name"),          // .model 材质
+	"Bones":                     fieldKey("Name"),          // .model 骨骼
+	"MorphData":                 fieldKey("Name"),          // .model 形态键
+	"BoneCurves":                fieldKey("BonePath"),      // .anm 骨骼曲线
+	"PropertyCurves":            fieldKey("PropertyIndex"), // .anm 属性曲线
+	"Keyframes":                 fieldKey("Time"),          // 曲线关键帧
+	"PartialDamping":            fieldKey("BoneName"),      // 以下为 .phy 各骨骼参数
+	"PartialElasticity":         fieldKey("BoneName"),
+	"PartialStiffness":          fieldKey("BoneName"),
+	"PartialInert":              fieldKey("BoneName"),
+	"PartialRadius":             fieldKey("BoneName"),
+	"PanierRadiusDistribGroups": fieldKey("BoneName"),         // .psk
+	"Colliders":                 fieldKey("Base", "SelfName"), // .col 碰撞体
+}
+
+// toTree 将结构体转换为通用 JSON 树，数字保留为 json.Number 以免损失精度
+func toTree(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// fromTree 将通用 JSON 树解码到 target
+func fromTree(tree interface{}, target interface{}) error {
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
+
+// fieldKey 使用元素中的字段作为匹配键，多个参数表示嵌套字段，例如 Base.SelfName
+func fieldKey(fields ...string) elementKeyFunc {
+	return func(element interface{}) (string, bool) {
+		value := element
+		for _, field := range fields {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			value = m[field]
+		}
+		switch v := value.(type) {
+		case string:
+			return v, v != ""
+		case json.Number:
+			return v.String(), true
+		default:
+			return "", false
+		}
+	}
+}
+
+// commandNameKey .menu 命令按命令名称匹配，同名命令按出现顺序区分
+func commandNameKey(element interface{}) (string, bool) {
+	args := commandArgs(element)
+	if len(args) == 0 {
+		return "", false
+	}
+	return args[0], true
+}
+
+// commandArgs 返回 .menu 命令的参数
+func commandArgs(element interface{}) []string {
+	m, ok := element.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	raw, ok := m["Args"].([]interface{})
+	if !ok {
+		return nil
+	}
+	args := make([]string, 0, len(raw))
+	for _, arg := range raw {
+		s, _ := arg.(string)
+		args = append(args, s)
+	}
+	return args
+}
+
+// keyElements 计算数组元素的匹配键，重复的键追加 #2、#3 区分，没有键的元素使用 #下标
+func keyElements(array []interface{}, keyFunc elementKeyFunc) []keyedElement {
+	keyed := make([]keyedElement, 0, len(array))
+	seen := map[string]int{}
+	for i, element := range array {
+		key, ok := "", false
+		if keyFunc != nil {
+			key, ok = keyFunc(element)
+		}
+		if !ok {
+			key = fmt.Sprintf("#%d", i)
+		}
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		keyed = append(keyed, keyedElement{key: key, value: element})
+	}
+	return keyed
+}
+
+// treeEqual 比较两个 JSON 树是否相同
+func treeEqual(a interface{}, b interface{}) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+// joinTreePath 拼接字段路径
+func joinTreePath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
@@ -0,0 +1,343 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 检查文件内容是否能被游戏正确使用，而不只是能否解析
+// 每条规则实现 LintRule 接口并通过 RegisterLintRule 注册，内置规则见 lintrules.go
+// 规则可以在项目目录的 .com3d2lint.json 中关闭或调整严重程度，例如 {"Rules": {"menu/required-commands": "off"}}
+
+// 诊断严重程度
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintInfo    = "info"
+	LintOff     = "off" // 只用于配置，表示关闭规则
+)
+
+// LintConfigFileName 项目配置文件名称，从被检查的文件所在目录开始向上查找
+const LintConfigFileName = ".com3d2lint.json"
+
+// lintParseRule 文件无法解析时使用的规则名称
+const lintParseRule = "parse"
+
+// LintRule 一条检查规则
+type LintRule interface {
+	Name() string        // 规则名称，格式为 文件类型/名称，例如 menu/required-commands，通用规则以 common/ 开头
+	Description() string // 说明
+	FileTypes() []string // 适用的文件类型
+	Severity() string    // 默认严重程度 error/warning/info
+	// Check 检查数据，data 为对应格式的结构体指针，发现问题时调用 report，path 为 JSON Pointer
+	Check(data interface{}, report func(path string, message string))
+}
+
+// LintService 检查文件内容
+type LintService struct{}
+
+// LintOptions 检查选项
+type LintOptions struct {
+	ConfigPath string            `json:"ConfigPath"` // 配置文件路径，为空时自动查找 .com3d2lint.json
+	Rules      map[string]string `json:"Rules"`      // 规则名称 → 严重程度或 off，优先于配置文件
+}
+
+// LintConfig .com3d2lint.json 的内容
+type LintConfig struct {
+	Rules map[string]string `json:"Rules"` // 规则名称 → 严重程度或 off
+}
+
+// LintDiagnostic 一个问题
+type LintDiagnostic struct {
+	Severity string `json:"Severity"` // error/warning/info，见顶部常量定义
+	Rule     string `json:"Rule"`
+	Path     string `json:"Path"` // JSON Pointer，例如 /Commands/3/Args/1
+	Message  string `json:"Message"`
+}
+
+// LintResult 一个文件的检查结果
+type LintResult struct {
+	Path        string           `json:"Path"`
+	FileType    string           `json:"FileType"`
+	Diagnostics []LintDiagnostic `json:"Diagnostics"`
+	Errors      int              `json:"Errors"`
+	Warnings    int              `json:"Warnings"`
+}
+
+// LintRuleInfo 前端使用的规则描述
+type LintRuleInfo struct {
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	FileTypes   []string `json:"FileTypes"`
+	Severity    string   `json:"Severity"`
+}
+
+// lintRules 已注册的规则
+var lintRules = map[string]LintRule{}
+
+// RegisterLintRule 注册规则，名称重复时 panic
+func RegisterLintRule(rule LintRule) {
+	if _, exists := lintRules[rule.Name()]; exists {
+		panic("duplicate lint rule: " + rule.Name())
+	}
+	lintRules[rule.Name()] = rule
+}
+
+// ListLintRules 返回所有规则，按名称排序
+func (l *LintService) ListLintRules() []LintRuleInfo {
+	infos := make([]LintRuleInfo, 0, len(lintRules))
+	for _, rule := range lintRules {
+		infos = append(infos, LintRuleInfo{
+			Name:        rule.Name(),
+			Description: rule.Description(),
+			FileTypes:   rule.FileTypes(),
+			Severity:    rule.Severity(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// LintFile 检查一个文件（二进制或 .json 均可），无法解析时返回一个 parse 错误诊断
+func (l *LintService) LintFile(path string, options LintOptions) (*LintResult, error) {
+	severities, err := lintSeverities(filepath.Dir(path), options)
+	if err != nil {
+		return nil, err
+	}
+	return lintFile(path, severities), nil
+}
+
+// LintDirectory 检查目录中所有能识别的文件
+func (l *LintService) LintDirectory(dir string, options LintOptions) ([]LintResult, error) {
+	severities, err := lintSeverities(dir, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []LintResult
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := FormatByPath(path); !ok {
+			return nil
+		}
+		results = append(results, *lintFile(path, severities))
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return results, nil
+}
+
+// lintFile 读取并检查文件
+func lintFile(path string, severities map[string]string) *LintResult {
+	result := &LintResult{Path: path}
+	report := func(rule string, severity string, pointer string, message string) {
+		result.Diagnostics = append(result.Diagnostics, LintDiagnostic{Severity: severity, Rule: rule, Path: pointer, Message: message})
+		switch severity {
+		case LintError:
+			result.Errors++
+		case LintWarning:
+			result.Warnings++
+		}
+	}
+
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(path, false)
+	if err != nil {
+		report(lintParseRule, LintError, "", err.Error())
+		return result
+	}
+	result.FileType = fileInfo.FileType
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok {
+		report(lintParseRule, LintError, "", fmt.Sprintf("unsupported file type %s", fileInfo.FileType))
+		return result
+	}
+	data, err := f.Read(path)
+	if err != nil {
+		report(lintParseRule, LintError, "", err.Error())
+		return result
+	}
+
+	for _, rule := range sortedLintRules() {
+		if !containsString(rule.FileTypes(), f.FileType()) {
+			continue
+		}
+		severity := rule.Severity()
+		if configured, ok := severities[rule.Name()]; ok {
+			severity = configured
+		}
+		if severity == LintOff {
+			continue
+		}
+		rule.Check(data, func(pointer string, message string) {
+			report(rule.Name(), severity, pointer, message)
+		})
+	}
+	return result
+}
+
+// sortedLintRules 按名称排序的规则，保证输出顺序稳定
+func sortedLintRules() []LintRule {
+	rules := make([]LintRule, 0, len(lintRules))
+	for _, rule := range lintRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name() < rules[j].Name()
+	})
+	return rules
+}
+
+// lintSeverities 合并配置文件和选项中的规则设置
+func lintSeverities(dir string, options LintOptions) (map[string]string, error) {
+	configPath := options.ConfigPath
+	if configPath == "" {
+		configPath = findLintConfig(dir)
+	}
+
+	severities := map[string]string{}
+	if configPath != "" {
+		raw, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read lint config: %w", err)
+		}
+		var config LintConfig
+		if err := json.Unmarshal(raw, &config); err != nil {
+			return nil, fmt.Errorf("invalid lint config %s: %w", configPath, err)
+		}
+		for name, severity := range config.Rules {
+			severities[name] = severity
+		}
+	}
+	for name, severity := range options.Rules {
+		severities[name] = severity
+	}
+
+	for name, severity := range severities {
+		if _, ok := lintRules[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+		switch severity {
+		case LintError, LintWarning, LintInfo, LintOff:
+		default:
+			return nil, fmt.Errorf("invalid severity %q for lint rule %s", severity, name)
+		}
+	}
+	return severities, nil
+}
+
+// findLintConfig 从 dir 开始向上查找配置文件，找不到时返回空字符串
+func findLintConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, LintConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// containsString 切片中是否包含 s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lintWalk 遍历结构体中的所有值，visit 收到 JSON Pointer 路径、所在的 Go 字段名称和值（已解开指针和接口）
+// 字段名称使用 JSON 中的名称拼接路径，数组元素的字段名称与数组相同
+func lintWalk(v reflect.Value, path string, field string, visit func(path string, field string, v reflect.Value)) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	visit(path, field, v)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		lintWalkStruct(v, path, visit)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			lintWalk(v.Index(i), fmt.Sprintf("%s/%d", path, i), field, visit)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			lintWalk(v.MapIndex(key), path+"/"+escapePointerToken(fmt.Sprint(key.Interface())), field, visit)
+		}
+	}
+}
+
+// lintWalkStruct 遍历结构体字段，匿名嵌入的结构体字段按 encoding/json 的规则展开
+func lintWalkStruct(v reflect.Value, path string, visit func(path string, field string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				lintWalkStruct(embedded, path, visit)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		lintWalk(v.Field(i), path+"/"+escapePointerToken(name), sf.Name, visit)
+	}
+}
+
+// escapePointerToken 转义 JSON Pointer 中的一级名称
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
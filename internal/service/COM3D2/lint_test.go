@@ -0,0 +1,367 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lintReport 规则报告的一个问题
+type lintReport struct {
+	Path    string
+	Message string
+}
+
+// runLintRule 使用已注册的规则检查 data
+func runLintRule(t *testing.T, name string, data interface{}) []lintReport {
+	t.Helper()
+	rule, ok := lintRules[name]
+	if !ok {
+		t.Fatalf("lint rule %s is not registered", name)
+	}
+	var reports []lintReport
+	rule.Check(data, func(path string, message string) {
+		reports = append(reports, lintReport{path, message})
+	})
+	return reports
+}
+
+// reportPaths 报告的路径，用于只关心位置的规则
+func reportPaths(reports []lintReport) []string {
+	var paths []string
+	for _, r := range reports {
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+// lintMenu 由命令构造 .menu 数据
+func lintMenu(commands ...[]string) *COM3D2.Menu {
+	menu := &COM3D2.Menu{}
+	for _, args := range commands {
+		menu.Commands = append(menu.Commands, COM3D2.Command{ArgCount: uint8(len(args)), Args: args})
+	}
+	return menu
+}
+
+func TestLintRequiredMenuCommands(t *testing.T) {
+	name, category := []string{"name", "test"}, []string{"category", "wear"}
+	tests := []struct {
+		name string
+		menu *COM3D2.Menu
+		want []lintReport
+	}{
+		{"icons", lintMenu(name, category, []string{"icons", "test_i_.tex"}), nil},
+		{"icon", lintMenu(name, category, []string{"icon", "test_i_.tex"}), nil},
+		{"missing icon", lintMenu(name, category), []lintReport{{"/Commands", "missing icon or icons command"}}},
+		{"empty value", lintMenu([]string{"name", " "}, category, []string{"icon"}), []lintReport{
+			{"/Commands/0", "name command has no value"},
+			{"/Commands/2", "icon command has no value"},
+		}},
+		{"empty menu", lintMenu(), []lintReport{
+			{"/Commands", "missing name command"},
+			{"/Commands", "missing category command"},
+			{"/Commands", "missing icon or icons command"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runLintRule(t, "menu/required-commands", tt.menu); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reports = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintEmptyMenuCommand(t *testing.T) {
+	menu := lintMenu([]string{"name", "test"}, nil, []string{"", "value"})
+	if got, want := reportPaths(runLintRule(t, "menu/empty-command", menu)), []string{"/Commands/1", "/Commands/2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+// 通用规则按字段名称匹配，使用最小的结构体即可
+func TestLintNonFinite(t *testing.T) {
+	type nested struct {
+		X float32 `json:"x"`
+	}
+	data := &struct {
+		Scale   float32
+		Values  []float64
+		Nested  *nested
+		Ignored float32 `json:"-"`
+		Count   int32
+	}{
+		Scale:   float32(math.Inf(1)),
+		Values:  []float64{0, math.NaN()},
+		Nested:  &nested{X: float32(math.Inf(-1))},
+		Ignored: float32(math.NaN()),
+	}
+	got := reportPaths(runLintRule(t, "common/non-finite", data))
+	if want := []string{"/Scale", "/Values/1", "/Nested/x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestLintNegativeRadius(t *testing.T) {
+	type collider struct {
+		Radius       float32
+		HeightRadius int32
+	}
+	data := &struct {
+		Colliders []collider
+		Size      float32
+	}{
+		Colliders: []collider{{Radius: 0.5, HeightRadius: -1}, {Radius: -0.5}},
+		Size:      -1,
+	}
+	got := runLintRule(t, "common/negative-radius", data)
+	want := []lintReport{
+		{"/Colliders/0/HeightRadius", "radius is negative (-1)"},
+		{"/Colliders/1/Radius", "radius is negative (-0.5)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reports = %v, want %v", got, want)
+	}
+}
+
+func TestLintKeyframeOrder(t *testing.T) {
+	type keyframe struct {
+		Time  float32
+		Value float32
+	}
+	type curve struct {
+		Keyframes []keyframe
+	}
+	data := &struct {
+		Sorted   curve
+		Unsorted curve
+	}{
+		Sorted:   curve{Keyframes: []keyframe{{Time: 0}, {Time: 0.5}, {Time: 1}}},
+		Unsorted: curve{Keyframes: []keyframe{{Time: 0}, {Time: 1}, {Time: 1}, {Time: 0.5}}},
+	}
+	got := reportPaths(runLintRule(t, "common/unsorted-keyframes", data))
+	if want := []string{"/Unsorted/Keyframes/2/Time", "/Unsorted/Keyframes/3/Time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestLintEmptyBonePath(t *testing.T) {
+	data := &struct {
+		Curves []struct{ BonePath string }
+	}{
+		Curves: []struct{ BonePath string }{{"Bip01"}, {""}},
+	}
+	if got, want := reportPaths(runLintRule(t, "anm/empty-bone-path", data)), []string{"/Curves/1/BonePath"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestLintMaterialRules(t *testing.T) {
+	data := &struct {
+		Materials []COM3D2.Material
+	}{
+		Materials: []COM3D2.Material{
+			{Name: "ok", ShaderName: "CM3D2/Toony_Lighted", ShaderFilename: "toony_lighted"},
+			{ShaderName: "CM3D2/Toony_Lighted"},
+		},
+	}
+	if got, want := reportPaths(runLintRule(t, "material/empty-shader", data)), []string{"/Materials/1/ShaderFilename"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("material/empty-shader paths = %v, want %v", got, want)
+	}
+	if got, want := reportPaths(runLintRule(t, "material/empty-name", data)), []string{"/Materials/1/Name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("material/empty-name paths = %v, want %v", got, want)
+	}
+}
+
+func TestLintPMatFields(t *testing.T) {
+	if got := runLintRule(t, "pmat/empty-fields", &COM3D2.PMat{MaterialName: "body", Shader: "CM3D2/Toony_Lighted"}); len(got) != 0 {
+		t.Errorf("reports = %v", got)
+	}
+	got := reportPaths(runLintRule(t, "pmat/empty-fields", &COM3D2.PMat{}))
+	if want := []string{"/MaterialName", "/Shader"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+}
+
+func TestLintModelBones(t *testing.T) {
+	var model COM3D2.Model
+	const modelJson = `{"RootBoneName": "Bip01 Pelvis", "Bones": [{"Name": "Bip01"}, {"Name": "Bip01 Spine"}, {"Name": "Bip01"}]}`
+	if err := json.Unmarshal([]byte(modelJson), &model); err != nil {
+		t.Fatal(err)
+	}
+	got := runLintRule(t, "model/root-bone", &model)
+	if want := []lintReport{{"/RootBoneName", `root bone "Bip01 Pelvis" is not in the bone list`}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("model/root-bone reports = %v, want %v", got, want)
+	}
+	model.RootBoneName = "Bip01"
+	if got := runLintRule(t, "model/root-bone", &model); len(got) != 0 {
+		t.Errorf("model/root-bone reports = %v", got)
+	}
+
+	got = runLintRule(t, "model/duplicate-bone", &model)
+	if want := []lintReport{{"/Bones/2/Name", `bone "Bip01" is already defined at /Bones/0`}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("model/duplicate-bone reports = %v, want %v", got, want)
+	}
+}
+
+// 规则只检查适用的文件类型，收到其他类型的数据时不报告
+func TestLintRulesIgnoreOtherTypes(t *testing.T) {
+	for _, name := range []string{"menu/required-commands", "menu/empty-command", "pmat/empty-fields", "model/root-bone", "model/duplicate-bone"} {
+		if got := runLintRule(t, name, &COM3D2.Mate{}); len(got) != 0 {
+			t.Errorf("%s reports = %v", name, got)
+		}
+	}
+}
+
+// 缺少 icon 命令的 .menu，menu/required-commands 报错
+var incompleteMenu = fixtureMenu([]string{"name", "test"}, []string{"category", "wear"})
+
+func TestLintSeverities(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string][]byte{
+		LintConfigFileName: []byte(`{"Rules": {"menu/required-commands": "warning", "material/empty-name": "off"}}`),
+		"other.json":       []byte(`{"Rules": {"pmat/empty-fields": "info"}}`),
+		"broken.json":      []byte(`{"Rules": `),
+		"sub/dir/.keep":    nil,
+	})
+	dir := filepath.Join(root, "sub", "dir")
+
+	tests := []struct {
+		name    string
+		options LintOptions
+		want    map[string]string
+		wantErr string
+	}{
+		// 从子目录向上找到配置文件
+		{"config", LintOptions{}, map[string]string{"menu/required-commands": "warning", "material/empty-name": "off"}, ""},
+		// 选项中的规则优先于配置文件，其余规则保留配置文件的设置
+		{"options override config", LintOptions{Rules: map[string]string{"material/empty-name": "error", "model/root-bone": "off"}},
+			map[string]string{"menu/required-commands": "warning", "material/empty-name": "error", "model/root-bone": "off"}, ""},
+		// 指定配置文件时不查找 .com3d2lint.json
+		{"explicit config", LintOptions{ConfigPath: filepath.Join(root, "other.json"), Rules: map[string]string{"menu/empty-command": "warning"}},
+			map[string]string{"pmat/empty-fields": "info", "menu/empty-command": "warning"}, ""},
+		{"unknown rule", LintOptions{Rules: map[string]string{"menu/unknown": "off"}}, nil, `unknown lint rule "menu/unknown"`},
+		{"invalid severity", LintOptions{Rules: map[string]string{"menu/empty-command": "fatal"}}, nil, `invalid severity "fatal"`},
+		{"broken config", LintOptions{ConfigPath: filepath.Join(root, "broken.json")}, nil, "invalid lint config"},
+		{"missing config", LintOptions{ConfigPath: filepath.Join(root, "missing.json")}, nil, "cannot read lint config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lintSeverities(dir, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("severities = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 没有配置文件时为空
+	if got, err := lintSeverities(t.TempDir(), LintOptions{}); err != nil || len(got) != 0 {
+		t.Errorf("severities without config = %v, %v", got, err)
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"incomplete.menu": incompleteMenu,
+		"broken.menu":     []byte("not a menu"),
+	})
+	service := &LintService{}
+
+	result, err := service.LintFile(filepath.Join(dir, "incomplete.menu"), LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LintDiagnostic{{Severity: LintError, Rule: "menu/required-commands", Path: "/Commands", Message: "missing icon or icons command"}}
+	if result.FileType != "menu" || result.Errors != 1 || result.Warnings != 0 || !reflect.DeepEqual(result.Diagnostics, want) {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 配置文件降低严重程度后只是警告
+	writeFiles(t, dir, map[string][]byte{LintConfigFileName: []byte(`{"Rules": {"menu/required-commands": "warning"}}`)})
+	result, err = service.LintFile(filepath.Join(dir, "incomplete.menu"), LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Errors != 0 || result.Warnings != 1 || result.Diagnostics[0].Severity != LintWarning {
+		t.Errorf("result with config = %+v", result)
+	}
+
+	// 关闭的规则不检查
+	result, err = service.LintFile(filepath.Join(dir, "incomplete.menu"), LintOptions{Rules: map[string]string{"menu/required-commands": LintOff}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diagnostics) != 0 {
+		t.Errorf("diagnostics with the rule off = %+v", result.Diagnostics)
+	}
+
+	// 无法解析时报告 parse 错误而不是返回错误
+	result, err = service.LintFile(filepath.Join(dir, "broken.menu"), LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Errors != 1 || len(result.Diagnostics) != 1 || result.Diagnostics[0].Rule != lintParseRule {
+		t.Errorf("result of a broken file = %+v", result)
+	}
+}
+
+func TestLintDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"a/complete.menu":   fixtureMenu([]string{"name", "test"}, []string{"category", "wear"}, []string{"icons", "test_i_.tex"}),
+		"b/incomplete.menu": incompleteMenu,
+		"readme.txt":        []byte("not checked"),
+	})
+	results, err := (&LintService{}).LintDirectory(dir, LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v", results)
+	}
+	if filepath.Base(results[0].Path) != "complete.menu" || len(results[0].Diagnostics) != 0 {
+		t.Errorf("results[0] = %+v", results[0])
+	}
+	if filepath.Base(results[1].Path) != "incomplete.menu" || results[1].Errors != 1 {
+		t.Errorf("results[1] = %+v", results[1])
+	}
+}
+
+func TestListLintRules(t *testing.T) {
+	rules := (&LintService{}).ListLintRules()
+	if len(rules) != len(lintRules) {
+		t.Fatalf("ListLintRules returned %d rules, want %d", len(rules), len(lintRules))
+	}
+	for i, rule := range rules {
+		if i > 0 && rules[i-1].Name >= rule.Name {
+			t.Errorf("rules are not sorted: %s before %s", rules[i-1].Name, rule.Name)
+		}
+		switch rule.Severity {
+		case LintError, LintWarning, LintInfo:
+		default:
+			t.Errorf("%s has default severity %q", rule.Name, rule.Severity)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate rule did not panic")
+		}
+	}()
+	RegisterLintRule(lintRules["menu/empty-command"])
+}
@@ -0,0 +1,320 @@
+package COM3D2
+
+import (
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// 内置检查规则
+// 通用规则按 Go 字段名称匹配（例如名称包含 Radius 的数值字段），不依赖具体格式的结构
+
+// lintRule LintRule 的通用实现
+type lintRule struct {
+	name        string
+	description string
+	fileTypes   []string
+	severity    string
+	check       func(data interface{}, report func(path string, message string))
+}
+
+func (r *lintRule) Name() string        { return r.name }
+func (r *lintRule) Description() string { return r.description }
+func (r *lintRule) FileTypes() []string { return r.fileTypes }
+func (r *lintRule) Severity() string    { return r.severity }
+func (r *lintRule) Check(data interface{}, report func(path string, message string)) {
+	r.check(data, report)
+}
+
+// requiredMenuCommands .menu 必须包含且参数不能为空的命令
+// 每组是同一命令的不同写法，游戏同时接受 icon 和 icons，有其中一个即可
+var requiredMenuCommands = [][]string{{"name"}, {"category"}, {"icon", "icons"}}
+
+// materialType 材质结构体类型，.mate 和 .model 中的材质都会被检查
+var materialType = reflect.TypeOf(COM3D2.Material{})
+
+func init() {
+	for _, rule := range []*lintRule{
+		{
+			name:        "common/non-finite",
+			description: "float values must not be NaN or Infinity",
+			fileTypes:   []string{"menu", "mate", "pmat", "phy", "col", "psk", "anm", "model"},
+			severity:    LintError,
+			check:       checkNonFinite,
+		},
+		{
+			name:        "common/negative-radius",
+			description: "radius values must not be negative",
+			fileTypes:   []string{"phy", "col", "psk"},
+			severity:    LintError,
+			check:       checkNegativeRadius,
+		},
+		{
+			name:        "common/unsorted-keyframes",
+			description: "curve keyframe times must be strictly increasing",
+			fileTypes:   []string{"phy", "anm"},
+			severity:    LintError,
+			check:       checkKeyframeOrder,
+		},
+		{
+			name:        "menu/required-commands",
+			description: "name, category and icon (or icons) commands must be present and have a value",
+			fileTypes:   []string{"menu"},
+			severity:    LintError,
+			check:       checkRequiredMenuCommands,
+		},
+		{
+			name:        "menu/empty-command",
+			description: "commands must have a name",
+			fileTypes:   []string{"menu"},
+			severity:    LintError,
+			check:       checkEmptyMenuCommands,
+		},
+		{
+			name:        "material/empty-shader",
+			description: "materials must have a ShaderName and ShaderFilename",
+			fileTypes:   []string{"mate", "model"},
+			severity:    LintError,
+			check:       checkMaterialShader,
+		},
+		{
+			name:        "material/empty-name",
+			description: "materials should have a name",
+			fileTypes:   []string{"mate", "model"},
+			severity:    LintWarning,
+			check:       checkMaterialName,
+		},
+		{
+			name:        "pmat/empty-fields",
+			description: "MaterialName and Shader must not be empty",
+			fileTypes:   []string{"pmat"},
+			severity:    LintError,
+			check:       checkPMatFields,
+		},
+		{
+			name:        "anm/empty-bone-path",
+			description: "bone curves must have a bone path",
+			fileTypes:   []string{"anm"},
+			severity:    LintError,
+			check:       checkEmptyBonePath,
+		},
+		{
+			name:        "model/root-bone",
+			description: "RootBoneName must be one of the model's bones",
+			fileTypes:   []string{"model"},
+			severity:    LintError,
+			check:       checkModelRootBone,
+		},
+		{
+			name:        "model/duplicate-bone",
+			description: "bone names should be unique",
+			fileTypes:   []string{"model"},
+			severity:    LintWarning,
+			check:       checkModelDuplicateBones,
+		},
+	} {
+		RegisterLintRule(rule)
+	}
+}
+
+// checkNonFinite 浮点数不能是 NaN 或无穷大
+func checkNonFinite(data interface{}, report func(path string, message string)) {
+	lintWalk(reflect.ValueOf(data), "", "", func(path string, field string, v reflect.Value) {
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return
+		}
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			report(path, fmt.Sprintf("value is %v", f))
+		}
+	})
+}
+
+// checkNegativeRadius 名称包含 Radius 的数值字段不能为负
+func checkNegativeRadius(data interface{}, report func(path string, message string)) {
+	lintWalk(reflect.ValueOf(data), "", "", func(path string, field string, v reflect.Value) {
+		if !strings.Contains(field, "Radius") {
+			return
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if v.Float() < 0 {
+				report(path, fmt.Sprintf("radius is negative (%v)", v.Float()))
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() < 0 {
+				report(path, fmt.Sprintf("radius is negative (%d)", v.Int()))
+			}
+		}
+	})
+}
+
+// checkKeyframeOrder Keyframes 数组中的 Time 必须严格递增
+func checkKeyframeOrder(data interface{}, report func(path string, message string)) {
+	lintWalk(reflect.ValueOf(data), "", "", func(path string, field string, v reflect.Value) {
+		if field != "Keyframes" || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) {
+			return
+		}
+		previous := math.Inf(-1)
+		for i := 0; i < v.Len(); i++ {
+			t, ok := floatField(v.Index(i), "Time")
+			if !ok {
+				return
+			}
+			if t <= previous {
+				report(fmt.Sprintf("%s/%d/Time", path, i), fmt.Sprintf("keyframe time %v is not after the previous keyframe (%v)", t, previous))
+			}
+			previous = t
+		}
+	})
+}
+
+// checkRequiredMenuCommands 必需的命令存在且第一个参数不为空
+func checkRequiredMenuCommands(data interface{}, report func(path string, message string)) {
+	menu, ok := data.(*COM3D2.Menu)
+	if !ok {
+		return
+	}
+	found := make([]bool, len(requiredMenuCommands))
+	for i, command := range menu.Commands {
+		if len(command.Args) == 0 {
+			continue
+		}
+		for group, names := range requiredMenuCommands {
+			if !containsString(names, command.Args[0]) {
+				continue
+			}
+			found[group] = true
+			if len(command.Args) < 2 || strings.TrimSpace(command.Args[1]) == "" {
+				report(fmt.Sprintf("/Commands/%d", i), fmt.Sprintf("%s command has no value", command.Args[0]))
+			}
+		}
+	}
+	for group, names := range requiredMenuCommands {
+		if !found[group] {
+			report("/Commands", fmt.Sprintf("missing %s command", strings.Join(names, " or ")))
+		}
+	}
+}
+
+// checkEmptyMenuCommands 命令不能没有名称
+func checkEmptyMenuCommands(data interface{}, report func(path string, message string)) {
+	menu, ok := data.(*COM3D2.Menu)
+	if !ok {
+		return
+	}
+	for i, command := range menu.Commands {
+		if len(command.Args) == 0 || strings.TrimSpace(command.Args[0]) == "" {
+			report(fmt.Sprintf("/Commands/%d", i), "command has no name")
+		}
+	}
+}
+
+// checkMaterialShader 材质的着色器不能为空
+func checkMaterialShader(data interface{}, report func(path string, message string)) {
+	forEachMaterial(data, func(path string, material *COM3D2.Material) {
+		if material.ShaderName == "" {
+			report(path+"/ShaderName", "material has no ShaderName")
+		}
+		if material.ShaderFilename == "" {
+			report(path+"/ShaderFilename", "material has no ShaderFilename")
+		}
+	})
+}
+
+// checkMaterialName 材质名称不应为空
+func checkMaterialName(data interface{}, report func(path string, message string)) {
+	forEachMaterial(data, func(path string, material *COM3D2.Material) {
+		if material.Name == "" {
+			report(path+"/Name", "material has no name")
+		}
+	})
+}
+
+// checkPMatFields .pmat 的材质名称和着色器不能为空
+func checkPMatFields(data interface{}, report func(path string, message string)) {
+	pmat, ok := data.(*COM3D2.PMat)
+	if !ok {
+		return
+	}
+	if pmat.MaterialName == "" {
+		report("/MaterialName", "MaterialName is empty")
+	}
+	if pmat.Shader == "" {
+		report("/Shader", "Shader is empty")
+	}
+}
+
+// checkEmptyBonePath 骨骼曲线的 BonePath 不能为空
+func checkEmptyBonePath(data interface{}, report func(path string, message string)) {
+	lintWalk(reflect.ValueOf(data), "", "", func(path string, field string, v reflect.Value) {
+		if field == "BonePath" && v.Kind() == reflect.String && v.String() == "" {
+			report(path, "bone path is empty")
+		}
+	})
+}
+
+// checkModelRootBone RootBoneName 必须是模型中的骨骼
+func checkModelRootBone(data interface{}, report func(path string, message string)) {
+	model, ok := data.(*COM3D2.Model)
+	if !ok {
+		return
+	}
+	for _, bone := range model.Bones {
+		if bone != nil && bone.Name == model.RootBoneName {
+			return
+		}
+	}
+	report("/RootBoneName", fmt.Sprintf("root bone %q is not in the bone list", model.RootBoneName))
+}
+
+// checkModelDuplicateBones 骨骼名称不应重复
+func checkModelDuplicateBones(data interface{}, report func(path string, message string)) {
+	model, ok := data.(*COM3D2.Model)
+	if !ok {
+		return
+	}
+	seen := map[string]int{}
+	for i, bone := range model.Bones {
+		if bone == nil {
+			continue
+		}
+		if first, exists := seen[bone.Name]; exists {
+			report(fmt.Sprintf("/Bones/%d/Name", i), fmt.Sprintf("bone %q is already defined at /Bones/%d", bone.Name, first))
+			continue
+		}
+		seen[bone.Name] = i
+	}
+}
+
+// forEachMaterial 遍历数据中的所有材质
+func forEachMaterial(data interface{}, fn func(path string, material *COM3D2.Material)) {
+	lintWalk(reflect.ValueOf(data), "", "", func(path string, field string, v reflect.Value) {
+		if v.Type() == materialType && v.CanAddr() {
+			fn(path, v.Addr().Interface().(*COM3D2.Material))
+		}
+	})
+}
+
+// floatField 读取结构体中的数值字段
+func floatField(v reflect.Value, name string) (float64, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0, false
+	}
+	f := v.FieldByName(name)
+	switch f.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return f.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(f.Int()), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,307 @@
+package COM3D2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 三方合并在通用 JSON 树上进行，见 jsontree.go
+// 对象按字段合并，登记了匹配方式的数组按元素的键合并，其余数组和标量作为整体比较
+// 双方都修改了同一个值且结果不同时记录为冲突
+
+// mergeableTypes 支持合并的文件类型，这些格式的内容以参数为主，合并结果有意义
+var mergeableTypes = map[string]bool{
+	"menu": true,
+	"mate": true,
+	"pmat": true,
+	"phy":  true,
+	"psk":  true,
+	"col":  true,
+}
+
+// mergeKeyFuncs 合并时使用的数组匹配方式
+// .menu 命令按 命令名称 + 第一个参数 匹配，这样两边分别修改不同部位的同名命令（例如 additem）不会互相冲突
+var mergeKeyFuncs = func() map[string]elementKeyFunc {
+	funcs := make(map[string]elementKeyFunc, len(elementKeyFuncs))
+	for field, keyFunc := range elementKeyFuncs {
+		funcs[field] = keyFunc
+	}
+	funcs["Commands"] = commandNameAndFirstArgKey
+	return funcs
+}()
+
+// absentValue 表示值在某一方中不存在
+type absentValue struct{}
+
+var absent interface{} = absentValue{}
+
+// MergeService 三方合并同一文件的两个修改版本
+type MergeService struct{}
+
+// MergeConflict 双方对同一个值做了不同的修改
+// 值为 null 表示该方删除了此值或值本身为 null
+type MergeConflict struct {
+	Path   string      `json:"Path"` // 字段路径，格式同 DiffChange.Path
+	Base   interface{} `json:"Base"`
+	Ours   interface{} `json:"Ours"`
+	Theirs interface{} `json:"Theirs"`
+}
+
+// MergeResult 合并结果，没有冲突时 Merged 为合并后的结构体，否则为空，需要根据 Conflicts 手动处理
+type MergeResult struct {
+	FileType  string          `json:"FileType"`
+	Clean     bool            `json:"Clean"`
+	Merged    interface{}     `json:"Merged"`
+	Conflicts []MergeConflict `json:"Conflicts"`
+}
+
+// MergeFiles 读取共同祖先 base 和两个修改版本 ours、theirs（二进制或 .json 均可）并合并
+func (m *MergeService) MergeFiles(basePath string, oursPath string, theirsPath string) (*MergeResult, error) {
+	f, baseTree, err := readMergeTree(basePath)
+	if err != nil {
+		return nil, err
+	}
+	oursFormat, oursTree, err := readMergeTree(oursPath)
+	if err != nil {
+		return nil, err
+	}
+	theirsFormat, theirsTree, err := readMergeTree(theirsPath)
+	if err != nil {
+		return nil, err
+	}
+	if oursFormat.FileType() != f.FileType() || theirsFormat.FileType() != f.FileType() {
+		return nil, fmt.Errorf("cannot merge files of different types: %s, %s, %s", f.Extension(), oursFormat.Extension(), theirsFormat.Extension())
+	}
+
+	result := &MergeResult{FileType: f.FileType()}
+	merged := mergeTree("", "", baseTree, oursTree, theirsTree, &result.Conflicts)
+	result.Clean = len(result.Conflicts) == 0
+	if !result.Clean {
+		return result, nil
+	}
+
+	data := f.New()
+	if err := fromTree(merged, data); err != nil {
+		return nil, fmt.Errorf("failed to build merged %s data: %w", f.FileType(), err)
+	}
+	result.Merged = data
+	return result, nil
+}
+
+// MergeFilesAndWrite 合并并在没有冲突时写入 outputPath，根据后缀写出二进制或 .json 文件
+// 有冲突时不写出任何文件，冲突列表在返回值中
+func (m *MergeService) MergeFilesAndWrite(basePath string, oursPath string, theirsPath string, outputPath string) (*MergeResult, error) {
+	result, err := m.MergeFiles(basePath, oursPath, theirsPath)
+	if err != nil || !result.Clean {
+		return result, err
+	}
+	f, _ := FormatByFileType(result.FileType)
+	if err := f.Write(outputPath, result.Merged); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// readMergeTree 读取文件并转换为用于合并的 JSON 树
+func readMergeTree(path string) (FormatHandler, interface{}, error) {
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(path, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok || !mergeableTypes[f.FileType()] {
+		return nil, nil, fmt.Errorf("%w: %s files cannot be merged", ErrUnsupportedFileType, fileInfo.FileType)
+	}
+	data, err := f.Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := toTree(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal %s data: %w", f.FileType(), err)
+	}
+	return f, tree, nil
+}
+
+// mergeTree 三方合并一个值，不存在的值用 absent 表示，返回 absent 表示合并结果中删除此值
+// 冲突时保留 ours 的值并记录冲突
+func mergeTree(path string, field string, base interface{}, ours interface{}, theirs interface{}, conflicts *[]MergeConflict) interface{} {
+	switch {
+	case sameValue(ours, theirs):
+		return ours
+	case sameValue(base, ours):
+		return theirs
+	case sameValue(base, theirs):
+		return ours
+	}
+
+	// 双方都修改了，尝试深入合并
+	baseMap, baseIsMap := base.(map[string]interface{})
+	oursMap, oursIsMap := ours.(map[string]interface{})
+	theirsMap, theirsIsMap := theirs.(map[string]interface{})
+	if baseIsMap && oursIsMap && theirsIsMap {
+		return mergeObject(path, baseMap, oursMap, theirsMap, conflicts)
+	}
+
+	baseArray, baseIsArray := base.([]interface{})
+	oursArray, oursIsArray := ours.([]interface{})
+	theirsArray, theirsIsArray := theirs.([]interface{})
+	if keyFunc := mergeKeyFuncs[field]; keyFunc != nil && baseIsArray && oursIsArray && theirsIsArray {
+		return mergeArray(path, keyFunc, baseArray, oursArray, theirsArray, conflicts)
+	}
+
+	*conflicts = append(*conflicts, MergeConflict{Path: path, Base: conflictValue(base), Ours: conflictValue(ours), Theirs: conflictValue(theirs)})
+	return ours
+}
+
+// mergeObject 按字段合并对象
+func mergeObject(path string, base map[string]interface{}, ours map[string]interface{}, theirs map[string]interface{}, conflicts *[]MergeConflict) interface{} {
+	merged := map[string]interface{}{}
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]interface{}{base, ours, theirs} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	// 排序使冲突列表的顺序稳定
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := mergeTree(joinTreePath(path, k), k, lookup(base, k), lookup(ours, k), lookup(theirs, k), conflicts)
+		if value != absent {
+			merged[k] = value
+		}
+	}
+	return merged
+}
+
+// mergeArray 按元素的键合并数组
+// 顺序以 ours 为准，只有 theirs 调整了顺序而 ours 没有时使用 theirs 的顺序；theirs 新增的元素插入到它在 theirs 中的前一个元素之后
+func mergeArray(path string, keyFunc elementKeyFunc, base []interface{}, ours []interface{}, theirs []interface{}, conflicts *[]MergeConflict) interface{} {
+	baseElements := keyElements(base, keyFunc)
+	oursElements := keyElements(ours, keyFunc)
+	theirsElements := keyElements(theirs, keyFunc)
+	baseByKey := elementsByKey(baseElements)
+	oursByKey := elementsByKey(oursElements)
+	theirsByKey := elementsByKey(theirsElements)
+
+	// 决定基础顺序
+	baseOrder := commonOrder(baseElements, oursByKey, theirsByKey)
+	oursOrder := commonOrder(oursElements, baseByKey, theirsByKey)
+	theirsOrder := commonOrder(theirsElements, baseByKey, oursByKey)
+	primary, secondary := oursElements, theirsElements
+	switch {
+	case baseOrder == oursOrder && baseOrder != theirsOrder:
+		primary, secondary = theirsElements, oursElements
+	case baseOrder != oursOrder && baseOrder != theirsOrder && oursOrder != theirsOrder:
+		*conflicts = append(*conflicts, MergeConflict{Path: path, Base: conflictValue(base), Ours: conflictValue(ours), Theirs: conflictValue(theirs)})
+	}
+
+	// 按基础顺序排列，另一方独有的新元素插入到它的前一个元素之后
+	order := make([]string, 0, len(primary)+len(secondary))
+	for _, e := range primary {
+		order = append(order, e.key)
+	}
+	inOrder := map[string]bool{}
+	for _, key := range order {
+		inOrder[key] = true
+	}
+	previous := ""
+	for _, e := range secondary {
+		if !inOrder[e.key] {
+			position := 0
+			if previous != "" {
+				for i, key := range order {
+					if key == previous {
+						position = i + 1
+						break
+					}
+				}
+			}
+			order = append(order[:position], append([]string{e.key}, order[position:]...)...)
+			inOrder[e.key] = true
+		}
+		previous = e.key
+	}
+
+	merged := make([]interface{}, 0, len(order))
+	for _, key := range order {
+		elementPath := fmt.Sprintf("%s[%s]", path, key)
+		value := mergeTree(elementPath, "", lookupElement(baseByKey, key), lookupElement(oursByKey, key), lookupElement(theirsByKey, key), conflicts)
+		if value != absent {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
+
+// commandNameAndFirstArgKey .menu 命令按 命令名称 + 第一个参数 匹配
+func commandNameAndFirstArgKey(element interface{}) (string, bool) {
+	args := commandArgs(element)
+	switch len(args) {
+	case 0:
+		return "", false
+	case 1:
+		return args[0], true
+	default:
+		return args[0] + " " + args[1], true
+	}
+}
+
+// elementsByKey 按键索引数组元素
+func elementsByKey(elements []keyedElement) map[string]interface{} {
+	byKey := make(map[string]interface{}, len(elements))
+	for _, e := range elements {
+		byKey[e.key] = e.value
+	}
+	return byKey
+}
+
+// commonOrder 返回三方都存在的元素在 elements 中的顺序，用于判断某一方是否调整了顺序
+func commonOrder(elements []keyedElement, a map[string]interface{}, b map[string]interface{}) string {
+	var keys []string
+	for _, e := range elements {
+		_, inA := a[e.key]
+		_, inB := b[e.key]
+		if inA && inB {
+			keys = append(keys, e.key)
+		}
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// lookup 取对象字段，不存在时返回 absent
+func lookup(m map[string]interface{}, key string) interface{} {
+	if value, ok := m[key]; ok {
+		return value
+	}
+	return absent
+}
+
+// lookupElement 取数组元素，不存在时返回 absent
+func lookupElement(byKey map[string]interface{}, key string) interface{} {
+	if value, ok := byKey[key]; ok {
+		return value
+	}
+	return absent
+}
+
+// sameValue 比较两个值，absent 只与 absent 相同
+func sameValue(a interface{}, b interface{}) bool {
+	if a == absent || b == absent {
+		return a == b
+	}
+	return treeEqual(a, b)
+}
+
+// conflictValue 冲突中不存在的值显示为 null
+func conflictValue(value interface{}) interface{} {
+	if value == absent {
+		return nil
+	}
+	return value
+}
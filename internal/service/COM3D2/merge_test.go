@@ -0,0 +1,157 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeTree(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string   // 合并结果，冲突时为保留 ours 的结果
+		conflicts []string // 冲突的路径
+	}{
+		{
+			name: "only ours changed",
+			base: `{"A": 1, "B": 2}`, ours: `{"A": 10, "B": 2}`, theirs: `{"A": 1, "B": 2}`,
+			want: `{"A": 10, "B": 2}`,
+		},
+		{
+			name: "only theirs changed",
+			base: `{"A": 1, "B": 2}`, ours: `{"A": 1, "B": 2}`, theirs: `{"A": 1, "B": 20}`,
+			want: `{"A": 1, "B": 20}`,
+		},
+		{
+			name: "different fields changed",
+			base: `{"A": 1, "B": 2}`, ours: `{"A": 10, "B": 2}`, theirs: `{"A": 1, "B": 20}`,
+			want: `{"A": 10, "B": 20}`,
+		},
+		{
+			name: "same change on both sides",
+			base: `{"A": 1}`, ours: `{"A": 5}`, theirs: `{"A": 5}`,
+			want: `{"A": 5}`,
+		},
+		{
+			name: "same field changed differently",
+			base: `{"A": 1, "B": {"C": "x"}}`, ours: `{"A": 1, "B": {"C": "ours"}}`, theirs: `{"A": 2, "B": {"C": "theirs"}}`,
+			want:      `{"A": 2, "B": {"C": "ours"}}`,
+			conflicts: []string{"B.C"},
+		},
+		{
+			name: "field removed by one side",
+			base: `{"A": 1, "B": 2}`, ours: `{"A": 1}`, theirs: `{"A": 3, "B": 2}`,
+			want: `{"A": 3}`,
+		},
+		{
+			name: "removed by ours and changed by theirs",
+			base: `{"A": 1, "B": 2}`, ours: `{"A": 1}`, theirs: `{"A": 1, "B": 3}`,
+			want:      `{"A": 1}`,
+			conflicts: []string{"B"},
+		},
+		{
+			name: "field added by both sides",
+			base: `{}`, ours: `{"A": 1}`, theirs: `{"B": 2}`,
+			want: `{"A": 1, "B": 2}`,
+		},
+		{
+			name: "unkeyed arrays changed differently",
+			base: `{"Values": [1, 2, 3]}`, ours: `{"Values": [1, 2, 4]}`, theirs: `{"Values": [0, 2, 3]}`,
+			want:      `{"Values": [1, 2, 4]}`,
+			conflicts: []string{"Values"},
+		},
+		{
+			name:   "properties changed by name",
+			base:   `{"Properties": [{"PropName": "_Color", "Value": 1}, {"PropName": "_Shininess", "Value": 0}]}`,
+			ours:   `{"Properties": [{"PropName": "_Color", "Value": 2}, {"PropName": "_Shininess", "Value": 0}]}`,
+			theirs: `{"Properties": [{"PropName": "_Color", "Value": 1}, {"PropName": "_Shininess", "Value": 5}]}`,
+			want:   `{"Properties": [{"PropName": "_Color", "Value": 2}, {"PropName": "_Shininess", "Value": 5}]}`,
+		},
+		{
+			name:   "elements added by both sides",
+			base:   `{"Properties": [{"PropName": "a"}, {"PropName": "b"}]}`,
+			ours:   `{"Properties": [{"PropName": "a"}, {"PropName": "x"}, {"PropName": "b"}]}`,
+			theirs: `{"Properties": [{"PropName": "a"}, {"PropName": "b"}, {"PropName": "y"}]}`,
+			want:   `{"Properties": [{"PropName": "a"}, {"PropName": "x"}, {"PropName": "b"}, {"PropName": "y"}]}`,
+		},
+		{
+			name:   "element removed by one side",
+			base:   `{"Properties": [{"PropName": "a"}, {"PropName": "b", "Value": 1}]}`,
+			ours:   `{"Properties": [{"PropName": "a"}]}`,
+			theirs: `{"Properties": [{"PropName": "a", "Value": 2}, {"PropName": "b", "Value": 1}]}`,
+			want:   `{"Properties": [{"PropName": "a", "Value": 2}]}`,
+		},
+		{
+			name:   "order changed by theirs only",
+			base:   `{"Properties": [{"PropName": "a"}, {"PropName": "b"}, {"PropName": "c"}]}`,
+			ours:   `{"Properties": [{"PropName": "a", "Value": 1}, {"PropName": "b"}, {"PropName": "c"}]}`,
+			theirs: `{"Properties": [{"PropName": "c"}, {"PropName": "a"}, {"PropName": "b"}]}`,
+			want:   `{"Properties": [{"PropName": "c"}, {"PropName": "a", "Value": 1}, {"PropName": "b"}]}`,
+		},
+		{
+			name:      "order changed differently",
+			base:      `{"Properties": [{"PropName": "a"}, {"PropName": "b"}, {"PropName": "c"}]}`,
+			ours:      `{"Properties": [{"PropName": "b"}, {"PropName": "a"}, {"PropName": "c"}]}`,
+			theirs:    `{"Properties": [{"PropName": "c"}, {"PropName": "b"}, {"PropName": "a"}]}`,
+			want:      `{"Properties": [{"PropName": "b"}, {"PropName": "a"}, {"PropName": "c"}]}`,
+			conflicts: []string{"Properties"},
+		},
+		{
+			name:      "same element changed differently",
+			base:      `{"Properties": [{"PropName": "a", "Value": 0}]}`,
+			ours:      `{"Properties": [{"PropName": "a", "Value": 1}]}`,
+			theirs:    `{"Properties": [{"PropName": "a", "Value": 2}]}`,
+			want:      `{"Properties": [{"PropName": "a", "Value": 1}]}`,
+			conflicts: []string{"Properties[a].Value"},
+		},
+		{
+			name:   "menu commands matched by name and first argument",
+			base:   `{"Commands": [{"Args": ["additem", "body.model", "body"]}, {"Args": ["additem", "hair.model", "hair"]}]}`,
+			ours:   `{"Commands": [{"Args": ["additem", "body.model", "skin"]}, {"Args": ["additem", "hair.model", "hair"]}]}`,
+			theirs: `{"Commands": [{"Args": ["additem", "body.model", "body"]}, {"Args": ["additem", "hair.model", "wear"]}]}`,
+			want:   `{"Commands": [{"Args": ["additem", "body.model", "skin"]}, {"Args": ["additem", "hair.model", "wear"]}]}`,
+		},
+		{
+			name:      "same menu command changed differently",
+			base:      `{"Commands": [{"Args": ["additem", "body.model", "body"]}]}`,
+			ours:      `{"Commands": [{"Args": ["additem", "body.model", "skin"]}]}`,
+			theirs:    `{"Commands": [{"Args": ["additem", "body.model", "wear"]}]}`,
+			want:      `{"Commands": [{"Args": ["additem", "body.model", "skin"]}]}`,
+			conflicts: []string{"Commands[additem body.model].Args"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conflicts []MergeConflict
+			merged := mergeTree("", "", parseTree(t, tt.base), parseTree(t, tt.ours), parseTree(t, tt.theirs), &conflicts)
+
+			var paths []string
+			for _, conflict := range conflicts {
+				paths = append(paths, conflict.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.conflicts) {
+				t.Errorf("conflicts = %q, want %q", paths, tt.conflicts)
+			}
+			if want := parseTree(t, tt.want); !treeEqual(merged, want) {
+				got, _ := json.Marshal(merged)
+				t.Errorf("merged = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// 冲突中不存在的值显示为 null
+func TestMergeConflictAbsentValue(t *testing.T) {
+	var conflicts []MergeConflict
+	mergeTree("", "", parseTree(t, `{"A": 1}`), parseTree(t, `{}`), parseTree(t, `{"A": 2}`), &conflicts)
+	if len(conflicts) != 1 {
+		t.Fatalf("got %d conflicts, want 1", len(conflicts))
+	}
+	conflict := conflicts[0]
+	if conflict.Ours != nil || conflict.Base == nil || conflict.Theirs == nil {
+		t.Errorf("conflict = %+v, want Ours null", conflict)
+	}
+}
@@ -1,96 +1,90 @@
 package COM3D2
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
-	"io"
-	"os"
-	"strings"
 )
 
+// modelFormat .model 文件格式
+// 注意，读取 Material 时需要进行 Seek，因此这里不能使用 bufio.NewReader，读 .mate 能用是因为后续没有其他数据可以直接全部读取到内存
+var modelFormat = registerFormat(&Format[COM3D2.Model]{
+	fileType:  "model",
+	signature: COM3D2.ModelSignature,
+	json:      true,
+	read:      COM3D2.ReadModel,
+	dump:      (*COM3D2.Model).Dump,
+	locate:    scanModel,
+})
+
 // ModelService 专门处理 .model 文件的读写
 type ModelService struct{}
 
-// ReadModelFile 读取 .model 或 .model.json 文件并返回对应结构体
-func (m *ModelService) ReadModelFile(path string) (*COM3D2.Model, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open .model file: %w", err)
-	}
-	defer f.Close()
-
-	if strings.HasSuffix(path, ".json") {
-		decoder := json.NewDecoder(f)
-		modelData := &COM3D2.Model{}
-		if err := decoder.Decode(modelData); err != nil {
-			return nil, fmt.Errorf("failed to read .model.json file: %w", err)
-		}
-		return modelData, nil
-	}
-
-	var rs io.ReadSeeker = f // 注意，读取 Material 时需要进行 Seek，因此这里不能使用 bufio.NewReader，读 .mate 能用是因为后续没有其他数据可以直接全部读取到内存
-	modelData, err := COM3D2.ReadModel(rs)
-	if err != nil {
-		return nil, fmt.Errorf("parsing the .model file failed: %w", err)
-	}
+// ModelHeader .model 中除网格数据以外的内容，由 ReadModelHeader 读取
+type ModelHeader struct {
+	Signature         string             `json:"Signature"`
+	Version           int32              `json:"Version"`
+	Name              string             `json:"Name"`
+	RootBoneName      string             `json:"RootBoneName"`
+	ShadowCastingMode *string            `json:"ShadowCastingMode"` // 2104 之前的版本没有
+	BoneNames         []string           `json:"BoneNames"`
+	VertexCount       int32              `json:"VertexCount"`
+	SubMeshCount      int32              `json:"SubMeshCount"`
+	Materials         []*COM3D2.Material `json:"Materials"`
+}
 
-	return modelData, nil
+// ReadModelFile 读取 .model、.model.json 或 .model.yaml 文件并返回对应结构体
+func (m *ModelService) ReadModelFile(path string) (*COM3D2.Model, error) {
+	return modelFormat.ReadFile(path)
 }
 
-// WriteModelFile 接收 Model 数据并写入 .model 文件或 .model.json 文件
+// WriteModelFile 接收 Model 数据并写入 .model、.model.json 或 .model.yaml 文件
 func (m *ModelService) WriteModelFile(outputPath string, modelData *COM3D2.Model) error {
-	f, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("unable to create .model file: %w", err)
-	}
-	defer f.Close()
-
-	if strings.HasSuffix(outputPath, ".json") {
-		marshal, err := json.Marshal(modelData)
-		if err != nil {
-			return err
-		}
-		_, err = f.Write(marshal)
-		if err != nil {
-			return fmt.Errorf("failed to write to .model.json file: %w", err)
-		}
-		return nil
-	}
+	return modelFormat.WriteFile(outputPath, modelData)
+}
 
-	bw := bufio.NewWriter(f)
-	if err := modelData.Dump(bw); err != nil {
-		return fmt.Errorf("failed to write to .model file: %w", err)
-	}
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("an error occurred while flush bufio: %w", err)
+// ReadModelHeader 只读取 .model 二进制文件的文件头、骨骼名称和材质，跳过顶点、索引和形态键，适合大模型和扫描目录
+func (m *ModelService) ReadModelHeader(path string) (*ModelHeader, error) {
+	layout, err := readModelLayout(path)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	materials, err := layout.decodeMaterials()
+	if err != nil {
+		return nil, fmt.Errorf("parsing the .model materials failed: %w", err)
+	}
+	return &ModelHeader{
+		Signature:         layout.signature,
+		Version:           layout.version,
+		Name:              layout.name,
+		RootBoneName:      layout.rootBoneName,
+		ShadowCastingMode: layout.shadowCastingMode,
+		BoneNames:         layout.boneNames,
+		VertexCount:       int32(layout.vertexCount),
+		SubMeshCount:      int32(layout.subMeshCount),
+		Materials:         materials,
+	}, nil
 }
 
 // ReadModelMetadata 读取.model 文件，但只返回其中的元数据
+// 二进制文件只读取文件头和材质，见 ReadModelHeader，扫描失败时仍然读取整个模型
 func (m *ModelService) ReadModelMetadata(path string) (*COM3D2.ModelMetadata, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open .model file: %w", err)
+	if textSuffix(path) == "" {
+		if header, err := m.ReadModelHeader(path); err == nil {
+			return &COM3D2.ModelMetadata{
+				Signature:         header.Signature,
+				Version:           header.Version,
+				Name:              header.Name,
+				RootBoneName:      header.RootBoneName,
+				ShadowCastingMode: header.ShadowCastingMode,
+				Materials:         header.Materials,
+			}, nil
+		}
 	}
-	defer f.Close()
-
-	var modelData *COM3D2.Model
 
-	if strings.HasSuffix(path, ".json") {
-		decoder := json.NewDecoder(f)
-		modelData = &COM3D2.Model{}
-		if err = decoder.Decode(modelData); err != nil {
-			return nil, fmt.Errorf("failed to read .model.json file: %w", err)
-		}
-	} else {
-		var rs io.ReadSeeker = f
-		modelData, err = COM3D2.ReadModel(rs)
-		if err != nil {
-			return nil, fmt.Errorf("parsing the .model file failed: %w", err)
-		}
+	modelData, err := m.ReadModelFile(path)
+	if err != nil {
+		return nil, err
 	}
 
 	return &COM3D2.ModelMetadata{
@@ -104,27 +98,19 @@ func (m *ModelService) ReadModelMetadata(path string) (*COM3D2.ModelMetadata, er
 }
 
 // WriteModelMetadata 将元数据写入现有的 .model 文件
+// 输入输出都是二进制文件时只重写文件头和材质，其余部分从原文件原样复制，见 modelpatch.go
+// 修改后的版本改变了骨骼或皮肤厚度的结构，或者扫描不了原文件的结构时，仍然重新编码整个模型
 func (m *ModelService) WriteModelMetadata(inputPath string, outputPath string, metadata *COM3D2.ModelMetadata) error {
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("cannot open .model file: %w", err)
+	if textSuffix(inputPath) == "" && textSuffix(outputPath) == "" {
+		layout, err := readModelLayout(inputPath)
+		if err == nil && canPatchModel(layout.version, metadata.Version) {
+			return patchModel(inputPath, outputPath, layout, metadata)
+		}
 	}
-	defer f.Close()
-
-	var modelData *COM3D2.Model
 
-	if strings.HasSuffix(inputPath, ".json") {
-		decoder := json.NewDecoder(f)
-		modelData = &COM3D2.Model{}
-		if err = decoder.Decode(modelData); err != nil {
-			return fmt.Errorf("failed to read .model.json file: %w", err)
-		}
-	} else {
-		var rs io.ReadSeeker = f
-		modelData, err = COM3D2.ReadModel(rs)
-		if err != nil {
-			return fmt.Errorf("parsing the .model file failed: %w", err)
-		}
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return err
 	}
 
 	modelData.Signature = metadata.Signature
@@ -138,113 +124,101 @@ func (m *ModelService) WriteModelMetadata(inputPath string, outputPath string, m
 }
 
 // ReadModelMaterial 读取 .model 文件，但只返回其中的材质数据
+// 文件已经通过 DocumentService 打开时返回文档中的材质（包括未保存的修改），不重新读取
+// 二进制文件只读取文件头和材质，见 ReadModelHeader，扫描失败时仍然读取整个模型
 func (m *ModelService) ReadModelMaterial(path string) ([]*COM3D2.Material, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, fmt.Errorf("cannot open .model file: %w", err)
+	if doc := openDocuments.lookup(path); doc != nil {
+		defer doc.mu.Unlock()
+		if doc.format == modelFormat {
+			return documentMaterials(doc)
+		}
 	}
-	defer f.Close()
 
-	if strings.HasSuffix(path, ".json") {
-		decoder := json.NewDecoder(f)
-		modelData := &COM3D2.Model{}
-		if err := decoder.Decode(modelData); err != nil {
-			return nil, fmt.Errorf("failed to read.model.json file: %w", err)
+	if textSuffix(path) == "" {
+		if header, err := m.ReadModelHeader(path); err == nil {
+			return header.Materials, nil
 		}
-		return modelData.Materials, nil
 	}
 
-	var rs io.ReadSeeker = f
-	modelData, err := COM3D2.ReadModel(rs)
+	modelData, err := m.ReadModelFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("parsing the .model file failed: %w", err)
+		return nil, err
 	}
 
 	return modelData.Materials, nil
 }
 
 // WriteModelMaterial 接收 Material 数据并写入.model 文件
-// 因为 Material 数据是在 Model 结构体中，所以需要先读取整个 Model 结构体，然后修改其中的 Material 数据，最后再写入文件
+// 因为 Material 数据是在 Model 结构体中，文本文件需要先读取整个 Model 结构体，然后修改其中的 Material 数据，最后再写入文件
 // 因此这里需要传入输入文件路径和输出文件路径，分别用于读取和写入.model 文件，可以为相同路径
+// 输入文件已经通过 DocumentService 打开时，替换材质作为文档的一次编辑（可以撤销）并保存文档，outputPath 不同时相当于 SaveAs
+// 输入输出都是二进制文件时只重写材质，不需要读取整个 Model，扫描不了原文件的结构时仍然读取整个 Model，见 WriteModelMetadata
 func (m *ModelService) WriteModelMaterial(inputPath string, outputPath string, materials []*COM3D2.Material) error {
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("cannot open .model file: %w", err)
+	if doc := openDocuments.lookup(inputPath); doc != nil {
+		defer doc.mu.Unlock()
+		if doc.format == modelFormat {
+			return writeDocumentMaterials(doc, outputPath, materials)
+		}
 	}
-	defer f.Close()
-
-	var modelData *COM3D2.Model
 
-	if strings.HasSuffix(inputPath, ".json") {
-		decoder := json.NewDecoder(f)
-		modelData = &COM3D2.Model{}
-		if err = decoder.Decode(modelData); err != nil {
-			return fmt.Errorf("failed to read.model.json file: %w", err)
-		}
-	} else {
-		var rs io.ReadSeeker = f
-		modelData, err = COM3D2.ReadModel(rs)
-		if err != nil {
-			return fmt.Errorf("parsing the .model file failed: %w", err)
+	if textSuffix(inputPath) == "" && textSuffix(outputPath) == "" {
+		if layout, err := readModelLayout(inputPath); err == nil {
+			return patchModel(inputPath, outputPath, layout, &COM3D2.ModelMetadata{
+				Signature:         layout.signature,
+				Version:           layout.version,
+				Name:              layout.name,
+				RootBoneName:      layout.rootBoneName,
+				ShadowCastingMode: layout.shadowCastingMode,
+				Materials:         materials,
+			})
 		}
 	}
 
+	modelData, err := m.ReadModelFile(inputPath)
+	if err != nil {
+		return err
+	}
+
 	modelData.Materials = materials
 
 	return m.WriteModelFile(outputPath, modelData)
 }
 
-// ConvertModelToJson 接收输入文件路径和输出文件路径，将输入文件转换为 .json 文件
+// ConvertModelToJson 接收输入文件路径和输出文件路径，将输入文件转换为 .json 或 .yaml 文件
 func (m *ModelService) ConvertModelToJson(inputPath string, outputPath string) error {
-	if strings.HasSuffix(outputPath, ".model") {
-		outputPath = strings.TrimSuffix(outputPath, ".model") + ".model.json"
-	}
-
-	modelData, err := m.ReadModelFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read model file: %w", err)
-	}
+	return modelFormat.ConvertToJson(inputPath, outputPath)
+}
 
-	jsonData, err := json.Marshal(modelData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal model data: %w", err)
-	}
+// ConvertJsonToModel 接收输入文件路径和输出文件路径，将输入文件转换为 .model 文件
+func (m *ModelService) ConvertJsonToModel(inputPath string, outputPath string) error {
+	return modelFormat.ConvertFromJson(inputPath, outputPath)
+}
 
-	f, err := os.Create(outputPath)
+// documentMaterials 返回文档中材质的副本，之后的编辑不会影响它，调用时需要持有 doc.mu
+func documentMaterials(doc *document) ([]*COM3D2.Material, error) {
+	raw, err := json.Marshal(doc.data.(*COM3D2.Model).Materials)
 	if err != nil {
-		return fmt.Errorf("unable to create model.json file: %w", err)
-	}
-	defer func() {
-		if closeErr := f.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("error closing output file: %w", closeErr)
-		}
-	}()
-
-	bw := bufio.NewWriter(f)
-	if _, err := bw.Write(jsonData); err != nil {
-		return fmt.Errorf("failed to write to model.json file: %w", err)
+		return nil, err
 	}
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("an error occurred while flush bufio: %w", err)
+	var materials []*COM3D2.Material
+	if err := json.Unmarshal(raw, &materials); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return materials, nil
 }
 
-// ConvertJsonToModel 接收输入文件路径和输出文件路径，将输入文件转换为 .model 文件
-func (m *ModelService) ConvertJsonToModel(inputPath string, outputPath string) error {
-	if strings.HasSuffix(outputPath, ".json") {
-		outputPath = strings.TrimSuffix(outputPath, ".json") + ".model"
-	}
-
-	f, err := os.Open(inputPath)
+// writeDocumentMaterials 将文档的材质替换为 materials 并保存到 outputPath，调用时需要持有 doc.mu
+func writeDocumentMaterials(doc *document, outputPath string, materials []*COM3D2.Material) error {
+	raw, err := json.Marshal(materials)
 	if err != nil {
-		return fmt.Errorf("cannot open model.json file: %w", err)
+		return err
 	}
-	defer f.Close()
-	var modelData *COM3D2.Model
-	if err := json.NewDecoder(f).Decode(&modelData); err != nil {
-		return fmt.Errorf("parsing the model.json file failed: %w", err)
+	ops := []pathOp{{Op: pathOpReplace, Path: "/Materials", Value: raw}}
+	inverse, err := applyPathOps(doc.data, ops)
+	if err != nil {
+		return err
 	}
-	return m.WriteModelFile(outputPath, modelData)
+	doc.record(ops, inverse)
+	_, err = doc.save(outputPath)
+	return err
 }
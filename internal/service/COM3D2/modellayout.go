@@ -0,0 +1,249 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+)
+
+// .model 的结构，依次为：
+// 文件头（签名、版本、名称、根骨骼名称、阴影投射模式）
+// 骨骼（名称、父骨骼、变换）
+// 网格数据块（绑定姿势、顶点、切线、权重、子网格索引）
+// 材质
+// 形态键和皮肤厚度
+// 只需要文件头、骨骼或材质时，网格数据块只读取数量并直接跳过，形态键之后的部分完全不读取
+
+// 与 .model 版本相关的结构变化
+const (
+	modelBoneScaleVersion     = 2001 // 骨骼变换后带有是否缩放的标记和缩放值
+	modelSkinThicknessVersion = 2100 // 形态键之后带有皮肤厚度数据
+	modelShadowCastingVersion = 2104 // 根骨骼名称之后带有阴影投射模式
+)
+
+// 各数据块中每个元素的字节数
+const (
+	bindPoseSize     = 16 * 4          // 4x4 矩阵
+	vertexSize       = (3 + 3 + 2) * 4 // 位置、法线、UV
+	tangentSize      = 4 * 4           // 四元数
+	boneWeightSize   = 4*2 + 4*4       // 4 个 uint16 骨骼索引和 4 个 float32 权重
+	morphVertexSize  = 2 + 3*4 + 3*4   // uint16 顶点索引、位置偏移、法线偏移
+	boneTransformLen = 3*4 + 4*4       // 位置和旋转
+	boneScaleLen     = 3 * 4           // 缩放
+	indexSize        = 2               // 子网格的 uint16 顶点索引
+)
+
+// modelLayout .model 中网格数据以外的内容，以及各部分在文件中的位置
+type modelLayout struct {
+	signature         string
+	version           int32
+	name              string
+	rootBoneName      string
+	shadowCastingMode *string
+	boneNames         []string
+	vertexCount       int
+	subMeshCount      int
+
+	bonesOffset     int64    // 骨骼数量的位置，之前为文件头
+	materialsOffset int64    // 材质数量的位置，之前为骨骼和网格数据块
+	materialsEnd    int64    // 材质结束的位置，之后为形态键
+	materials       [][]byte // 每个材质的原始数据
+}
+
+// scanModelLayout 读取文件头、骨骼和材质，跳过网格数据块，停在形态键之前
+func scanModelLayout(s *binaryScanner) (*modelLayout, error) {
+	layout := &modelLayout{}
+	var err error
+	if layout.signature, layout.version, err = scanHeader(s); err != nil {
+		return layout, err
+	}
+	if layout.name, err = s.string("name"); err != nil {
+		return layout, err
+	}
+	if layout.rootBoneName, err = s.string("root bone name"); err != nil {
+		return layout, err
+	}
+	if layout.version >= modelShadowCastingVersion {
+		mode, err := s.string("shadow casting mode")
+		if err != nil {
+			return layout, err
+		}
+		layout.shadowCastingMode = &mode
+	}
+
+	layout.bonesOffset = s.pos
+	boneCount, err := s.count("bone count")
+	if err != nil {
+		return layout, err
+	}
+	layout.boneNames = make([]string, boneCount)
+	for i := 0; i < boneCount; i++ {
+		if layout.boneNames[i], err = s.string(fmt.Sprintf("bone %d name", i)); err != nil {
+			return layout, err
+		}
+		if err := s.skip(fmt.Sprintf("bone %d scale flag", i), 1); err != nil {
+			return layout, err
+		}
+	}
+	if err := s.skip("bone parent indices", int64(boneCount)*4); err != nil {
+		return layout, err
+	}
+	for i := 0; i < boneCount; i++ {
+		if err := s.skip(fmt.Sprintf("bone %d transform", i), boneTransformLen); err != nil {
+			return layout, err
+		}
+		if layout.version < modelBoneScaleVersion {
+			continue
+		}
+		hasScale, err := s.byte(fmt.Sprintf("bone %d has scale", i))
+		if err != nil {
+			return layout, err
+		}
+		if hasScale != 0 {
+			if err := s.skip(fmt.Sprintf("bone %d scale", i), boneScaleLen); err != nil {
+				return layout, err
+			}
+		}
+	}
+
+	if layout.vertexCount, err = s.count("vertex count"); err != nil {
+		return layout, err
+	}
+	if layout.subMeshCount, err = s.count("submesh count"); err != nil {
+		return layout, err
+	}
+	localBoneCount, err := s.count("local bone count")
+	if err != nil {
+		return layout, err
+	}
+	for i := 0; i < localBoneCount; i++ {
+		if _, err := s.string(fmt.Sprintf("local bone %d name", i)); err != nil {
+			return layout, err
+		}
+	}
+	if err := s.skip("bind poses", int64(localBoneCount)*bindPoseSize); err != nil {
+		return layout, err
+	}
+	if err := s.skip("vertices", int64(layout.vertexCount)*vertexSize); err != nil {
+		return layout, err
+	}
+	tangentCount, err := s.count("tangent count")
+	if err != nil {
+		return layout, err
+	}
+	if err := s.skip("tangents", int64(tangentCount)*tangentSize); err != nil {
+		return layout, err
+	}
+	if err := s.skip("bone weights", int64(layout.vertexCount)*boneWeightSize); err != nil {
+		return layout, err
+	}
+	for i := 0; i < layout.subMeshCount; i++ {
+		indexCount, err := s.count(fmt.Sprintf("submesh %d index count", i))
+		if err != nil {
+			return layout, err
+		}
+		if err := s.skip(fmt.Sprintf("submesh %d indices", i), int64(indexCount)*indexSize); err != nil {
+			return layout, err
+		}
+	}
+
+	layout.materialsOffset = s.pos
+	materialCount, err := s.count("material count")
+	if err != nil {
+		return layout, err
+	}
+	for i := 0; i < materialCount; i++ {
+		s.record = &bytes.Buffer{}
+		err := scanMaterial(s, fmt.Sprintf("material %d", i))
+		layout.materials = append(layout.materials, s.record.Bytes())
+		s.record = nil
+		if err != nil {
+			return layout, err
+		}
+	}
+	layout.materialsEnd = s.pos
+	return layout, nil
+}
+
+// scanModel 扫描整个 .model，用于定位解析错误
+func scanModel(s *binaryScanner) error {
+	layout, err := scanModelLayout(s)
+	if err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		tag, err := s.string(fmt.Sprintf("morph %d tag", i))
+		if err != nil {
+			return err
+		}
+		if tag == "end" {
+			break
+		}
+		if tag != "morph" {
+			return s.errorf("unknown tag %q", tag)
+		}
+		if _, err := s.string(fmt.Sprintf("morph %d name", i)); err != nil {
+			return err
+		}
+		n, err := s.count(fmt.Sprintf("morph %d vertex count", i))
+		if err != nil {
+			return err
+		}
+		if err := s.skip(fmt.Sprintf("morph %d vertices", i), int64(n)*morphVertexSize); err != nil {
+			return err
+		}
+	}
+	if layout.version >= modelSkinThicknessVersion {
+		return s.enter("skin thickness")
+	}
+	return nil
+}
+
+// readModelLayout 打开文件并读取 .model 的布局，失败时返回 *ParseError
+func readModelLayout(path string) (*modelLayout, error) {
+	file, err := openFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open .model file: %w", err)
+	}
+	defer file.Close()
+
+	s := newBinaryScanner(file, -1)
+	layout, err := scanModelLayout(s)
+	if err == nil && layout.signature != COM3D2.ModelSignature {
+		err = fmt.Errorf("invalid .model signature %q", layout.signature)
+	}
+	if err != nil {
+		return nil, s.parseError("model", path, err)
+	}
+	return layout, nil
+}
+
+// decodeMaterials 解码布局中记录的材质
+func (l *modelLayout) decodeMaterials() ([]*COM3D2.Material, error) {
+	materials := make([]*COM3D2.Material, len(l.materials))
+	for i, raw := range l.materials {
+		material, err := decodeModelMaterial(raw, l.version)
+		if err != nil {
+			return nil, fmt.Errorf("material %d: %w", i, err)
+		}
+		materials[i] = material
+	}
+	return materials, nil
+}
+
+// decodeModelMaterial 材质在 .model 中的格式与 .mate 中名称之后的部分相同，补上 .mate 的文件头后交给 ReadMate 解码
+// .mate 和 .model 的版本号都与游戏版本对应，这里使用 .model 的版本
+func decodeModelMaterial(raw []byte, version int32) (*COM3D2.Material, error) {
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.MateSignature)
+	binary.Write(&buf, binary.LittleEndian, version)
+	appendString(&buf, "")
+	buf.Write(raw)
+
+	mate, err := COM3D2.ReadMate(&buf)
+	if err != nil {
+		return nil, err
+	}
+	return mate.Material, nil
+}
@@ -0,0 +1,201 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fixtureShadowCastingMode 2104 及之后的版本文件头中的阴影投射模式
+const fixtureShadowCastingMode = "On"
+
+// fixtureModelVersions 结构各不相同的版本：2000 为基础结构，2001 起骨骼带有缩放，2100 起带有皮肤厚度，2104 起文件头带有阴影投射模式
+var fixtureModelVersions = []int32{2000, modelBoneScaleVersion, modelSkinThicknessVersion, modelShadowCastingVersion}
+
+// fixtureModel 构造版本为 2000 的最小 .model，不带骨骼缩放、皮肤厚度和阴影投射模式
+func fixtureModel(materials ...[]byte) []byte {
+	return fixtureModelVersion(2000, materials...)
+}
+
+// fixtureModelVersion 构造一个最小的 .model：两根骨骼、三个顶点、一个子网格
+// 2001 起第二根骨骼带有缩放；2100 之前带有一个形态键，之后只有结束标记和表示没有皮肤厚度数据的 0
+func fixtureModelVersion(version int32, materials ...[]byte) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.ModelSignature)
+	binary.Write(&buf, binary.LittleEndian, version)
+	appendString(&buf, "test")
+	appendString(&buf, "root")
+	if version >= modelShadowCastingVersion {
+		appendString(&buf, fixtureShadowCastingMode)
+	}
+
+	// 骨骼
+	binary.Write(&buf, binary.LittleEndian, int32(2))
+	for _, name := range []string{"root", "child"} {
+		appendString(&buf, name)
+		buf.WriteByte(0)
+	}
+	binary.Write(&buf, binary.LittleEndian, []int32{-1, 0})
+	for i := 0; i < 2; i++ {
+		fixtureFloats(&buf, float32(i), 0, 0, 0, 0, 0, 1)
+		if version < modelBoneScaleVersion {
+			continue
+		}
+		if i == 0 {
+			buf.WriteByte(0)
+		} else {
+			buf.WriteByte(1)
+			fixtureFloats(&buf, 1, 2, 3)
+		}
+	}
+
+	// 网格数据块
+	binary.Write(&buf, binary.LittleEndian, int32(3)) // 顶点数量
+	binary.Write(&buf, binary.LittleEndian, int32(1)) // 子网格数量
+	binary.Write(&buf, binary.LittleEndian, int32(1)) // 局部骨骼数量
+	appendString(&buf, "root")
+	fixtureFloats(&buf, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1)
+	for i := 0; i < 3; i++ {
+		fixtureFloats(&buf, float32(i), 0.1, 0.2, 0, 1, 0, 0.3, 0.4)
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(0)) // 切线数量
+	for i := 0; i < 3; i++ {
+		binary.Write(&buf, binary.LittleEndian, []uint16{0, 0, 0, 0})
+		fixtureFloats(&buf, 1, 0, 0, 0)
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(3))
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 2})
+
+	// 材质
+	binary.Write(&buf, binary.LittleEndian, int32(len(materials)))
+	for _, material := range materials {
+		buf.Write(material)
+	}
+
+	// 形态键
+	if version < modelSkinThicknessVersion {
+		appendString(&buf, "morph")
+		appendString(&buf, "smile")
+		binary.Write(&buf, binary.LittleEndian, int32(1))
+		binary.Write(&buf, binary.LittleEndian, uint16(1))
+		fixtureFloats(&buf, 0.1, 0.2, 0.3, 0, 0, 0)
+	}
+	appendString(&buf, "end")
+	if version >= modelSkinThicknessVersion {
+		binary.Write(&buf, binary.LittleEndian, int32(0))
+	}
+	return buf.Bytes()
+}
+
+// fixtureModelAllProperties 构造包含每种属性类型的 .model，属性按名称排序以保证结果稳定
+func fixtureModelAllProperties(version int32) []byte {
+	all := fixtureAllProperties()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	properties := make([][]byte, 0, len(names))
+	for _, name := range names {
+		properties = append(properties, all[name])
+	}
+	return fixtureModelVersion(version, fixtureMaterial("body", properties...), fixtureMaterial("empty"))
+}
+
+func TestScanModelLayout(t *testing.T) {
+	for _, version := range fixtureModelVersions {
+		t.Run(fmt.Sprint(version), func(t *testing.T) {
+			data := fixtureModelAllProperties(version)
+			layout, err := scanModelLayout(newBinaryScanner(bytes.NewReader(data), -1))
+			if err != nil {
+				t.Fatalf("scanModelLayout: %v", err)
+			}
+			if layout.name != "test" || layout.rootBoneName != "root" || layout.version != version {
+				t.Errorf("header = %q %q %d", layout.name, layout.rootBoneName, layout.version)
+			}
+			if version >= modelShadowCastingVersion {
+				if layout.shadowCastingMode == nil || *layout.shadowCastingMode != fixtureShadowCastingMode {
+					t.Errorf("shadowCastingMode = %v, want %q", layout.shadowCastingMode, fixtureShadowCastingMode)
+				}
+			} else if layout.shadowCastingMode != nil {
+				t.Errorf("shadowCastingMode = %q, want none", *layout.shadowCastingMode)
+			}
+			if !reflect.DeepEqual(layout.boneNames, []string{"root", "child"}) {
+				t.Errorf("boneNames = %v", layout.boneNames)
+			}
+			if layout.vertexCount != 3 || layout.subMeshCount != 1 {
+				t.Errorf("vertexCount = %d, subMeshCount = %d", layout.vertexCount, layout.subMeshCount)
+			}
+			if len(layout.materials) != 2 {
+				t.Fatalf("got %d materials, want 2", len(layout.materials))
+			}
+			if !bytes.Equal(layout.materials[1], fixtureMaterial("empty")) {
+				t.Errorf("material 1 raw bytes differ")
+			}
+
+			// 材质之后只剩形态键和皮肤厚度
+			var tail bytes.Buffer
+			appendString(&tail, "end")
+			if version >= modelSkinThicknessVersion {
+				binary.Write(&tail, binary.LittleEndian, int32(0))
+			}
+			if !bytes.HasSuffix(data[layout.materialsEnd:], tail.Bytes()) {
+				t.Errorf("data after the materials = % x", data[layout.materialsEnd:])
+			}
+
+			if err := scanModel(newBinaryScanner(bytes.NewReader(data), -1)); err != nil {
+				t.Errorf("scanModel: %v", err)
+			}
+		})
+	}
+}
+
+// decodeMaterials 只解码材质部分，结果必须与完整读取模型得到的材质相同
+// 同时确认 fixture 的骨骼缩放、皮肤厚度和阴影投射模式与 ReadModel 的理解一致
+func TestDecodeMaterialsMatchesReadModel(t *testing.T) {
+	for _, version := range fixtureModelVersions {
+		t.Run(fmt.Sprint(version), func(t *testing.T) {
+			data := fixtureModelAllProperties(version)
+			layout, err := scanModelLayout(newBinaryScanner(bytes.NewReader(data), -1))
+			if err != nil {
+				t.Fatalf("scanModelLayout: %v", err)
+			}
+			got, err := layout.decodeMaterials()
+			if err != nil {
+				t.Fatalf("decodeMaterials: %v", err)
+			}
+
+			model, err := COM3D2.ReadModel(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("ReadModel: %v", err)
+			}
+			if model == nil {
+				t.Fatal("ReadModel returned nil")
+			}
+			if !reflect.DeepEqual(got, model.Materials) {
+				t.Errorf("decodeMaterials = %+v, want %+v", got, model.Materials)
+			}
+
+			if len(model.Bones) != 2 {
+				t.Fatalf("ReadModel read %d bones, want 2", len(model.Bones))
+			}
+			hasScale := version >= modelBoneScaleVersion
+			if model.Bones[0].HasScale || model.Bones[1].HasScale != hasScale {
+				t.Errorf("HasScale = %v, %v, want false, %v", model.Bones[0].HasScale, model.Bones[1].HasScale, hasScale)
+			}
+			if hasScale && (model.Bones[1].Scale == nil || *model.Bones[1].Scale != (COM3D2.Vector3{X: 1, Y: 2, Z: 3})) {
+				t.Errorf("Scale = %v", model.Bones[1].Scale)
+			}
+			if model.SkinThickness != nil {
+				t.Errorf("SkinThickness = %+v, want none", model.SkinThickness)
+			}
+			if !reflect.DeepEqual(model.ShadowCastingMode, layout.shadowCastingMode) {
+				t.Errorf("ShadowCastingMode = %v, want %v", model.ShadowCastingMode, layout.shadowCastingMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"io"
+)
+
+// 只修改 .model 的元数据或材质时不重新编码整个模型：
+// 重新写出文件头和材质，骨骼、网格数据块、形态键和皮肤厚度按 modelLayout 记录的位置从原文件原样复制
+// 这样大模型保存更快，未修改的部分与原文件逐字节相同，不会因为浮点数重新编码产生差异
+
+// canPatchModel 新旧版本的骨骼、网格数据块和皮肤厚度结构相同时才能直接复制
+// 阴影投射模式只在文件头中，不影响复制
+func canPatchModel(oldVersion int32, newVersion int32) bool {
+	for _, threshold := range []int32{modelBoneScaleVersion, modelSkinThicknessVersion} {
+		if (oldVersion >= threshold) != (newVersion >= threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// patchModel 用 metadata 中的文件头和材质替换 inputPath 中的对应部分，写入 outputPath，两者可以为相同路径
+func patchModel(inputPath string, outputPath string, layout *modelLayout, metadata *COM3D2.ModelMetadata) error {
+	if metadata.Version >= modelShadowCastingVersion && metadata.ShadowCastingMode == nil {
+		return fmt.Errorf("ShadowCastingMode is required for .model version %d", metadata.Version)
+	}
+
+	var header bytes.Buffer
+	appendString(&header, metadata.Signature)
+	binary.Write(&header, binary.LittleEndian, metadata.Version)
+	appendString(&header, metadata.Name)
+	appendString(&header, metadata.RootBoneName)
+	if metadata.Version >= modelShadowCastingVersion {
+		appendString(&header, *metadata.ShadowCastingMode)
+	}
+
+	var materials bytes.Buffer
+	binary.Write(&materials, binary.LittleEndian, int32(len(metadata.Materials)))
+	for i, material := range metadata.Materials {
+		raw, err := encodeModelMaterial(material, metadata.Version)
+		if err != nil {
+			return fmt.Errorf("failed to encode material %d: %w", i, err)
+		}
+		materials.Write(raw)
+	}
+
+	outputPath, err := resolveWritePath(outputPath)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(outputPath, func(w io.Writer) error {
+		// 在回调中打开并关闭原文件，输入输出为相同路径时替换前原文件已经关闭
+		src, err := openFile(inputPath)
+		if err != nil {
+			return fmt.Errorf("cannot open .model file: %w", err)
+		}
+		defer src.Close()
+
+		if _, err := w.Write(header.Bytes()); err != nil {
+			return fmt.Errorf("failed to write to .model file: %w", err)
+		}
+		if err := copyRange(w, src, layout.bonesOffset, layout.materialsOffset-layout.bonesOffset); err != nil {
+			return err
+		}
+		if _, err := w.Write(materials.Bytes()); err != nil {
+			return fmt.Errorf("failed to write to .model file: %w", err)
+		}
+		return copyRange(w, src, layout.materialsEnd, -1)
+	})
+}
+
+// copyRange 从 src 的 offset 处复制 n 个字节到 w，n 小于 0 时复制到文件末尾
+func copyRange(w io.Writer, src io.ReadSeeker, offset int64, n int64) error {
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek .model file: %w", err)
+	}
+	var err error
+	if n < 0 {
+		_, err = io.Copy(w, src)
+	} else {
+		_, err = io.CopyN(w, src, n)
+	}
+	if err == io.EOF {
+		return fmt.Errorf(".model file changed while patching: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy .model data: %w", err)
+	}
+	return nil
+}
+
+// encodeModelMaterial 与 decodeModelMaterial 相反，按 .mate 编码后去掉 .mate 的文件头
+func encodeModelMaterial(material *COM3D2.Material, version int32) ([]byte, error) {
+	var header bytes.Buffer
+	appendString(&header, COM3D2.MateSignature)
+	binary.Write(&header, binary.LittleEndian, version)
+	appendString(&header, "")
+
+	mate := &COM3D2.Mate{Signature: COM3D2.MateSignature, Version: version, Material: material}
+	var buf bytes.Buffer
+	if err := mate.Dump(&buf); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(buf.Bytes(), header.Bytes()) {
+		return nil, fmt.Errorf("unexpected .mate header")
+	}
+	return buf.Bytes()[header.Len():], nil
+}
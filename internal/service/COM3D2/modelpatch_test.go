@@ -0,0 +1,199 @@
+package COM3D2
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCanPatchModel(t *testing.T) {
+	tests := []struct {
+		oldVersion int32
+		newVersion int32
+		want       bool
+	}{
+		{1000, 1000, true},
+		{1000, 2000, true},
+		{2000, 2001, false},
+		{2001, 2100, false},
+		{2100, 2104, true},
+		{2104, 2100, true},
+		{2104, 2000, false},
+	}
+	for _, tt := range tests {
+		if got := canPatchModel(tt.oldVersion, tt.newVersion); got != tt.want {
+			t.Errorf("canPatchModel(%d, %d) = %v, want %v", tt.oldVersion, tt.newVersion, got, tt.want)
+		}
+	}
+}
+
+// writeFixture 将 data 写入临时目录中的 name，返回路径
+func writeFixture(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// patchFixture 用 metadata 修改 original，返回修改后的文件内容和两者的布局
+func patchFixture(t *testing.T, original []byte, edit func(metadata *COM3D2.ModelMetadata)) (patched []byte, before *modelLayout, after *modelLayout) {
+	t.Helper()
+	inputPath := writeFixture(t, "input.model", original)
+	before, err := readModelLayout(inputPath)
+	if err != nil {
+		t.Fatalf("readModelLayout: %v", err)
+	}
+	metadata := &COM3D2.ModelMetadata{
+		Signature:         before.signature,
+		Version:           before.version,
+		Name:              before.name,
+		RootBoneName:      before.rootBoneName,
+		ShadowCastingMode: before.shadowCastingMode,
+	}
+	edit(metadata)
+
+	outputPath := filepath.Join(filepath.Dir(inputPath), "output.model")
+	if err := patchModel(inputPath, outputPath, before, metadata); err != nil {
+		t.Fatalf("patchModel: %v", err)
+	}
+	if patched, err = os.ReadFile(outputPath); err != nil {
+		t.Fatal(err)
+	}
+	if after, err = readModelLayout(outputPath); err != nil {
+		t.Fatalf("readModelLayout of patched file: %v", err)
+	}
+	return patched, before, after
+}
+
+// assertMeshDataUnchanged 骨骼、网格数据块和形态键必须与原文件逐字节相同
+func assertMeshDataUnchanged(t *testing.T, original []byte, patched []byte, before *modelLayout, after *modelLayout) {
+	t.Helper()
+	if !bytes.Equal(original[before.bonesOffset:before.materialsOffset], patched[after.bonesOffset:after.materialsOffset]) {
+		t.Errorf("bones, vertices or indices changed")
+	}
+	if !bytes.Equal(original[before.materialsEnd:], patched[after.materialsEnd:]) {
+		t.Errorf("morphs changed")
+	}
+}
+
+// 不涉及材质编码的修改：只重写文件头和材质数量
+func TestPatchModelHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      func(metadata *COM3D2.ModelMetadata)
+		identical bool // 修改后与原文件完全相同
+	}{
+		{"unchanged", func(metadata *COM3D2.ModelMetadata) {}, true},
+		{"rename", func(metadata *COM3D2.ModelMetadata) { metadata.Name = "renamed model" }, false},
+		{"root bone", func(metadata *COM3D2.ModelMetadata) { metadata.RootBoneName = "Bip01" }, false},
+	}
+	for _, version := range fixtureModelVersions {
+		original := fixtureModelVersion(version)
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%d %s", version, tt.name), func(t *testing.T) {
+				var want COM3D2.ModelMetadata
+				patched, before, after := patchFixture(t, original, func(metadata *COM3D2.ModelMetadata) {
+					tt.edit(metadata)
+					want = *metadata
+				})
+				if after.name != want.Name || after.rootBoneName != want.RootBoneName || after.version != want.Version {
+					t.Errorf("header = %q %q %d, want %q %q %d",
+						after.name, after.rootBoneName, after.version, want.Name, want.RootBoneName, want.Version)
+				}
+				if !reflect.DeepEqual(after.shadowCastingMode, before.shadowCastingMode) {
+					t.Errorf("shadowCastingMode = %v, want %v", after.shadowCastingMode, before.shadowCastingMode)
+				}
+				assertMeshDataUnchanged(t, original, patched, before, after)
+				if tt.identical != bytes.Equal(original, patched) {
+					t.Errorf("patched file identical to original = %v, want %v", !tt.identical, tt.identical)
+				}
+			})
+		}
+	}
+}
+
+// 在骨骼、网格数据块和皮肤厚度结构相同的版本之间修改版本，阴影投射模式随版本添加或删除
+func TestPatchModelVersion(t *testing.T) {
+	mode := "TwoSided"
+	tests := []struct {
+		from int32
+		to   int32
+		mode *string
+	}{
+		{2000, 1000, nil},
+		{modelSkinThicknessVersion, modelShadowCastingVersion, &mode},
+		{modelShadowCastingVersion, modelSkinThicknessVersion, nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d to %d", tt.from, tt.to), func(t *testing.T) {
+			original := fixtureModelVersion(tt.from)
+			patched, before, after := patchFixture(t, original, func(metadata *COM3D2.ModelMetadata) {
+				metadata.Version = tt.to
+				metadata.ShadowCastingMode = tt.mode
+			})
+			if after.version != tt.to || !reflect.DeepEqual(after.shadowCastingMode, tt.mode) {
+				t.Errorf("version = %d, shadowCastingMode = %v", after.version, after.shadowCastingMode)
+			}
+			assertMeshDataUnchanged(t, original, patched, before, after)
+		})
+	}
+
+	// 2104 的文件头必须有阴影投射模式
+	path := writeFixture(t, "input.model", fixtureModelVersion(modelSkinThicknessVersion))
+	layout, err := readModelLayout(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := &COM3D2.ModelMetadata{Signature: layout.signature, Version: modelShadowCastingVersion, Name: layout.name, RootBoneName: layout.rootBoneName}
+	if err := patchModel(path, path+".out", layout, metadata); err == nil {
+		t.Error("patchModel to 2104 without ShadowCastingMode succeeded")
+	}
+}
+
+// patchModel 的结果必须与修改结构体后完整编码的结果逐字节相同
+func TestPatchModelMatchesDump(t *testing.T) {
+	for _, version := range fixtureModelVersions {
+		t.Run(fmt.Sprint(version), func(t *testing.T) {
+			original := fixtureModelAllProperties(version)
+			model, err := COM3D2.ReadModel(bytes.NewReader(original))
+			if err != nil {
+				t.Fatalf("ReadModel: %v", err)
+			}
+			if model == nil {
+				t.Fatal("ReadModel returned nil")
+			}
+
+			// 未修改时 Dump 必须还原 fixture，确认 fixture 的结构与 MeidoSerialization 一致
+			var unchanged bytes.Buffer
+			if err := model.Dump(&unchanged); err != nil {
+				t.Fatalf("Dump: %v", err)
+			}
+			if !bytes.Equal(unchanged.Bytes(), original) {
+				t.Fatalf("Dump of the unchanged fixture differs: got %d bytes, want %d bytes", unchanged.Len(), len(original))
+			}
+
+			model.Name = "edited"
+			model.Materials[0].Name = "edited body"
+			model.Materials = model.Materials[:1]
+			var want bytes.Buffer
+			if err := model.Dump(&want); err != nil {
+				t.Fatalf("Dump: %v", err)
+			}
+
+			patched, before, after := patchFixture(t, original, func(metadata *COM3D2.ModelMetadata) {
+				metadata.Name = model.Name
+				metadata.Materials = model.Materials
+			})
+			if !bytes.Equal(patched, want.Bytes()) {
+				t.Errorf("patched file differs from Dump: got %d bytes, want %d bytes", len(patched), want.Len())
+			}
+			assertMeshDataUnchanged(t, original, patched, before, after)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package COM3D2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/utilities"
+	"io"
+	"strings"
+)
+
+// 二进制文件解析失败时，只知道解析器返回的错误并不能判断文件是被截断、版本未知还是某个部分已损坏
+// 读取时用 offsetReader 记录解析器已经读取的字节数，失败后再按文件结构（见 binscan.go）从头扫描到该位置，得到出错的部分
+
+// ParseError 二进制文件解析失败的位置
+type ParseError struct {
+	Path      string `json:"Path"`      // 文件路径，解码内存中的数据时为空
+	FileType  string `json:"FileType"`  // 文件类型名称
+	Offset    int64  `json:"Offset"`    // 解析器停止时已经读取的字节数
+	Size      int64  `json:"Size"`      // 文件大小
+	Signature string `json:"Signature"` // 文件开头的签名，无法读取时为空
+	Version   int32  `json:"Version"`   // 文件版本
+	Section   string `json:"Section"`   // 出错时正在读取的部分，例如 bone 14 name，无法定位时为空
+	Message   string `json:"Message"`   // 解析器返回的错误
+	Err       error  `json:"-"`
+}
+
+func (e *ParseError) Error() string {
+	var details []string
+	details = append(details, fmt.Sprintf("%d of %d bytes", e.Offset, e.Size))
+	if e.Signature != "" {
+		details = append(details, fmt.Sprintf("signature %s, version %d", e.Signature, e.Version))
+	}
+	if e.Section != "" {
+		details = append(details, "in "+e.Section)
+	}
+	return fmt.Sprintf("parsing the .%s file failed at offset 0x%x (%s): %s", e.FileType, e.Offset, strings.Join(details, ", "), e.Message)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DiagnoseFile 解析二进制文件，失败时返回描述出错位置的 ParseError，解析成功时返回 nil
+// 文件类型无法识别或文件无法打开时返回错误
+func (m *CommonService) DiagnoseFile(path string) (*ParseError, error) {
+	fileInfo, err := m.FileTypeDetermine(path, false)
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.StorageFormat != FormatBinary {
+		return nil, fmt.Errorf("%w: %s is not a binary file", ErrUnsupportedFileType, path)
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFileType, fileInfo.FileType)
+	}
+
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = f.Decode(file)
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Path = path
+		return parseErr, nil
+	}
+	return nil, err
+}
+
+// offsetReader 记录解析器已经读取的字节数
+type offsetReader struct {
+	r      io.Reader
+	s      io.Seeker // 加入缓冲后为 nil，此时不支持 Seek
+	offset int64
+}
+
+// newOffsetReader 包装 rs，rs 需要位于文件开头
+func newOffsetReader(rs io.ReadSeeker) *offsetReader {
+	return &offsetReader{r: rs, s: rs}
+}
+
+func (o *offsetReader) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	if o.s == nil {
+		return o.offset, errors.New("seek is not supported on a buffered reader")
+	}
+	pos, err := o.s.Seek(offset, whence)
+	if err == nil {
+		o.offset = pos
+	}
+	return pos, err
+}
+
+// buffered 在底层读取器和计数之间加入缓冲，计数仍然是解析器实际消耗的字节数，见 readBuffered
+func (o *offsetReader) buffered(size int) *offsetReader {
+	o.r = bufio.NewReaderSize(o.r, size)
+	o.s = nil
+	return o
+}
+
+// newParseError 根据解析器停止的位置生成 ParseError，rs 为原始数据，会被移动到其他位置
+func newParseError(fileType string, locate func(s *binaryScanner) error, rs io.ReadSeeker, offset int64, err error) *ParseError {
+	parseErr := &ParseError{FileType: fileType, Offset: offset, Message: err.Error(), Err: err}
+
+	if size, seekErr := rs.Seek(0, io.SeekEnd); seekErr == nil {
+		parseErr.Size = size
+	}
+	if _, seekErr := rs.Seek(0, io.SeekStart); seekErr != nil {
+		return parseErr
+	}
+	// 签名和版本可能就是出错的原因，读取失败时留空
+	if signature, readErr := utilities.ReadString(rs); readErr == nil {
+		parseErr.Signature = signature
+		if version, readErr := utilities.ReadInt32(rs); readErr == nil {
+			parseErr.Version = version
+		}
+	}
+
+	if locate != nil {
+		if _, seekErr := rs.Seek(0, io.SeekStart); seekErr == nil {
+			parseErr.Section = locateSection(rs, offset, locate)
+		}
+	}
+	return parseErr
+}
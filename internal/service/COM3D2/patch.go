@@ -0,0 +1,164 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSON Patch（RFC 6902）编辑，用于只传输修改的部分，例如
+// [{"op": "replace", "path": "/Materials/2/Properties/5/Color", "value": {...}}]
+// 路径按 Go 结构体校验，见 structpath.go；补丁中任何一个操作失败时整个补丁不生效
+
+// JSON Patch 操作类型
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// PatchService 对文件应用 JSON Patch
+type PatchService struct{}
+
+// PatchOperation 一个 JSON Patch 操作，字段名称遵循 RFC 6902
+type PatchOperation struct {
+	Op    string      `json:"op"`              // 操作类型，见顶部常量定义
+	Path  string      `json:"path"`            // 目标路径，JSON Pointer
+	From  string      `json:"from,omitempty"`  // move、copy 的来源路径
+	Value interface{} `json:"value,omitempty"` // add、replace、test 的值
+}
+
+// ApplyPatchToFile 读取文件（二进制或 .json 均可），应用补丁后写入 outputPath
+// outputPath 可以与 inputPath 相同，根据后缀写出二进制或 .json 文件
+func (p *PatchService) ApplyPatchToFile(inputPath string, outputPath string, patch []PatchOperation) error {
+	fileInfo, err := (&CommonService{}).FileTypeDetermine(inputPath, false)
+	if err != nil {
+		return err
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedFileType, inputPath)
+	}
+	data, err := f.Read(inputPath)
+	if err != nil {
+		return err
+	}
+	if _, _, err := applyPatch(data, patch); err != nil {
+		return err
+	}
+	return f.Write(outputPath, data)
+}
+
+// ApplyPatch 对打开的文档应用补丁，整个补丁作为一次编辑，可以一次撤销
+func (d *DocumentService) ApplyPatch(handle string, patch []PatchOperation) (*DocumentInfo, error) {
+	return d.editWith(handle, func(data interface{}) ([]pathOp, []pathOp, error) {
+		return applyPatch(data, patch)
+	})
+}
+
+// ParsePatch 解析 JSON Patch 文档
+func ParsePatch(raw []byte) ([]PatchOperation, error) {
+	var patch []PatchOperation
+	if err := json.Unmarshal(raw, &patch); err != nil {
+		return nil, fmt.Errorf("invalid JSON Patch: %w", err)
+	}
+	return patch, nil
+}
+
+// applyPatch 依次执行补丁中的操作，返回实际执行的基本操作和对应的逆操作，失败时回滚
+// move、copy 会在执行时读取来源的值，因此需要逐个展开
+func applyPatch(root interface{}, patch []PatchOperation) ([]pathOp, []pathOp, error) {
+	var ops, inverse []pathOp
+	for i, operation := range patch {
+		expanded, err := expandPatchOperation(root, operation)
+		if err != nil {
+			return nil, nil, rollbackPathOps(root, inverse, fmt.Errorf("patch operation %d: %w", i, err))
+		}
+		for _, op := range expanded {
+			undo, err := applyPathOp(root, op)
+			if err != nil {
+				return nil, nil, rollbackPathOps(root, inverse, fmt.Errorf("patch operation %d: %w", i, err))
+			}
+			ops = append(ops, op)
+			inverse = append(inverse, undo)
+		}
+	}
+	return ops, inverse, nil
+}
+
+// expandPatchOperation 将一个 JSON Patch 操作转换为基本操作
+// test 在这里直接检查，不产生基本操作
+func expandPatchOperation(root interface{}, operation PatchOperation) ([]pathOp, error) {
+	switch operation.Op {
+	case PatchAdd, PatchReplace:
+		value, err := json.Marshal(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []pathOp{{Op: operation.Op, Path: operation.Path, Value: value}}, nil
+
+	case PatchRemove:
+		return []pathOp{{Op: pathOpRemove, Path: operation.Path}}, nil
+
+	case PatchMove, PatchCopy:
+		if operation.Op == PatchMove && strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("cannot move %s into its own child %s", operation.From, operation.Path)
+		}
+		value, err := getPath(root, operation.From)
+		if err != nil {
+			return nil, fmt.Errorf("%s from %s: %w", operation.Op, operation.From, err)
+		}
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		add := pathOp{Op: pathOpAdd, Path: operation.Path, Value: raw}
+		if operation.Op == PatchCopy {
+			return []pathOp{add}, nil
+		}
+		if operation.From == operation.Path {
+			return nil, nil
+		}
+		return []pathOp{{Op: pathOpRemove, Path: operation.From}, add}, nil
+
+	case PatchTest:
+		return nil, testPatchValue(root, operation)
+
+	default:
+		return nil, fmt.Errorf("unknown JSON Patch op %q", operation.Op)
+	}
+}
+
+// testPatchValue 检查路径处的值是否等于 operation.Value
+// 期望值先按目标的类型解码再比较，因此 1 和 1.0 等数值上相等的写法视为相同
+func testPatchValue(root interface{}, operation PatchOperation) error {
+	actual, err := getPath(root, operation.Path)
+	if err != nil {
+		return fmt.Errorf("test %s: %w", operation.Path, err)
+	}
+	actualRaw, err := json.Marshal(actual)
+	if err != nil {
+		return err
+	}
+	expectedRaw, err := json.Marshal(operation.Value)
+	if err != nil {
+		return err
+	}
+
+	target, err := getPathType(root, operation.Path)
+	if err != nil {
+		return err
+	}
+	expected, err := decodeValue(expectedRaw, target)
+	if err == nil {
+		expectedRaw, err = json.Marshal(expected.Interface())
+	}
+	if err != nil || !bytes.Equal(actualRaw, expectedRaw) {
+		return fmt.Errorf("test %s failed: value is %s", operation.Path, actualRaw)
+	}
+	return nil
+}
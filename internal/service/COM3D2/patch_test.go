@@ -0,0 +1,139 @@
+package COM3D2
+
+import (
+	"strings"
+	"testing"
+)
+
+// 应用补丁后再倒序执行逆操作，文档必须恢复原样
+func TestApplyPatchUndo(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  func(doc *pathTestDoc)
+	}{
+		{
+			name:  "replace",
+			patch: `[{"op": "replace", "path": "/Items/1/Value", "value": 2.5}]`,
+			want:  func(doc *pathTestDoc) { doc.Items[1].Value = 2.5 },
+		},
+		{
+			name:  "add and remove",
+			patch: `[{"op": "add", "path": "/Values/-", "value": 4}, {"op": "remove", "path": "/Values/0"}]`,
+			want:  func(doc *pathTestDoc) { doc.Values = []int32{2, 3, 4} },
+		},
+		{
+			name:  "move within slice",
+			patch: `[{"op": "move", "from": "/Items/0", "path": "/Items/1"}]`,
+			want:  func(doc *pathTestDoc) { doc.Items[0], doc.Items[1] = doc.Items[1], doc.Items[0] },
+		},
+		{
+			name:  "move to same path",
+			patch: `[{"op": "move", "from": "/Name", "path": "/Name"}]`,
+			want:  func(doc *pathTestDoc) {},
+		},
+		{
+			name:  "copy",
+			patch: `[{"op": "copy", "from": "/Child", "path": "/Items/-"}]`,
+			want: func(doc *pathTestDoc) {
+				doc.Items = append(doc.Items, &pathTestItem{Key: doc.Child.Key, Value: doc.Child.Value})
+			},
+		},
+		{
+			name:  "copy map value",
+			patch: `[{"op": "copy", "from": "/Tags/a~1b", "path": "/Name"}]`,
+			want:  func(doc *pathTestDoc) { doc.Name = "slash" },
+		},
+		{
+			name:  "test then replace",
+			patch: `[{"op": "test", "path": "/Values/0", "value": 1.0}, {"op": "replace", "path": "/Values/0", "value": 10}]`,
+			want:  func(doc *pathTestDoc) { doc.Values[0] = 10 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := ParsePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := newPathTestDoc()
+			original := mustJson(t, doc)
+			expected := newPathTestDoc()
+			tt.want(expected)
+
+			_, inverse, err := applyPatch(doc, patch)
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if got := mustJson(t, doc); got != mustJson(t, expected) {
+				t.Errorf("after patch:\n got %s\nwant %s", got, mustJson(t, expected))
+			}
+			if err := applyReversed(doc, inverse); err != nil {
+				t.Fatalf("undo: %v", err)
+			}
+			if got := mustJson(t, doc); got != original {
+				t.Errorf("after undo:\n got %s\nwant %s", got, original)
+			}
+		})
+	}
+}
+
+// 任何一个操作失败时整个补丁不生效
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{
+			name:    "failed test",
+			patch:   `[{"op": "replace", "path": "/Name", "value": "changed"}, {"op": "test", "path": "/Values/0", "value": 2}]`,
+			wantErr: "test /Values/0 failed",
+		},
+		{
+			name:    "move into own child",
+			patch:   `[{"op": "move", "from": "/Child", "path": "/Child/Key"}]`,
+			wantErr: "into its own child",
+		},
+		{
+			name:    "missing from",
+			patch:   `[{"op": "copy", "from": "/Items/5", "path": "/Items/-"}]`,
+			wantErr: "copy from /Items/5",
+		},
+		{
+			name:    "unknown op",
+			patch:   `[{"op": "remove", "path": "/Values/0"}, {"op": "swap", "path": "/Name"}]`,
+			wantErr: `unknown JSON Patch op "swap"`,
+		},
+		{
+			name:    "later operation fails",
+			patch:   `[{"op": "remove", "path": "/Items/0"}, {"op": "add", "path": "/Tags/x", "value": 1}]`,
+			wantErr: "patch operation 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := ParsePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := newPathTestDoc()
+			original := mustJson(t, doc)
+			_, _, err = applyPatch(doc, patch)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("applyPatch error = %v, want %q", err, tt.wantErr)
+			}
+			if got := mustJson(t, doc); got != original {
+				t.Errorf("failed patch changed the document:\n got %s\nwant %s", got, original)
+			}
+		})
+	}
+}
+
+func TestParsePatchInvalid(t *testing.T) {
+	for _, raw := range []string{``, `{}`, `[{"op": 1}]`} {
+		if _, err := ParsePatch([]byte(raw)); err == nil {
+			t.Errorf("ParsePatch(%q) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package COM3D2
+
+import (
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"github.com/emmansun/base64" // use faster base64 implementation
+	"io"
+	"sort"
+	"strings"
+)
+
+// presetFormat .preset 文件格式
+var presetFormat = registerFormat(&Format[COM3D2.Preset]{
+	fileType:  "preset",
+	signature: COM3D2.PresetSignature,
+	json:      true,
+	read:      readBuffered(COM3D2.ReadPreset, 1024*1024*1), // 1MB 缓冲区
+	dump:      (*COM3D2.Preset).Dump,
+})
+
+// 预设类型，对应游戏中的 CharacterMgr.PresetType
+var presetTypeNames = map[int32]string{
+	0: "Wear", // 服装
+	1: "Body", // 身体
+	2: "All",  // 全部
+}
+
+// PresetService 专门处理 .preset 文件的读写
+type PresetService struct{}
+
+// PresetItem 预设中的一个部位（MPN）及其使用的 .menu 文件
+type PresetItem struct {
+	MPN      string `json:"MPN"`      // 部位名称，例如 hairf、wear
+	FileName string `json:"FileName"` // 使用的 .menu 文件名
+	Value    int32  `json:"Value"`    // 部位的数值，身体滑块等没有 .menu 文件的部位只有数值
+}
+
+// PresetSummary 预设的概要信息，前端不接受多个返回值，因此使用结构体
+type PresetSummary struct {
+	Version        int32        `json:"Version"`
+	PresetType     int32        `json:"PresetType"`
+	PresetTypeName string       `json:"PresetTypeName"` // Wear/Body/All
+	HasThumbnail   bool         `json:"HasThumbnail"`
+	Items          []PresetItem `json:"Items"` // 按 MPN 排序
+}
+
+// ReadPresetFile 读取 .preset、.preset.json 或 .preset.yaml 文件并返回对应结构体
+func (s *PresetService) ReadPresetFile(path string) (*COM3D2.Preset, error) {
+	return presetFormat.ReadFile(path)
+}
+
+// WritePresetFile 接收 Preset 数据并写入 .preset、.preset.json 或 .preset.yaml 文件
+func (s *PresetService) WritePresetFile(path string, presetData *COM3D2.Preset) error {
+	return presetFormat.WriteFile(path, presetData)
+}
+
+// ConvertPresetToJson 接收输入文件路径和输出文件路径，将输入文件转换为 .json 或 .yaml 文件
+func (s *PresetService) ConvertPresetToJson(inputPath string, outputPath string) error {
+	return presetFormat.ConvertToJson(inputPath, outputPath)
+}
+
+// ConvertJsonToPreset 接收输入文件路径和输出文件路径，将输入文件转换为 .preset 文件
+func (s *PresetService) ConvertJsonToPreset(inputPath string, outputPath string) error {
+	return presetFormat.ConvertFromJson(inputPath, outputPath)
+}
+
+// ReadPresetSummary 读取 .preset 文件，返回预设类型和 MPN → .menu 的列表
+func (s *PresetService) ReadPresetSummary(path string) (PresetSummary, error) {
+	presetData, err := s.ReadPresetFile(path)
+	if err != nil {
+		return PresetSummary{}, err
+	}
+
+	return PresetSummary{
+		Version:        presetData.Version,
+		PresetType:     presetData.PresetType,
+		PresetTypeName: presetTypeNames[presetData.PresetType],
+		HasThumbnail:   len(presetData.ThumbData) > 0,
+		Items:          maidPropItems(presetData.PropList),
+	}, nil
+}
+
+// ReadPresetItems 读取 .preset 文件，只返回使用了 .menu 文件的部位
+func (s *PresetService) ReadPresetItems(path string) ([]PresetItem, error) {
+	presetData, err := s.ReadPresetFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []PresetItem
+	for _, item := range maidPropItems(presetData.PropList) {
+		if item.FileName != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// ReadPresetThumbnail 读取 .preset 文件中嵌入的缩略图，返回 base64 编码的 PNG 数据
+func (s *PresetService) ReadPresetThumbnail(path string) (Base64EncodedPngData string, err error) {
+	presetData, err := s.ReadPresetFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(presetData.ThumbData) == 0 {
+		return "", fmt.Errorf("preset %s has no thumbnail", path)
+	}
+	return base64.StdEncoding.EncodeToString(presetData.ThumbData), nil
+}
+
+// WritePresetThumbnail 将 .preset 文件中嵌入的缩略图写出为 PNG 文件
+func (s *PresetService) WritePresetThumbnail(inputPath string, outputPath string) error {
+	presetData, err := s.ReadPresetFile(inputPath)
+	if err != nil {
+		return err
+	}
+	if len(presetData.ThumbData) == 0 {
+		return fmt.Errorf("preset %s has no thumbnail", inputPath)
+	}
+	outputPath, err = resolveWritePath(outputPath)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(outputPath, func(w io.Writer) error {
+		if _, err := w.Write(presetData.ThumbData); err != nil {
+			return fmt.Errorf("unable to write thumbnail: %w", err)
+		}
+		return nil
+	})
+}
+
+// maidPropItems 将女仆的属性列表转换为 PresetItem，按 MPN 排序，.preset 和 .save 共用
+func maidPropItems(props []*COM3D2.MaidProp) []PresetItem {
+	items := make([]PresetItem, 0, len(props))
+	for _, prop := range props {
+		if prop == nil {
+			continue
+		}
+		items = append(items, PresetItem{
+			MPN:      prop.Name,
+			FileName: prop.FileName,
+			Value:    prop.Value,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return strings.ToLower(items[i].MPN) < strings.ToLower(items[j].MPN)
+	})
+	return items
+}
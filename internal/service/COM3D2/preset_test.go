@@ -0,0 +1,83 @@
+package COM3D2
+
+import (
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 预设经过 .json 和 .yaml 写出再读取后保持不变，缩略图以 base64 保存
+func TestPresetTextRoundTrip(t *testing.T) {
+	preset := &COM3D2.Preset{
+		Signature:  COM3D2.PresetSignature,
+		Version:    2000,
+		PresetType: 1,
+		ThumbData:  []byte{0x89, 'P', 'N', 'G', 0, 1, 2, 0xff},
+		PropList: []*COM3D2.MaidProp{
+			{Index: 1, Name: "wear", Type: 3, Value: 0, FileName: "wear_a.menu"},
+			{Index: 2, Name: "Hara", Type: 2, Value: 50},
+		},
+	}
+
+	presetService := &PresetService{}
+	for _, name := range []string{"test.preset.json", "test.preset.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := presetService.WritePresetFile(path, preset); err != nil {
+				t.Fatalf("WritePresetFile: %v", err)
+			}
+			got, err := presetService.ReadPresetFile(path)
+			if err != nil {
+				t.Fatalf("ReadPresetFile: %v", err)
+			}
+			if !reflect.DeepEqual(got, preset) {
+				t.Errorf("round trip = %+v, want %+v", got, preset)
+			}
+		})
+	}
+}
+
+func TestReadPresetSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.preset.json")
+	const presetJson = `{"Signature": "CM3D2_PRESET", "Version": 2000, "PresetType": 2, "PropList": [
+		{"Name": "wear", "FileName": "wear_a.menu"},
+		{"Name": "Hara", "Value": 50},
+		{"Name": "acchat", "FileName": "hat.menu"}
+	]}`
+	if err := os.WriteFile(path, []byte(presetJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	presetService := &PresetService{}
+	summary, err := presetService.ReadPresetSummary(path)
+	if err != nil {
+		t.Fatalf("ReadPresetSummary: %v", err)
+	}
+	want := PresetSummary{
+		Version:        2000,
+		PresetType:     2,
+		PresetTypeName: "All",
+		Items: []PresetItem{
+			{MPN: "acchat", FileName: "hat.menu"},
+			{MPN: "Hara", Value: 50},
+			{MPN: "wear", FileName: "wear_a.menu"},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+
+	items, err := presetService.ReadPresetItems(path)
+	if err != nil {
+		t.Fatalf("ReadPresetItems: %v", err)
+	}
+	if len(items) != 2 || items[0].MPN != "acchat" || items[1].MPN != "wear" {
+		t.Errorf("items = %+v", items)
+	}
+
+	if _, err := presetService.ReadPresetThumbnail(path); err == nil {
+		t.Error("ReadPresetThumbnail of a preset without a thumbnail succeeded")
+	}
+}
@@ -0,0 +1,328 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// 往返校验：读取二进制文件后重新编码，检查结果是否与原文件逐字节相同
+// 二进制往返：Decode → Encode → 比较
+// JSON 往返：Decode → JSON → Decode → Encode → 比较，用于确认 .json 编辑流程不会丢失数据
+// YAML 往返：Decode → JSON → YAML → JSON → Decode → Encode → 比较，确认 .yaml 同样不会丢失数据
+// 差异通常只是编码不同（例如字符串长度的写法、浮点数的位模式），解码后的结构体完全相同，因此先按文件结构扫描原文件定位差异
+
+// roundTripContextSize 从差异位置开始展示的字节数
+const roundTripContextSize = 16
+
+// RoundTripDiff 往返结果与原文件的第一个差异
+type RoundTripDiff struct {
+	Offset        int64  `json:"Offset"`        // 第一个不同字节的偏移
+	OriginalSize  int64  `json:"OriginalSize"`  // 原文件大小
+	RewrittenSize int64  `json:"RewrittenSize"` // 重新编码后的大小
+	Field         string `json:"Field"`         // 差异所在的部分，例如 command 3 argument 1，见 FormatHandler.Locate；该格式无法扫描时为第一个不同的结构体字段，例如 Commands[3].Args[1]，都无法定位时为空
+	Original      string `json:"Original"`      // 原文件在 Offset 处的字节，十六进制
+	Rewritten     string `json:"Rewritten"`     // 重新编码后在 Offset 处的字节，十六进制
+}
+
+// RoundTripResult 单个文件的往返校验结果
+type RoundTripResult struct {
+	Path        string         `json:"Path"`
+	FileType    string         `json:"FileType"`
+	BinaryOK    bool           `json:"BinaryOK"`
+	BinaryDiff  *RoundTripDiff `json:"BinaryDiff"`
+	JsonOK      bool           `json:"JsonOK"`
+	JsonSkipped bool           `json:"JsonSkipped"` // 该格式不支持 JSON，例如 .tex
+	JsonDiff    *RoundTripDiff `json:"JsonDiff"`
+	YamlOK      bool           `json:"YamlOK"` // JsonSkipped 时同样跳过
+	YamlDiff    *RoundTripDiff `json:"YamlDiff"`
+	Error       string         `json:"Error"` // 读取或编码失败时的错误
+}
+
+// OK 是否通过全部校验
+func (r RoundTripResult) OK() bool {
+	return r.Error == "" && r.BinaryOK && ((r.JsonOK && r.YamlOK) || r.JsonSkipped)
+}
+
+// VerifyRoundTrip 对单个二进制文件进行二进制往返、JSON 往返和 YAML 往返校验
+// 只有读取、解码或二进制编码失败时才返回错误，内容不一致和 JSON、YAML 阶段的错误记录在结果中
+func (m *CommonService) VerifyRoundTrip(path string) (RoundTripResult, error) {
+	result := RoundTripResult{Path: path}
+
+	fileInfo, err := m.FileTypeDetermine(path, false)
+	if err != nil {
+		return result, err
+	}
+	result.FileType = fileInfo.FileType
+	if fileInfo.StorageFormat != FormatBinary {
+		return result, fmt.Errorf("%w: %s is not a binary file", ErrUnsupportedFileType, path)
+	}
+	f, ok := FormatByFileType(fileInfo.FileType)
+	if !ok || !f.SupportsWrite() {
+		return result, fmt.Errorf("%w: %s files cannot be written", ErrUnsupportedFileType, fileInfo.FileType)
+	}
+
+	file, err := openFile(path)
+	if err != nil {
+		return result, err
+	}
+	original, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return result, err
+	}
+
+	decoded, err := f.Decode(bytes.NewReader(original))
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			parseErr.Path = path
+		}
+		return result, err
+	}
+
+	// 二进制往返
+	rewritten, err := encodeToBytes(f, decoded)
+	if err != nil {
+		return result, err
+	}
+	result.BinaryDiff = compareRoundTrip(original, rewritten)
+	result.BinaryOK = result.BinaryDiff == nil
+	if !result.BinaryOK {
+		result.BinaryDiff.Field = f.Locate(bytes.NewReader(original), result.BinaryDiff.Offset)
+		if result.BinaryDiff.Field == "" {
+			// 该格式无法扫描时，重新解码后与原结构体比较
+			if redecoded, err := f.Decode(bytes.NewReader(rewritten)); err == nil {
+				result.BinaryDiff.Field = firstDifference(reflect.ValueOf(decoded), reflect.ValueOf(redecoded), "")
+			}
+		}
+	}
+
+	// JSON 往返
+	if !f.SupportsJSON() {
+		result.JsonSkipped = true
+		return result, nil
+	}
+	// JSON 阶段失败时保留二进制往返的结果，错误记录在 Error 中
+	jsonData, err := json.Marshal(decoded)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to marshal %s data: %v", f.FileType(), err)
+		return result, nil
+	}
+	result.JsonDiff, err = roundTripFromJson(f, original, decoded, jsonData, jsonSuffix)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.JsonOK = result.JsonDiff == nil
+
+	// YAML 往返
+	yamlData, err := jsonToYaml(jsonData, yamlIndent)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to convert %s data to YAML: %v", f.FileType(), err)
+		return result, nil
+	}
+	jsonData, err = yamlToJson(yamlData)
+	if err != nil {
+		result.Error = fmt.Sprintf("parsing the %s.yaml data failed: %v", f.FileType(), err)
+		return result, nil
+	}
+	result.YamlDiff, err = roundTripFromJson(f, original, decoded, jsonData, yamlSuffix)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.YamlOK = result.YamlDiff == nil
+	return result, nil
+}
+
+// roundTripFromJson 将 JSON 解码后重新编码为二进制并与原文件比较，相同时返回 nil
+// suffix 为数据来源的文本格式，只用于错误信息
+func roundTripFromJson(f FormatHandler, original []byte, decoded interface{}, jsonData []byte, suffix string) (*RoundTripDiff, error) {
+	fromText := f.New()
+	if err := json.Unmarshal(jsonData, fromText); err != nil {
+		return nil, fmt.Errorf("parsing the %s%s data failed: %v", f.FileType(), suffix, err)
+	}
+	rewritten, err := encodeToBytes(f, fromText)
+	if err != nil {
+		return nil, err
+	}
+	diff := compareRoundTrip(original, rewritten)
+	if diff != nil {
+		diff.Field = f.Locate(bytes.NewReader(original), diff.Offset)
+		if diff.Field == "" {
+			diff.Field = firstDifference(reflect.ValueOf(decoded), reflect.ValueOf(fromText), "")
+		}
+	}
+	return diff, nil
+}
+
+// VerifyRoundTripDirectory 递归校验目录中所有可写回的二进制文件，单个文件失败记录在结果的 Error 中
+func (m *CommonService) VerifyRoundTripDirectory(dir string) ([]RoundTripResult, error) {
+	var results []RoundTripResult
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, ok := FormatByFileType(strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+		if !ok || !f.SupportsWrite() {
+			return nil
+		}
+		result, err := m.VerifyRoundTrip(path)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return results, nil
+}
+
+// encodeToBytes 将结构体编码为二进制数据
+func encodeToBytes(f FormatHandler, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.Encode(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to write to %s file: %w", f.Extension(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// compareRoundTrip 比较原文件和重新编码的结果，相同时返回 nil
+func compareRoundTrip(original []byte, rewritten []byte) *RoundTripDiff {
+	if bytes.Equal(original, rewritten) {
+		return nil
+	}
+	offset := 0
+	for offset < len(original) && offset < len(rewritten) && original[offset] == rewritten[offset] {
+		offset++
+	}
+	return &RoundTripDiff{
+		Offset:        int64(offset),
+		OriginalSize:  int64(len(original)),
+		RewrittenSize: int64(len(rewritten)),
+		Original:      hexAt(original, offset),
+		Rewritten:     hexAt(rewritten, offset),
+	}
+}
+
+// hexAt 返回 offset 开始的若干字节的十六进制表示
+func hexAt(data []byte, offset int) string {
+	if offset >= len(data) {
+		return ""
+	}
+	end := offset + roundTripContextSize
+	if end > len(data) {
+		end = len(data)
+	}
+	return hex.EncodeToString(data[offset:end])
+}
+
+// firstDifference 按字段顺序比较两个值，返回第一个不同字段的路径，相同时返回空字符串
+// 字段顺序与二进制编码顺序一致，因此第一个不同的字段就是差异字节所属的字段
+func firstDifference(a reflect.Value, b reflect.Value, path string) string {
+	if a.IsValid() != b.IsValid() {
+		return fieldPath(path)
+	}
+	if !a.IsValid() {
+		return ""
+	}
+	if a.Type() != b.Type() {
+		return fieldPath(path)
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				return fieldPath(path)
+			}
+			return ""
+		}
+		return firstDifference(a.Elem(), b.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			name := a.Type().Field(i).Name
+			if diff := firstDifference(a.Field(i), b.Field(i), joinField(path, name)); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case reflect.Slice, reflect.Array:
+		n := a.Len()
+		if b.Len() < n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			if diff := firstDifference(a.Index(i), b.Index(i), fmt.Sprintf("%s[%d]", path, i)); diff != "" {
+				return diff
+			}
+		}
+		if a.Len() != b.Len() {
+			return fieldPath(path)
+		}
+		return ""
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return fieldPath(path)
+		}
+		for _, key := range a.MapKeys() {
+			if diff := firstDifference(a.MapIndex(key), b.MapIndex(key), fmt.Sprintf("%s[%v]", path, key)); diff != "" {
+				return diff
+			}
+		}
+		return ""
+	case reflect.Float32, reflect.Float64:
+		// 按位比较，NaN 和 -0 也需要原样保留
+		if math.Float64bits(a.Float()) != math.Float64bits(b.Float()) {
+			return fieldPath(path)
+		}
+		return ""
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			return fieldPath(path)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			return fieldPath(path)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			return fieldPath(path)
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			return fieldPath(path)
+		}
+	}
+	return ""
+}
+
+// joinField 拼接字段路径
+func joinField(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// fieldPath 根路径显示为 (root)
+func fieldPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
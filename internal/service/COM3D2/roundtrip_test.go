@@ -0,0 +1,127 @@
+package COM3D2
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// itemNameOffset fixtureMenu 中 item name 的偏移：签名 1 + 10、版本 4、source file name 1 + 4
+const itemNameOffset = 20
+
+func TestVerifyRoundTripIdentical(t *testing.T) {
+	path := writeFixture(t, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	result, err := (&CommonService{}).VerifyRoundTrip(path)
+	if err != nil {
+		t.Fatalf("VerifyRoundTrip: %v", err)
+	}
+	if !result.OK() || result.BinaryDiff != nil || result.JsonDiff != nil || result.YamlDiff != nil {
+		t.Errorf("result = %+v", result)
+	}
+	if result.FileType != "menu" || result.JsonSkipped {
+		t.Errorf("FileType = %q, JsonSkipped = %v", result.FileType, result.JsonSkipped)
+	}
+}
+
+// 字符串长度使用了非最短的 7 位编码，解码结果相同，重新编码后从该字符串开始不同
+func TestVerifyRoundTripFirstDifference(t *testing.T) {
+	data := fixtureMenu([]string{"additem", "item.model"})
+	if data[itemNameOffset] != 4 {
+		t.Fatalf("unexpected fixture layout at 0x%x: %x", itemNameOffset, data[itemNameOffset])
+	}
+	original := append([]byte{}, data[:itemNameOffset]...)
+	original = append(original, 0x84, 0x00)
+	original = append(original, data[itemNameOffset+1:]...)
+	path := writeFixture(t, "item.menu", original)
+
+	result, err := (&CommonService{}).VerifyRoundTrip(path)
+	if err != nil {
+		t.Fatalf("VerifyRoundTrip: %v", err)
+	}
+	if result.OK() {
+		t.Fatal("round trip of a non-canonical file passed")
+	}
+	want := &RoundTripDiff{
+		Offset:        itemNameOffset,
+		OriginalSize:  int64(len(original)),
+		RewrittenSize: int64(len(data)),
+		Field:         "item name",
+		Original:      hexAt(original, itemNameOffset),
+		Rewritten:     hexAt(data, itemNameOffset),
+	}
+	for name, diff := range map[string]*RoundTripDiff{"binary": result.BinaryDiff, "json": result.JsonDiff, "yaml": result.YamlDiff} {
+		if !reflect.DeepEqual(diff, want) {
+			t.Errorf("%s diff = %+v, want %+v", name, diff, want)
+		}
+	}
+	if result.Error != "" {
+		t.Errorf("Error = %q", result.Error)
+	}
+}
+
+func TestCompareRoundTrip(t *testing.T) {
+	if diff := compareRoundTrip([]byte{1, 2, 3}, []byte{1, 2, 3}); diff != nil {
+		t.Errorf("identical data diff = %+v", diff)
+	}
+	tests := []struct {
+		original  []byte
+		rewritten []byte
+		offset    int64
+	}{
+		{[]byte{1, 2, 3}, []byte{1, 9, 3}, 1},
+		{[]byte{1, 2, 3}, []byte{9, 2, 3}, 0},
+		{[]byte{1, 2, 3}, []byte{1, 2}, 2},
+		{[]byte{1, 2}, []byte{1, 2, 3}, 2},
+		{nil, []byte{1}, 0},
+	}
+	for _, tt := range tests {
+		diff := compareRoundTrip(tt.original, tt.rewritten)
+		if diff == nil || diff.Offset != tt.offset || diff.OriginalSize != int64(len(tt.original)) || diff.RewrittenSize != int64(len(tt.rewritten)) {
+			t.Errorf("compareRoundTrip(%v, %v) = %+v, want offset %d", tt.original, tt.rewritten, diff, tt.offset)
+		}
+	}
+
+	// 只展示差异位置开始的 roundTripContextSize 个字节
+	long := bytes.Repeat([]byte{0xab}, roundTripContextSize*2)
+	diff := compareRoundTrip(long, []byte{0xab, 0xcd})
+	if diff == nil || diff.Offset != 1 || len(diff.Original) != roundTripContextSize*2 || diff.Rewritten != "cd" {
+		t.Errorf("context = %+v", diff)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	type item struct {
+		Name  string
+		Value float32
+	}
+	type file struct {
+		Version int32
+		Items   []*item
+		Extra   interface{}
+	}
+	base := func() file {
+		return file{Version: 1, Items: []*item{{"a", 1}, {"b", 2}}, Extra: "x"}
+	}
+	tests := []struct {
+		change func(f *file)
+		want   string
+	}{
+		{func(f *file) {}, ""},
+		{func(f *file) { f.Version = 2 }, "Version"},
+		{func(f *file) { f.Items[1].Name = "c" }, "Items[1].Name"},
+		{func(f *file) { f.Items[0].Value = 3 }, "Items[0].Value"},
+		{func(f *file) { f.Items = f.Items[:1] }, "Items"},
+		{func(f *file) { f.Items[1] = nil }, "Items[1]"},
+		{func(f *file) { f.Extra = 1 }, "Extra"},
+	}
+	for _, tt := range tests {
+		a, b := base(), base()
+		tt.change(&b)
+		if got := firstDifference(reflect.ValueOf(a), reflect.ValueOf(b), ""); got != tt.want {
+			t.Errorf("firstDifference = %q, want %q", got, tt.want)
+		}
+	}
+	if got := firstDifference(reflect.ValueOf(1), reflect.ValueOf(2), ""); got != "(root)" {
+		t.Errorf("root difference = %q", got)
+	}
+}
@@ -0,0 +1,202 @@
+package COM3D2
+
+import (
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// saveFormat .save 文件格式
+// 存档结构复杂且与游戏进度强相关，目前只读，不提供写回，避免损坏玩家存档
+var saveFormat = registerFormat(&Format[COM3D2.SaveData]{
+	fileType:  "save",
+	signature: COM3D2.SaveSignature,
+	json:      true,
+	read:      readBuffered(COM3D2.ReadSave, 1024*1024*10), // 10MB 缓冲区
+	dump:      nil,
+})
+
+// 导出女仆外观时使用的预设类型和版本
+const (
+	presetTypeAll = 2    // 包含服装和身体，见 presetTypeNames
+	presetVersion = 2000 // 导出时写入的 .preset 版本，与存档的版本无关
+)
+
+// SaveService 专门处理 .save 文件的读取和导出
+type SaveService struct{}
+
+// SaveMaidInfo 存档中一个女仆的概要信息
+type SaveMaidInfo struct {
+	Index     int          `json:"Index"` // 在存档女仆列表中的位置，用于导出预设
+	Guid      string       `json:"Guid"`
+	FirstName string       `json:"FirstName"`
+	LastName  string       `json:"LastName"`
+	Items     []PresetItem `json:"Items"` // 穿戴的 .menu 文件，按 MPN 排序
+}
+
+// SaveSummary 存档的概要信息，前端不接受多个返回值，因此使用结构体
+type SaveSummary struct {
+	Version    int32          `json:"Version"`
+	SaveTime   string         `json:"SaveTime"`
+	GameDay    int32          `json:"GameDay"`
+	PlayerName string         `json:"PlayerName"`
+	Comment    string         `json:"Comment"`
+	Maids      []SaveMaidInfo `json:"Maids"`
+}
+
+// ReadSaveFile 读取 .save、.save.json 或 .save.yaml 文件并返回对应结构体
+func (s *SaveService) ReadSaveFile(path string) (*COM3D2.SaveData, error) {
+	return saveFormat.ReadFile(path)
+}
+
+// ConvertSaveToJson 接收输入文件路径和输出文件路径，将输入文件转换为 .json 或 .yaml 文件
+func (s *SaveService) ConvertSaveToJson(inputPath string, outputPath string) error {
+	return saveFormat.ConvertToJson(inputPath, outputPath)
+}
+
+// ReadSaveSummary 读取 .save 文件，返回存档头信息、女仆列表以及每个女仆穿戴的 .menu 文件
+func (s *SaveService) ReadSaveSummary(path string) (SaveSummary, error) {
+	saveData, err := s.ReadSaveFile(path)
+	if err != nil {
+		return SaveSummary{}, err
+	}
+
+	summary := SaveSummary{
+		Version:    saveData.Version,
+		SaveTime:   saveData.Header.SaveTime,
+		GameDay:    saveData.Header.GameDay,
+		PlayerName: saveData.Header.PlayerName,
+		Comment:    saveData.Header.Comment,
+		Maids:      make([]SaveMaidInfo, 0, len(saveData.Maids)),
+	}
+	for i, maid := range saveData.Maids {
+		if maid == nil {
+			continue
+		}
+		var items []PresetItem
+		for _, item := range maidPropItems(maid.PropList) {
+			if item.FileName != "" {
+				items = append(items, item)
+			}
+		}
+		summary.Maids = append(summary.Maids, SaveMaidInfo{
+			Index:     i,
+			Guid:      maid.Guid,
+			FirstName: maid.FirstName,
+			LastName:  maid.LastName,
+			Items:     items,
+		})
+	}
+	return summary, nil
+}
+
+// ReadSaveMenuFiles 读取 .save 文件，返回所有女仆穿戴的 .menu 文件，去重并排序
+// 用于确认一个存档依赖哪些 MOD 物品
+func (s *SaveService) ReadSaveMenuFiles(path string) ([]string, error) {
+	summary, err := s.ReadSaveSummary(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	var files []string
+	for _, maid := range summary.Maids {
+		for _, item := range maid.Items {
+			key := strings.ToLower(item.FileName)
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			files = append(files, item.FileName)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return strings.ToLower(files[i]) < strings.ToLower(files[j])
+	})
+	return files, nil
+}
+
+// ExportMaidPreset 将存档中指定女仆的外观导出为独立的 .preset 文件
+// maidIndex 为 SaveMaidInfo.Index，导出的预设类型为 All（服装和身体）
+func (s *SaveService) ExportMaidPreset(savePath string, maidIndex int, outputPath string) error {
+	saveData, err := s.ReadSaveFile(savePath)
+	if err != nil {
+		return err
+	}
+	if maidIndex < 0 || maidIndex >= len(saveData.Maids) || saveData.Maids[maidIndex] == nil {
+		return fmt.Errorf("maid index %d out of range, the save has %d maids", maidIndex, len(saveData.Maids))
+	}
+
+	return presetFormat.WriteFile(outputPath, maidToPreset(saveData.Maids[maidIndex]))
+}
+
+// ExportAllMaidPresets 将存档中所有女仆的外观导出到目录，文件名为女仆姓名，返回写出的文件路径
+func (s *SaveService) ExportAllMaidPresets(savePath string, outputDir string) ([]string, error) {
+	saveData, err := s.ReadSaveFile(savePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create output directory: %w", err)
+	}
+
+	var written []string
+	usedNames := map[string]int{}
+	for i, maid := range saveData.Maids {
+		if maid == nil {
+			continue
+		}
+		name := sanitizeFileName(strings.TrimSpace(maid.LastName + " " + maid.FirstName))
+		if name == "" {
+			name = fmt.Sprintf("maid_%d", i)
+		}
+		// 同名女仆追加序号，避免互相覆盖
+		usedNames[strings.ToLower(name)]++
+		if n := usedNames[strings.ToLower(name)]; n > 1 {
+			name = fmt.Sprintf("%s_%d", name, n)
+		}
+
+		outputPath := filepath.Join(outputDir, name+".preset")
+		if err := presetFormat.WriteFile(outputPath, maidToPreset(maid)); err != nil {
+			return written, err
+		}
+		written = append(written, outputPath)
+	}
+	return written, nil
+}
+
+// maidToPreset 使用存档中女仆的属性列表构造类型为 All 的预设
+// 属性列表会被复制，修改导出的预设不会影响存档数据；存档中没有的字段（例如缩略图）保持零值
+func maidToPreset(maid *COM3D2.SaveMaid) *COM3D2.Preset {
+	propList := make([]*COM3D2.MaidProp, 0, len(maid.PropList))
+	for _, prop := range maid.PropList {
+		if prop == nil {
+			continue
+		}
+		copied := *prop
+		propList = append(propList, &copied)
+	}
+	return &COM3D2.Preset{
+		Signature:  COM3D2.PresetSignature,
+		Version:    presetVersion,
+		PresetType: presetTypeAll,
+		PropList:   propList,
+	}
+}
+
+// sanitizeFileName 替换文件名中不允许的字符
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '<', '>', ':', '"', '/', '\\', '|', '?', '*':
+			return '_'
+		}
+		if r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,141 @@
+package COM3D2
+
+import (
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// 两个同名女仆和一个没有名字的女仆，第二个女仆与第一个共用一个 .menu
+const saveFixtureJson = `{
+	"Signature": "COM3D2_SAVE",
+	"Version": 1000,
+	"Header": {"SaveTime": "20260101120000", "GameDay": 12, "PlayerName": "master", "MaidNum": 3, "Comment": "test"},
+	"Maids": [
+		{"Guid": "a", "FirstName": "Hanako", "LastName": "Yamada", "PropList": [
+			{"Index": 1, "Name": "wear", "Type": 3, "Value": 0, "FileName": "wear_a.menu"},
+			{"Index": 2, "Name": "Hara", "Type": 2, "Value": 50, "FileName": ""},
+			{"Index": 3, "Name": "acchat", "Type": 3, "Value": 0, "FileName": "Hat.menu"}
+		]},
+		{"Guid": "b", "FirstName": "Hanako", "LastName": "Yamada", "PropList": [
+			{"Index": 1, "Name": "wear", "Type": 3, "Value": 0, "FileName": "WEAR_A.menu"}
+		]},
+		{"Guid": "c", "FirstName": "", "LastName": "", "PropList": []}
+	]
+}`
+
+// writeSaveFixture 写出 saveFixtureJson，返回路径
+func writeSaveFixture(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.save.json")
+	if err := os.WriteFile(path, []byte(saveFixtureJson), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSaveSummary(t *testing.T) {
+	summary, err := (&SaveService{}).ReadSaveSummary(writeSaveFixture(t))
+	if err != nil {
+		t.Fatalf("ReadSaveSummary: %v", err)
+	}
+	if summary.Version != 1000 || summary.GameDay != 12 || summary.PlayerName != "master" || summary.Comment != "test" {
+		t.Errorf("header = %+v", summary)
+	}
+	if len(summary.Maids) != 3 {
+		t.Fatalf("got %d maids, want 3", len(summary.Maids))
+	}
+	// 只列出使用 .menu 的部位，按 MPN 排序
+	want := []PresetItem{{MPN: "acchat", FileName: "Hat.menu"}, {MPN: "wear", FileName: "wear_a.menu"}}
+	if maid := summary.Maids[0]; maid.Index != 0 || maid.Guid != "a" || !reflect.DeepEqual(maid.Items, want) {
+		t.Errorf("maid 0 = %+v", maid)
+	}
+	if maid := summary.Maids[2]; maid.Index != 2 || len(maid.Items) != 0 {
+		t.Errorf("maid 2 = %+v", maid)
+	}
+}
+
+func TestReadSaveMenuFiles(t *testing.T) {
+	files, err := (&SaveService{}).ReadSaveMenuFiles(writeSaveFixture(t))
+	if err != nil {
+		t.Fatalf("ReadSaveMenuFiles: %v", err)
+	}
+	// 不区分大小写去重，保留第一次出现的写法
+	if want := []string{"Hat.menu", "wear_a.menu"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
+
+func TestExportMaidPreset(t *testing.T) {
+	savePath := writeSaveFixture(t)
+	outputPath := filepath.Join(t.TempDir(), "maid.preset.json")
+	saveService := &SaveService{}
+	if err := saveService.ExportMaidPreset(savePath, 0, outputPath); err != nil {
+		t.Fatalf("ExportMaidPreset: %v", err)
+	}
+
+	preset, err := (&PresetService{}).ReadPresetFile(outputPath)
+	if err != nil {
+		t.Fatalf("ReadPresetFile: %v", err)
+	}
+	if preset.Signature != COM3D2.PresetSignature || preset.Version != presetVersion || preset.PresetType != presetTypeAll {
+		t.Errorf("preset header = %q %d %d", preset.Signature, preset.Version, preset.PresetType)
+	}
+	saveData, err := saveService.ReadSaveFile(savePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preset.PropList, saveData.Maids[0].PropList) {
+		t.Errorf("PropList = %+v, want %+v", preset.PropList, saveData.Maids[0].PropList)
+	}
+
+	for _, index := range []int{-1, 3} {
+		if err := saveService.ExportMaidPreset(savePath, index, outputPath); err == nil {
+			t.Errorf("ExportMaidPreset(%d) succeeded", index)
+		}
+	}
+}
+
+// 导出的预设复制属性列表，修改预设不会改变存档数据
+func TestMaidToPresetCopiesProps(t *testing.T) {
+	maid := &COM3D2.SaveMaid{PropList: []*COM3D2.MaidProp{{Name: "wear", FileName: "a.menu"}, nil}}
+	preset := maidToPreset(maid)
+	if len(preset.PropList) != 1 {
+		t.Fatalf("PropList = %+v", preset.PropList)
+	}
+	preset.PropList[0].FileName = "b.menu"
+	if maid.PropList[0].FileName != "a.menu" {
+		t.Error("changing the preset changed the save")
+	}
+}
+
+func TestExportAllMaidPresets(t *testing.T) {
+	outputDir := t.TempDir()
+	written, err := (&SaveService{}).ExportAllMaidPresets(writeSaveFixture(t), outputDir)
+	if err != nil {
+		t.Fatalf("ExportAllMaidPresets: %v", err)
+	}
+	// 同名女仆追加序号，没有名字时使用序号
+	want := []string{"Yamada Hanako.preset", "Yamada Hanako_2.preset", "maid_2.preset"}
+	var got []string
+	for _, path := range written {
+		if filepath.Dir(path) != outputDir {
+			t.Errorf("%s written outside %s", path, outputDir)
+		}
+		got = append(got, filepath.Base(path))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("written = %v, want %v", got, want)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	if got := sanitizeFileName("a<b>c:d\"e/f\\g|h?i*j\tk"); got != "a_b_c_d_e_f_g_h_i_j_k" {
+		t.Errorf("sanitizeFileName = %q", got)
+	}
+	if got := sanitizeFileName("山田 花子"); got != "山田 花子" {
+		t.Errorf("sanitizeFileName changed %q", got)
+	}
+}
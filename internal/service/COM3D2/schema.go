@@ -0,0 +1,235 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// 根据 Go 结构体生成 .X.json 文件的 JSON Schema（draft-07，VS Code 支持的版本）
+// 多态字段在 Go 中是接口切片，JSON 中通过 TypeName 区分具体类型，可能的类型登记在 polymorphicFields 中
+
+// jsonSchemaDraft 生成的 Schema 使用的版本
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// schemaVariant 多态字段的一种具体类型
+type schemaVariant struct {
+	typeName string // JSON 中 TypeName 的值
+	value    interface{}
+}
+
+// polymorphicFields 多态字段可能的具体类型，键为 结构体名称.字段名称
+// MeidoSerialization 新增属性或碰撞体类型时需要在这里登记
+var polymorphicFields = map[string][]schemaVariant{
+	"Material.Properties": {
+		{typeName: propertyTagTex, value: COM3D2.TexProperty{}},
+		{typeName: propertyTagCol, value: COM3D2.ColProperty{}},
+		{typeName: propertyTagVec, value: COM3D2.VecProperty{}},
+		{typeName: propertyTagF, value: COM3D2.FProperty{}},
+		{typeName: propertyTagRange, value: COM3D2.RangeProperty{}},
+		{typeName: propertyTagTexOffset, value: COM3D2.TexOffsetProperty{}},
+		{typeName: propertyTagTexScale, value: COM3D2.TexScaleProperty{}},
+		{typeName: propertyTagKeyword, value: COM3D2.KeywordProperty{}},
+	},
+	"Col.Colliders": {
+		{typeName: "dbc", value: COM3D2.DynamicBoneCollider{}},
+		{typeName: "dbm", value: COM3D2.DynamicBoneMuneCollider{}},
+		{typeName: "dbp", value: COM3D2.DynamicBonePlaneCollider{}},
+		{typeName: "missing", value: COM3D2.MissingCollider{}},
+	},
+}
+
+// schemaGenerator 生成一个根类型的 Schema，所有结构体放在 definitions 中
+type schemaGenerator struct {
+	definitions map[string]interface{}
+	typeNames   map[reflect.Type]string // 多态类型 → TypeName
+}
+
+// GetJsonSchema 返回指定文件类型的 .json 文件的 JSON Schema
+func (m *CommonService) GetJsonSchema(fileType string) (string, error) {
+	schema, err := jsonSchemaFor(fileType)
+	if err != nil {
+		return "", err
+	}
+	return string(schema), nil
+}
+
+// WriteJsonSchemas 将所有支持 JSON 的格式的 Schema 写入目录，文件名为 <type>.schema.json，返回写出的文件路径
+func (m *CommonService) WriteJsonSchemas(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create output directory: %w", err)
+	}
+
+	var written []string
+	for _, f := range Formats() {
+		if !f.SupportsJSON() {
+			continue
+		}
+		schema, err := jsonSchemaFor(f.FileType())
+		if err != nil {
+			return written, err
+		}
+		path := filepath.Join(dir, f.FileType()+".schema.json")
+		err = writeFileAtomic(path, func(w io.Writer) error {
+			_, err := w.Write(schema)
+			return err
+		})
+		if err != nil {
+			return written, fmt.Errorf("unable to write schema: %w", err)
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// jsonSchemaFor 生成指定文件类型的 Schema
+func jsonSchemaFor(fileType string) ([]byte, error) {
+	f, ok := FormatByFileType(fileType)
+	if !ok || !f.SupportsJSON() {
+		return nil, fmt.Errorf("%w: %s has no JSON representation", ErrUnsupportedFileType, fileType)
+	}
+
+	g := &schemaGenerator{
+		definitions: map[string]interface{}{},
+		typeNames:   map[reflect.Type]string{},
+	}
+	for _, variants := range polymorphicFields {
+		for _, v := range variants {
+			g.typeNames[reflect.TypeOf(v.value)] = v.typeName
+		}
+	}
+
+	// draft-07 中与 $ref 并列的关键字会被忽略，因此根结构体直接展开而不是引用
+	rootType := reflect.TypeOf(f.New()).Elem()
+	g.schemaFor(rootType, "")
+	root := map[string]interface{}{}
+	for k, v := range g.definitions[rootType.Name()].(map[string]interface{}) {
+		root[k] = v
+	}
+	root["$schema"] = jsonSchemaDraft
+	root["title"] = "COM3D2 " + f.Extension() + ".json"
+	root["definitions"] = g.definitions
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaFor 返回类型的 Schema，结构体返回 $ref 并登记到 definitions
+// field 为 结构体名称.字段名称，用于查找多态字段
+func (g *schemaGenerator) schemaFor(t reflect.Type, field string) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return nullable(g.schemaFor(t.Elem(), field))
+	case reflect.Interface:
+		if variants, ok := polymorphicFields[field]; ok {
+			oneOf := make([]interface{}, 0, len(variants))
+			for _, v := range variants {
+				oneOf = append(oneOf, g.schemaFor(reflect.TypeOf(v.value), ""))
+			}
+			return map[string]interface{}{"oneOf": oneOf}
+		}
+		return map[string]interface{}{}
+	case reflect.Struct:
+		name := t.Name()
+		if _, exists := g.definitions[name]; !exists {
+			g.definitions[name] = true // 先占位，避免递归类型无限展开
+			g.definitions[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": g.schemaFor(t.Elem(), field)}
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem(), field), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": g.schemaFor(t.Elem(), field)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Int8:
+		return integerSchema(math.MinInt8, math.MaxInt8)
+	case reflect.Int16:
+		return integerSchema(math.MinInt16, math.MaxInt16)
+	case reflect.Int32:
+		return integerSchema(math.MinInt32, math.MaxInt32)
+	case reflect.Uint8:
+		return integerSchema(0, math.MaxUint8)
+	case reflect.Uint16:
+		return integerSchema(0, math.MaxUint16)
+	case reflect.Uint32:
+		return integerSchema(0, math.MaxUint32)
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema 生成结构体的 Schema，字段名称和可选性遵循 encoding/json 的规则
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	g.collectFields(t, t.Name(), properties, &required)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// collectFields 收集结构体字段，匿名嵌入的结构体字段会被展开
+func (g *schemaGenerator) collectFields(t reflect.Type, owner string, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.collectFields(embedded, owner, properties, required)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		schema := g.schemaFor(sf.Type, owner+"."+sf.Name)
+		if typeName, ok := g.typeNames[t]; ok && sf.Name == "TypeName" && sf.Type.Kind() == reflect.String {
+			schema = map[string]interface{}{"type": "string", "const": typeName}
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable 允许值为 null
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
+
+// integerSchema 带范围的整数
+func integerSchema(min int64, max int64) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "minimum": min, "maximum": max}
+}
@@ -0,0 +1,104 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// readSchema 生成 fileType 的 Schema 并解析为 map
+func readSchema(t *testing.T, fileType string) map[string]interface{} {
+	t.Helper()
+	data, err := jsonSchemaFor(fileType)
+	if err != nil {
+		t.Fatalf("jsonSchemaFor(%s): %v", fileType, err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema of %s is not valid JSON: %v", fileType, err)
+	}
+	return schema
+}
+
+// 多态字段的每个具体类型都出现在 oneOf 中，并且 TypeName 固定为对应的值
+func TestJsonSchemaTypeNameConst(t *testing.T) {
+	tests := []struct {
+		fileType string
+		owner    string
+		field    string
+	}{
+		{"mate", "Material", "Properties"},
+		{"model", "Material", "Properties"},
+		{"col", "Col", "Colliders"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileType, func(t *testing.T) {
+			schema := readSchema(t, tt.fileType)
+			definitions := schema["definitions"].(map[string]interface{})
+
+			owner, ok := definitions[tt.owner].(map[string]interface{})
+			if !ok {
+				t.Fatalf("definition %s missing", tt.owner)
+			}
+			field := owner["properties"].(map[string]interface{})[tt.field].(map[string]interface{})
+			var refs []interface{}
+			for _, variant := range field["items"].(map[string]interface{})["oneOf"].([]interface{}) {
+				refs = append(refs, variant.(map[string]interface{})["$ref"])
+			}
+
+			variants := polymorphicFields[tt.owner+"."+tt.field]
+			var wantRefs []interface{}
+			for _, v := range variants {
+				name := reflect.TypeOf(v.value).Name()
+				wantRefs = append(wantRefs, "#/definitions/"+name)
+
+				definition, ok := definitions[name].(map[string]interface{})
+				if !ok {
+					t.Errorf("definition %s missing", name)
+					continue
+				}
+				want := map[string]interface{}{"type": "string", "const": v.typeName}
+				if got := definition["properties"].(map[string]interface{})["TypeName"]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s.TypeName = %v, want %v", name, got, want)
+				}
+			}
+			if !reflect.DeepEqual(refs, wantRefs) {
+				t.Errorf("%s.%s oneOf = %v, want %v", tt.owner, tt.field, refs, wantRefs)
+			}
+		})
+	}
+}
+
+// 与二进制标签共用常量，JSON 中的 TypeName 与 .mate 中的属性标签一致
+func TestJsonSchemaPropertyTypeNames(t *testing.T) {
+	var got []string
+	for _, v := range polymorphicFields["Material.Properties"] {
+		got = append(got, v.typeName)
+	}
+	want := []string{"tex", "col", "vec", "f", "range", "tex_offset", "tex_scale", "keyword"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("property type names = %v, want %v", got, want)
+	}
+}
+
+// 不在 polymorphicFields 中的结构体即使有 TypeName 字段也不固定取值
+func TestJsonSchemaTypeNameNotConstOutsideVariants(t *testing.T) {
+	definitions := readSchema(t, "col")["definitions"].(map[string]interface{})
+	base, ok := definitions["DynamicBoneColliderBase"].(map[string]interface{})
+	if !ok {
+		t.Fatal("definition DynamicBoneColliderBase missing")
+	}
+	typeName, ok := base["properties"].(map[string]interface{})["TypeName"].(map[string]interface{})
+	if !ok {
+		t.Fatal("DynamicBoneColliderBase.TypeName missing")
+	}
+	if _, ok := typeName["const"]; ok {
+		t.Errorf("DynamicBoneColliderBase.TypeName = %v", typeName)
+	}
+}
+
+func TestJsonSchemaUnsupportedType(t *testing.T) {
+	if _, err := jsonSchemaFor("tex"); err == nil {
+		t.Error("jsonSchemaFor(tex) succeeded")
+	}
+}
@@ -0,0 +1,296 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 设置和最近打开的文件保存在用户配置目录（Linux 为 $XDG_CONFIG_HOME，Windows 为 %AppData%）下的 COM3D2_MOD_EDITOR_V2/settings.json
+// 程序所在目录存在 settings.json 或 portable 文件时为便携模式，设置保存在程序所在目录
+// GUI 和命令行都在启动时调用 LoadSettings，命令行参数的默认值来自设置
+
+// settingsAppName 配置目录名称
+const settingsAppName = "COM3D2_MOD_EDITOR_V2"
+
+// settingsFileName 设置文件名称
+const settingsFileName = "settings.json"
+
+// portableMarkerName 便携模式标记文件名称
+const portableMarkerName = "portable"
+
+// defaultRecentFilesLimit 默认保留的最近文件数量
+const defaultRecentFilesLimit = 20
+
+// SettingsService 读写持久化的设置和最近打开的文件
+type SettingsService struct{}
+
+// Settings 用户设置
+type Settings struct {
+	FileTypeStrictMode bool              `json:"FileTypeStrictMode"` // 文件类型判断的严格模式，见 FileTypeDetermine
+	TexForcePng        bool              `json:"TexForcePng"`        // .tex 转图片时强制使用 PNG
+	TexDirectConvert   bool              `json:"TexDirectConvert"`   // 图片直接转换为 .tex，不经过编辑器
+	TexCompress        bool              `json:"TexCompress"`        // 图片转 .tex 时压缩
+	TexDefaultFormat   string            `json:"TexDefaultFormat"`   // .tex 导出图片的默认格式，例如 png
+	EditorViewModes    map[string]string `json:"EditorViewModes"`    // 文件类型 → 编辑器默认显示方式
+	ArchiveOutputDir   string            `json:"ArchiveOutputDir"`   // 见 SetArchiveOutputDir
+	BackupCount        int               `json:"BackupCount"`        // 见 SetBackupCount
+	Json               JsonOptions       `json:"Json"`               // 见 SetJsonOptions
+	RecentFilesLimit   int               `json:"RecentFilesLimit"`   // 最多保留的最近文件数量
+}
+
+// RecentFile 最近打开的文件
+type RecentFile struct {
+	Path     string    `json:"Path"`
+	FileType string    `json:"FileType"`
+	OpenedAt time.Time `json:"OpenedAt"`
+}
+
+// settingsFile 设置文件的内容
+type settingsFile struct {
+	Settings    Settings     `json:"Settings"`
+	RecentFiles []RecentFile `json:"RecentFiles"`
+}
+
+var (
+	settingsMu      sync.Mutex
+	settings        = settingsFile{Settings: defaultSettings()}
+	settingsLoadErr error // 设置文件存在但无法读取时的错误，此时不覆盖该文件，避免丢失用户的设置
+)
+
+// defaultSettings 没有设置文件时使用的设置
+func defaultSettings() Settings {
+	return Settings{
+		TexForcePng:      true,
+		TexDefaultFormat: "png",
+		EditorViewModes:  map[string]string{},
+		RecentFilesLimit: defaultRecentFilesLimit,
+	}
+}
+
+// LoadSettings 读取设置文件并应用，文件不存在时使用默认设置
+// 文件存在但无法读取或内容无效时返回错误并继续使用默认设置，之后的保存都会失败，直到用户修复或删除该文件
+func LoadSettings() error {
+	path, _, err := settingsPath()
+	if err != nil {
+		return err
+	}
+
+	loaded, err := readSettingsFile(path)
+	settingsMu.Lock()
+	settingsLoadErr = err
+	if err == nil {
+		settings = loaded
+	}
+	settingsMu.Unlock()
+	if err != nil {
+		return err
+	}
+	applySettings(loaded.Settings)
+	return nil
+}
+
+// readSettingsFile 读取并校验设置文件，文件不存在时返回默认设置
+func readSettingsFile(path string) (settingsFile, error) {
+	loaded := settingsFile{Settings: defaultSettings()}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return loaded, fmt.Errorf("cannot read settings: %w", err)
+	default:
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return loaded, fmt.Errorf("invalid settings file %s: %w", path, err)
+		}
+	}
+	if err := validateSettings(&loaded.Settings); err != nil {
+		return loaded, fmt.Errorf("invalid settings file %s: %w", path, err)
+	}
+	return loaded, nil
+}
+
+// CurrentSettings 返回当前的设置
+func CurrentSettings() Settings {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return copySettings(settings.Settings)
+}
+
+// GetSettings 返回当前的设置
+func (s *SettingsService) GetSettings() Settings {
+	return CurrentSettings()
+}
+
+// SetSettings 校验、应用并保存设置
+func (s *SettingsService) SetSettings(newSettings Settings) error {
+	if err := validateSettings(&newSettings); err != nil {
+		return err
+	}
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.Settings = copySettings(newSettings)
+	settings.RecentFiles = trimRecentFiles(settings.RecentFiles, newSettings.RecentFilesLimit)
+	applySettings(newSettings)
+	return saveSettingsLocked()
+}
+
+// GetSettingsPath 返回设置文件的路径
+func (s *SettingsService) GetSettingsPath() (string, error) {
+	path, _, err := settingsPath()
+	return path, err
+}
+
+// IsPortableMode 设置是否保存在程序所在目录
+func (s *SettingsService) IsPortableMode() bool {
+	_, portable, _ := settingsPath()
+	return portable
+}
+
+// GetRecentFiles 返回最近打开的文件，最新的在前
+func (s *SettingsService) GetRecentFiles() []RecentFile {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return append([]RecentFile{}, settings.RecentFiles...)
+}
+
+// AddRecentFile 将文件添加到最近打开的文件列表的最前面并保存
+func (s *SettingsService) AddRecentFile(path string) error {
+	return addRecentFile(path)
+}
+
+// RemoveRecentFile 从最近打开的文件列表中删除文件并保存
+func (s *SettingsService) RemoveRecentFile(path string) error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.RecentFiles = removeRecentFile(settings.RecentFiles, path)
+	return saveSettingsLocked()
+}
+
+// ClearRecentFiles 清空最近打开的文件列表并保存
+func (s *SettingsService) ClearRecentFiles() error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings.RecentFiles = nil
+	return saveSettingsLocked()
+}
+
+// addRecentFile 添加最近打开的文件，已存在时移到最前面
+func addRecentFile(path string) error {
+	if !IsArchivePath(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	fileType := ""
+	if f, ok := FormatByPath(path); ok {
+		fileType = f.FileType()
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	recent := RecentFile{Path: path, FileType: fileType, OpenedAt: time.Now()}
+	settings.RecentFiles = append([]RecentFile{recent}, removeRecentFile(settings.RecentFiles, path)...)
+	settings.RecentFiles = trimRecentFiles(settings.RecentFiles, settings.Settings.RecentFilesLimit)
+	return saveSettingsLocked()
+}
+
+// removeRecentFile 返回删除了 path 的列表
+func removeRecentFile(files []RecentFile, path string) []RecentFile {
+	kept := make([]RecentFile, 0, len(files))
+	for _, f := range files {
+		if f.Path != path {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// trimRecentFiles 只保留最新的 limit 个文件
+func trimRecentFiles(files []RecentFile, limit int) []RecentFile {
+	if len(files) > limit {
+		return files[:limit]
+	}
+	return files
+}
+
+// validateSettings 校验设置并补全默认值
+func validateSettings(s *Settings) error {
+	if err := validateJsonOptions(s.Json); err != nil {
+		return err
+	}
+	if s.BackupCount < 0 {
+		return fmt.Errorf("backup count must not be negative, got %d", s.BackupCount)
+	}
+	if s.RecentFilesLimit <= 0 {
+		s.RecentFilesLimit = defaultRecentFilesLimit
+	}
+	if s.EditorViewModes == nil {
+		s.EditorViewModes = map[string]string{}
+	}
+	return nil
+}
+
+// copySettings 复制设置，避免调用方修改 map
+func copySettings(s Settings) Settings {
+	viewModes := make(map[string]string, len(s.EditorViewModes))
+	for k, v := range s.EditorViewModes {
+		viewModes[k] = v
+	}
+	s.EditorViewModes = viewModes
+	return s
+}
+
+// applySettings 将设置应用到各服务的包级配置
+func applySettings(s Settings) {
+	common := &CommonService{}
+	common.SetArchiveOutputDir(s.ArchiveOutputDir)
+	common.SetBackupCount(s.BackupCount)
+	// 已经校验过，不会失败
+	_ = common.SetJsonOptions(s.Json)
+}
+
+// saveSettingsLocked 写出设置文件，调用时需要持有 settingsMu
+// 设置文件每次打开文件都会写出，不保留备份；加载失败时拒绝覆盖
+func saveSettingsLocked() error {
+	path, _, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if settingsLoadErr != nil {
+		return fmt.Errorf("settings were not saved because the existing settings file could not be loaded, fix or delete it first: %w", settingsLoadErr)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("unable to create settings directory: %w", err)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, 0, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// settingsPath 返回设置文件的路径以及是否为便携模式
+func settingsPath() (path string, portable bool, err error) {
+	if exe, err := os.Executable(); err == nil {
+		exeDir := filepath.Dir(exe)
+		for _, name := range []string{settingsFileName, portableMarkerName} {
+			if _, err := os.Stat(filepath.Join(exeDir, name)); err == nil {
+				return filepath.Join(exeDir, settingsFileName), true, nil
+			}
+		}
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false, fmt.Errorf("cannot locate the user config directory: %w", err)
+	}
+	return filepath.Join(configDir, settingsAppName, settingsFileName), false, nil
+}
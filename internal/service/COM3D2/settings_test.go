@@ -0,0 +1,220 @@
+package COM3D2
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// resetSettings 使用临时的配置目录和默认设置，结束后恢复包级设置
+func resetSettings(t *testing.T) string {
+	t.Helper()
+	isolateSettings(t)
+	settingsMu.Lock()
+	previous, previousErr := settings, settingsLoadErr
+	settings, settingsLoadErr = settingsFile{Settings: defaultSettings()}, nil
+	settingsMu.Unlock()
+	t.Cleanup(func() {
+		settingsMu.Lock()
+		settings, settingsLoadErr = previous, previousErr
+		settingsMu.Unlock()
+		applySettings(previous.Settings)
+	})
+
+	path, portable, err := settingsPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if portable {
+		t.Skip("settings are stored next to the test binary")
+	}
+	return path
+}
+
+func TestLoadSettingsWithoutFile(t *testing.T) {
+	path := resetSettings(t)
+	if err := LoadSettings(); err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if got := CurrentSettings(); !reflect.DeepEqual(got, defaultSettings()) {
+		t.Errorf("settings = %+v, want %+v", got, defaultSettings())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("LoadSettings created %s", path)
+	}
+}
+
+func TestSetSettingsPersists(t *testing.T) {
+	path := resetSettings(t)
+	service := &SettingsService{}
+	want := defaultSettings()
+	want.FileTypeStrictMode = true
+	want.TexDefaultFormat = "jpg"
+	want.EditorViewModes = map[string]string{"menu": "JSON", "mate": "2"}
+	want.ArchiveOutputDir = t.TempDir()
+	want.BackupCount = 2
+	want.Json = JsonOptions{Indent: 4, SortKeys: true}
+	if err := service.SetSettings(want); err != nil {
+		t.Fatalf("SetSettings: %v", err)
+	}
+	if got, err := service.GetSettingsPath(); err != nil || got != path {
+		t.Errorf("GetSettingsPath = %q, %v, want %q", got, err, path)
+	}
+
+	// 设置立即应用到各服务
+	if got := (&CommonService{}).GetArchiveOutputDir(); got != want.ArchiveOutputDir {
+		t.Errorf("archive output dir = %q", got)
+	}
+
+	// 重新读取设置文件
+	settingsMu.Lock()
+	settings = settingsFile{Settings: defaultSettings()}
+	settingsMu.Unlock()
+	if err := LoadSettings(); err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if got := service.GetSettings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded settings = %+v, want %+v", got, want)
+	}
+
+	// 返回的是副本
+	got := service.GetSettings()
+	got.EditorViewModes["menu"] = "TSV"
+	if service.GetSettings().EditorViewModes["menu"] != "JSON" {
+		t.Error("changing the returned settings changed the stored settings")
+	}
+}
+
+func TestSetSettingsValidation(t *testing.T) {
+	resetSettings(t)
+	service := &SettingsService{}
+
+	invalid := defaultSettings()
+	invalid.BackupCount = -1
+	if err := service.SetSettings(invalid); err == nil {
+		t.Error("SetSettings with a negative backup count succeeded")
+	}
+	invalid = defaultSettings()
+	invalid.Json.Indent = -1
+	if err := service.SetSettings(invalid); err == nil {
+		t.Error("SetSettings with a negative indent succeeded")
+	}
+
+	// 缺省的值补全为默认值
+	if err := service.SetSettings(Settings{}); err != nil {
+		t.Fatalf("SetSettings: %v", err)
+	}
+	got := service.GetSettings()
+	if got.RecentFilesLimit != defaultRecentFilesLimit || got.EditorViewModes == nil {
+		t.Errorf("settings = %+v", got)
+	}
+}
+
+// 设置文件无法读取时不覆盖，避免丢失用户的设置
+func TestInvalidSettingsFileIsNotOverwritten(t *testing.T) {
+	path := resetSettings(t)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	const broken = `{"Settings": {"BackupCount": `
+	if err := os.WriteFile(path, []byte(broken), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadSettings(); err == nil {
+		t.Fatal("LoadSettings of an invalid file succeeded")
+	}
+	if got := CurrentSettings(); !reflect.DeepEqual(got, defaultSettings()) {
+		t.Errorf("settings = %+v", got)
+	}
+	service := &SettingsService{}
+	if err := service.SetSettings(defaultSettings()); err == nil || !strings.Contains(err.Error(), "could not be loaded") {
+		t.Errorf("SetSettings error = %v", err)
+	}
+	if err := addRecentFile(path); err == nil {
+		t.Error("addRecentFile succeeded")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != broken {
+		t.Errorf("settings file = %q, %v", data, err)
+	}
+}
+
+func TestRecentFiles(t *testing.T) {
+	resetSettings(t)
+	service := &SettingsService{}
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a.menu"), filepath.Join(dir, "b.mate"), filepath.Join(dir, "c.model.json")
+
+	for _, path := range []string{a, b, a, c} {
+		if err := service.AddRecentFile(path); err != nil {
+			t.Fatalf("AddRecentFile: %v", err)
+		}
+	}
+	paths := func() []string {
+		var got []string
+		for _, f := range service.GetRecentFiles() {
+			got = append(got, f.Path)
+		}
+		return got
+	}
+	// 最新的在前，重复添加时移到最前面
+	if want := []string{c, a, b}; !reflect.DeepEqual(paths(), want) {
+		t.Errorf("recent files = %v, want %v", paths(), want)
+	}
+	if got := service.GetRecentFiles()[0].FileType; got != "model" {
+		t.Errorf("FileType = %q", got)
+	}
+
+	// 减小数量限制时只保留最新的
+	limited := service.GetSettings()
+	limited.RecentFilesLimit = 2
+	if err := service.SetSettings(limited); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{c, a}; !reflect.DeepEqual(paths(), want) {
+		t.Errorf("recent files after limiting = %v, want %v", paths(), want)
+	}
+
+	if err := service.RemoveRecentFile(c); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{a}; !reflect.DeepEqual(paths(), want) {
+		t.Errorf("recent files after removing = %v, want %v", paths(), want)
+	}
+	if err := service.ClearRecentFiles(); err != nil {
+		t.Fatal(err)
+	}
+	if got := service.GetRecentFiles(); len(got) != 0 {
+		t.Errorf("recent files after clearing = %v", got)
+	}
+}
+
+// 打开文档时添加到最近打开的文件，相对路径保存为绝对路径
+func TestOpenDocumentAddsRecentFile(t *testing.T) {
+	resetSettings(t)
+	path := writeFixture(t, "item.menu", fixtureMenu([]string{"additem", "item.model"}))
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(wd, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &DocumentService{}
+	info, err := service.OpenDocument(relative)
+	if err != nil {
+		t.Fatalf("OpenDocument: %v", err)
+	}
+	defer service.CloseDocument(info.Handle)
+	if len(info.Warnings) != 0 {
+		t.Errorf("Warnings = %v", info.Warnings)
+	}
+	recent := (&SettingsService{}).GetRecentFiles()
+	if len(recent) != 1 || recent[0].Path != path || recent[0].FileType != "menu" {
+		t.Errorf("recent files = %+v", recent)
+	}
+}
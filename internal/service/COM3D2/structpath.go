@@ -0,0 +1,437 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 按 JSON Pointer（RFC 6901）路径直接读写 Go 结构体，例如 /Materials/2/Properties/5/Color
+// 路径中的名称与 .json 文件中的字段名称相同，会根据 Go 类型校验，写入的值按目标字段的类型解码
+// 这样编辑时不需要把整个结构体转换为 JSON 树，大文件（例如 .model）只会修改被编辑的部分
+
+// 结构体路径支持的基本操作，名称与 JSON Patch 相同
+const (
+	pathOpAdd     = "add"
+	pathOpRemove  = "remove"
+	pathOpReplace = "replace"
+)
+
+// pathOp 一个基本操作，Value 为 JSON 编码的值，remove 时为空
+type pathOp struct {
+	Op    string
+	Path  string
+	Value json.RawMessage
+}
+
+// parsePointer 将 JSON Pointer 拆分为各级名称，空字符串表示根
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q: must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// getPath 返回路径处的值
+func getPath(root interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = walkPath(reflect.ValueOf(root), tokens, func(target reflect.Value) error {
+		result = target.Interface()
+		return nil
+	})
+	return result, err
+}
+
+// getPathType 返回路径处值的声明类型，接口类型的字段返回接口本身
+func getPathType(root interface{}, pointer string) (reflect.Type, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	var result reflect.Type
+	err = walkPath(reflect.ValueOf(root), tokens, func(target reflect.Value) error {
+		result = target.Type()
+		return nil
+	})
+	return result, err
+}
+
+// applyPathOp 在 root 上执行一个基本操作，返回用于撤销的逆操作
+// root 必须是结构体指针
+func applyPathOp(root interface{}, op pathOp) (pathOp, error) {
+	tokens, err := parsePointer(op.Path)
+	if err != nil {
+		return pathOp{}, err
+	}
+	rootValue := reflect.ValueOf(root)
+	if rootValue.Kind() != reflect.Ptr || rootValue.IsNil() {
+		return pathOp{}, fmt.Errorf("document root must be a non-nil pointer, got %T", root)
+	}
+
+	// 根只能被整体替换
+	if len(tokens) == 0 {
+		if op.Op != pathOpReplace {
+			return pathOp{}, fmt.Errorf("cannot %s the document root", op.Op)
+		}
+		return replaceValue(rootValue.Elem(), op)
+	}
+
+	last := tokens[len(tokens)-1]
+	var inverse pathOp
+	err = walkPath(rootValue, tokens[:len(tokens)-1], func(parent reflect.Value) error {
+		return withContainer(parent, func(container reflect.Value) error {
+			var err error
+			inverse, err = applyToContainer(container, last, op)
+			return err
+		})
+	})
+	if err != nil {
+		return pathOp{}, fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+	}
+	return inverse, nil
+}
+
+// applyPathOps 依次执行操作并返回逆操作，失败时回滚已执行的操作
+func applyPathOps(root interface{}, ops []pathOp) ([]pathOp, error) {
+	inverse := make([]pathOp, 0, len(ops))
+	for _, op := range ops {
+		undo, err := applyPathOp(root, op)
+		if err != nil {
+			return nil, rollbackPathOps(root, inverse, err)
+		}
+		inverse = append(inverse, undo)
+	}
+	return inverse, nil
+}
+
+// applyReversed 倒序执行逆操作
+func applyReversed(root interface{}, inverse []pathOp) error {
+	for i := len(inverse) - 1; i >= 0; i-- {
+		if _, err := applyPathOp(root, inverse[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackPathOps 操作失败时撤销已执行的操作，返回原始错误
+func rollbackPathOps(root interface{}, inverse []pathOp, err error) error {
+	if rollbackErr := applyReversed(root, inverse); rollbackErr != nil {
+		return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+	}
+	return err
+}
+
+// applyToContainer 对 container 中名称为 token 的成员执行操作
+func applyToContainer(container reflect.Value, token string, op pathOp) (pathOp, error) {
+	switch container.Kind() {
+	case reflect.Struct:
+		field, err := structField(container, token)
+		if err != nil {
+			return pathOp{}, err
+		}
+		// 结构体字段总是存在，add 等同于 replace，remove 不允许
+		if op.Op == pathOpRemove {
+			return pathOp{}, fmt.Errorf("cannot remove field %q of %s", token, container.Type().Name())
+		}
+		return replaceValue(field, op)
+
+	case reflect.Slice:
+		if op.Op == pathOpAdd {
+			index := container.Len()
+			if token != "-" {
+				var err error
+				if index, err = sliceIndex(token, container.Len()+1); err != nil {
+					return pathOp{}, err
+				}
+			}
+			value, err := decodeValue(op.Value, container.Type().Elem())
+			if err != nil {
+				return pathOp{}, err
+			}
+			grown := reflect.Append(container, value)
+			reflect.Copy(grown.Slice(index+1, grown.Len()), grown.Slice(index, grown.Len()-1))
+			grown.Index(index).Set(value)
+			container.Set(grown)
+			return pathOp{Op: pathOpRemove, Path: siblingPath(op.Path, strconv.Itoa(index))}, nil
+		}
+		index, err := sliceIndex(token, container.Len())
+		if err != nil {
+			return pathOp{}, err
+		}
+		if op.Op == pathOpReplace {
+			return replaceValue(container.Index(index), op)
+		}
+		old, err := json.Marshal(container.Index(index).Interface())
+		if err != nil {
+			return pathOp{}, err
+		}
+		reflect.Copy(container.Slice(index, container.Len()), container.Slice(index+1, container.Len()))
+		container.Index(container.Len() - 1).Set(reflect.Zero(container.Type().Elem()))
+		container.SetLen(container.Len() - 1)
+		return pathOp{Op: pathOpAdd, Path: op.Path, Value: old}, nil
+
+	case reflect.Array:
+		index, err := sliceIndex(token, container.Len())
+		if err != nil {
+			return pathOp{}, err
+		}
+		if op.Op != pathOpReplace {
+			return pathOp{}, fmt.Errorf("cannot %s elements of a fixed-size array", op.Op)
+		}
+		return replaceValue(container.Index(index), op)
+
+	case reflect.Map:
+		if container.Type().Key().Kind() != reflect.String {
+			return pathOp{}, fmt.Errorf("unsupported map key type %s", container.Type().Key())
+		}
+		key := reflect.ValueOf(token).Convert(container.Type().Key())
+		existing := container.MapIndex(key)
+		if !existing.IsValid() && op.Op != pathOpAdd {
+			return pathOp{}, fmt.Errorf("key %q does not exist", token)
+		}
+
+		inverse := pathOp{Op: pathOpRemove, Path: op.Path}
+		if existing.IsValid() {
+			old, err := json.Marshal(existing.Interface())
+			if err != nil {
+				return pathOp{}, err
+			}
+			inverse = pathOp{Op: pathOpAdd, Path: op.Path, Value: old}
+		}
+		if op.Op == pathOpRemove {
+			container.SetMapIndex(key, reflect.Value{})
+			return inverse, nil
+		}
+		value, err := decodeValue(op.Value, container.Type().Elem())
+		if err != nil {
+			return pathOp{}, err
+		}
+		if container.IsNil() {
+			container.Set(reflect.MakeMap(container.Type()))
+		}
+		container.SetMapIndex(key, value)
+		return inverse, nil
+
+	default:
+		return pathOp{}, fmt.Errorf("cannot index into %s with %q", container.Type(), token)
+	}
+}
+
+// replaceValue 将 target 替换为 op.Value，返回恢复原值的操作
+func replaceValue(target reflect.Value, op pathOp) (pathOp, error) {
+	old, err := json.Marshal(target.Interface())
+	if err != nil {
+		return pathOp{}, err
+	}
+	value, err := decodeValue(op.Value, target.Type())
+	if err != nil {
+		return pathOp{}, err
+	}
+	target.Set(value)
+	return pathOp{Op: pathOpReplace, Path: op.Path, Value: old}, nil
+}
+
+// walkPath 沿路径找到目标值并调用 visit，visit 中可以修改目标
+// 接口中保存的非指针值不可寻址，会复制一份修改后再写回
+func walkPath(v reflect.Value, tokens []string, visit func(target reflect.Value) error) error {
+	if len(tokens) == 0 {
+		return visit(v)
+	}
+	return withContainer(v, func(container reflect.Value) error {
+		token := tokens[0]
+		switch container.Kind() {
+		case reflect.Struct:
+			field, err := structField(container, token)
+			if err != nil {
+				return err
+			}
+			return walkPath(field, tokens[1:], visit)
+		case reflect.Slice, reflect.Array:
+			index, err := sliceIndex(token, container.Len())
+			if err != nil {
+				return err
+			}
+			return walkPath(container.Index(index), tokens[1:], visit)
+		case reflect.Map:
+			if container.Type().Key().Kind() != reflect.String {
+				return fmt.Errorf("unsupported map key type %s", container.Type().Key())
+			}
+			key := reflect.ValueOf(token).Convert(container.Type().Key())
+			value := container.MapIndex(key)
+			if !value.IsValid() {
+				return fmt.Errorf("key %q does not exist", token)
+			}
+			element := reflect.New(value.Type()).Elem()
+			element.Set(value)
+			err := walkPath(element, tokens[1:], visit)
+			container.SetMapIndex(key, element)
+			return err
+		default:
+			return fmt.Errorf("cannot index into %s with %q", container.Type(), token)
+		}
+	})
+}
+
+// withContainer 解开指针和接口后调用 fn
+func withContainer(v reflect.Value, fn func(container reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Errorf("value is null")
+		}
+		return withContainer(v.Elem(), fn)
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("value is null")
+		}
+		element := v.Elem()
+		if element.Kind() == reflect.Ptr {
+			return withContainer(element, fn)
+		}
+		copied := reflect.New(element.Type()).Elem()
+		copied.Set(element)
+		err := withContainer(copied, fn)
+		if v.CanSet() {
+			v.Set(copied)
+		}
+		return err
+	default:
+		return fn(v)
+	}
+}
+
+// structField 按 JSON 字段名称查找结构体字段，匿名嵌入的结构体字段同样可以直接访问
+func structField(v reflect.Value, name string) (reflect.Value, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && tagName == "" {
+			embedded := v.Field(i)
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if field, err := structField(embedded, name); err == nil {
+					return field, nil
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if tagName == "" {
+			tagName = sf.Name
+		}
+		if tagName == name {
+			return v.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("%s has no field %q", t.Name(), name)
+}
+
+// sliceIndex 解析数组下标，RFC 6901 不允许前导零
+func sliceIndex(token string, length int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index >= length {
+		return 0, fmt.Errorf("array index %d out of range (length %d)", index, length)
+	}
+	return index, nil
+}
+
+// siblingPath 将路径最后一级替换为 token
+func siblingPath(pointer string, token string) string {
+	return pointer[:strings.LastIndex(pointer, "/")+1] + token
+}
+
+// decodeValue 将 JSON 值解码为类型 t
+// 多态接口（例如材质属性）根据 TypeName 选择 polymorphicFields 中登记的具体类型
+func decodeValue(raw json.RawMessage, t reflect.Type) (reflect.Value, error) {
+	if len(raw) == 0 {
+		return reflect.Value{}, fmt.Errorf("missing value")
+	}
+
+	switch {
+	case t.Kind() == reflect.Interface && t.NumMethod() > 0:
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			return reflect.Zero(t), nil
+		}
+		var header struct {
+			TypeName string `json:"TypeName"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot decode %s: %w", t, err)
+		}
+		for _, variants := range polymorphicFields {
+			for _, variant := range variants {
+				variantType := reflect.TypeOf(variant.value)
+				if variant.typeName != header.TypeName {
+					continue
+				}
+				ptr := reflect.New(variantType)
+				if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+					return reflect.Value{}, fmt.Errorf("cannot decode %s: %w", variantType.Name(), err)
+				}
+				switch {
+				case ptr.Type().Implements(t):
+					return ptr, nil
+				case variantType.Implements(t):
+					return ptr.Elem(), nil
+				}
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("unknown TypeName %q for %s", header.TypeName, t)
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Interface && t.Elem().NumMethod() > 0:
+		var elements []json.RawMessage
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot decode %s: %w", t, err)
+		}
+		if elements == nil {
+			return reflect.Zero(t), nil
+		}
+		slice := reflect.MakeSlice(t, 0, len(elements))
+		for _, element := range elements {
+			value, err := decodeValue(element, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice = reflect.Append(slice, value)
+		}
+		return slice, nil
+	}
+
+	ptr := reflect.New(t)
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ptr.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("cannot decode %s: %w", t, err)
+	}
+	return ptr.Elem(), nil
+}
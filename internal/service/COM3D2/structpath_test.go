@@ -0,0 +1,247 @@
+package COM3D2
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 测试用的文档结构，覆盖结构体、指针、切片、数组和 map
+type pathTestDoc struct {
+	Name   string            `json:"Name"`
+	Values []int32           `json:"Values"`
+	Matrix [3]float32        `json:"Matrix"`
+	Tags   map[string]string `json:"Tags"`
+	Items  []*pathTestItem   `json:"Items"`
+	Child  *pathTestItem     `json:"Child"`
+}
+
+type pathTestItem struct {
+	Key   string  `json:"Key"`
+	Value float32 `json:"Value"`
+}
+
+func newPathTestDoc() *pathTestDoc {
+	return &pathTestDoc{
+		Name:   "doc",
+		Values: []int32{1, 2, 3},
+		Matrix: [3]float32{1, 0, 0},
+		Tags:   map[string]string{"a/b": "slash", "c~d": "tilde"},
+		Items:  []*pathTestItem{{Key: "first", Value: 1}, {Key: "second", Value: 2}},
+		Child:  &pathTestItem{Key: "child", Value: 0.5},
+	}
+}
+
+// mustJson 将 v 编码为 JSON 字符串，用于比较文档
+func mustJson(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"/", []string{""}, false},
+		{"/Items/0/Key", []string{"Items", "0", "Key"}, false},
+		{"/Tags/a~1b", []string{"Tags", "a/b"}, false},
+		{"/Tags/c~0d", []string{"Tags", "c~d"}, false},
+		{"/Tags/~01", []string{"Tags", "~1"}, false},
+		{"Items", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePointer(tt.pointer)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePointer(%q) error = %v, wantErr %v", tt.pointer, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePointer(%q) = %q, want %q", tt.pointer, got, tt.want)
+		}
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr string
+	}{
+		{"/Name", `"doc"`, ""},
+		{"/Values/2", `3`, ""},
+		{"/Matrix/0", `1`, ""},
+		{"/Tags/a~1b", `"slash"`, ""},
+		{"/Items/1/Key", `"second"`, ""},
+		{"/Child/Value", `0.5`, ""},
+		{"/Values/3", "", "out of range"},
+		{"/Values/01", "", "invalid array index"},
+		{"/Values/-1", "", "invalid array index"},
+		{"/Missing", "", `no field "Missing"`},
+		{"/Tags/missing", "", `key "missing" does not exist`},
+		{"/Name/0", "", "cannot index into string"},
+	}
+	doc := newPathTestDoc()
+	for _, tt := range tests {
+		got, err := getPath(doc, tt.path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("getPath(%q) error = %v, want %q", tt.path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("getPath(%q): %v", tt.path, err)
+			continue
+		}
+		if raw := mustJson(t, got); raw != tt.want {
+			t.Errorf("getPath(%q) = %s, want %s", tt.path, raw, tt.want)
+		}
+	}
+}
+
+// 每个基本操作执行后再执行它的逆操作，文档必须恢复原样
+func TestApplyPathOpInverse(t *testing.T) {
+	tests := []struct {
+		name string
+		op   pathOp
+		want func(doc *pathTestDoc) // 对原文档做相同的修改，得到期望的结果
+	}{
+		{
+			name: "replace field",
+			op:   pathOp{Op: pathOpReplace, Path: "/Name", Value: json.RawMessage(`"renamed"`)},
+			want: func(doc *pathTestDoc) { doc.Name = "renamed" },
+		},
+		{
+			name: "add to struct field replaces it",
+			op:   pathOp{Op: pathOpAdd, Path: "/Child/Key", Value: json.RawMessage(`"new"`)},
+			want: func(doc *pathTestDoc) { doc.Child.Key = "new" },
+		},
+		{
+			name: "replace slice element",
+			op:   pathOp{Op: pathOpReplace, Path: "/Values/1", Value: json.RawMessage(`20`)},
+			want: func(doc *pathTestDoc) { doc.Values[1] = 20 },
+		},
+		{
+			name: "insert into slice",
+			op:   pathOp{Op: pathOpAdd, Path: "/Values/0", Value: json.RawMessage(`0`)},
+			want: func(doc *pathTestDoc) { doc.Values = []int32{0, 1, 2, 3} },
+		},
+		{
+			name: "append to slice",
+			op:   pathOp{Op: pathOpAdd, Path: "/Values/-", Value: json.RawMessage(`4`)},
+			want: func(doc *pathTestDoc) { doc.Values = append(doc.Values, 4) },
+		},
+		{
+			name: "remove from slice",
+			op:   pathOp{Op: pathOpRemove, Path: "/Items/0"},
+			want: func(doc *pathTestDoc) { doc.Items = doc.Items[1:] },
+		},
+		{
+			name: "replace array element",
+			op:   pathOp{Op: pathOpReplace, Path: "/Matrix/2", Value: json.RawMessage(`1.5`)},
+			want: func(doc *pathTestDoc) { doc.Matrix[2] = 1.5 },
+		},
+		{
+			name: "add map key",
+			op:   pathOp{Op: pathOpAdd, Path: "/Tags/new", Value: json.RawMessage(`"value"`)},
+			want: func(doc *pathTestDoc) { doc.Tags["new"] = "value" },
+		},
+		{
+			name: "replace map key",
+			op:   pathOp{Op: pathOpReplace, Path: "/Tags/a~1b", Value: json.RawMessage(`"changed"`)},
+			want: func(doc *pathTestDoc) { doc.Tags["a/b"] = "changed" },
+		},
+		{
+			name: "remove map key",
+			op:   pathOp{Op: pathOpRemove, Path: "/Tags/c~0d"},
+			want: func(doc *pathTestDoc) { delete(doc.Tags, "c~d") },
+		},
+		{
+			name: "replace pointer",
+			op:   pathOp{Op: pathOpReplace, Path: "/Child", Value: json.RawMessage(`null`)},
+			want: func(doc *pathTestDoc) { doc.Child = nil },
+		},
+		{
+			name: "replace root",
+			op:   pathOp{Op: pathOpReplace, Path: "", Value: json.RawMessage(`{"Name":"root"}`)},
+			want: func(doc *pathTestDoc) { *doc = pathTestDoc{Name: "root"} },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newPathTestDoc()
+			original := mustJson(t, doc)
+			expected := newPathTestDoc()
+			tt.want(expected)
+
+			inverse, err := applyPathOp(doc, tt.op)
+			if err != nil {
+				t.Fatalf("applyPathOp: %v", err)
+			}
+			if got := mustJson(t, doc); got != mustJson(t, expected) {
+				t.Errorf("after %s %s:\n got %s\nwant %s", tt.op.Op, tt.op.Path, got, mustJson(t, expected))
+			}
+			if _, err := applyPathOp(doc, inverse); err != nil {
+				t.Fatalf("inverse %s %s: %v", inverse.Op, inverse.Path, err)
+			}
+			if got := mustJson(t, doc); got != original {
+				t.Errorf("after undo:\n got %s\nwant %s", got, original)
+			}
+		})
+	}
+}
+
+func TestApplyPathOpErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      pathOp
+		wantErr string
+	}{
+		{"remove struct field", pathOp{Op: pathOpRemove, Path: "/Name"}, "cannot remove field"},
+		{"remove root", pathOp{Op: pathOpRemove, Path: ""}, "cannot remove the document root"},
+		{"add to array", pathOp{Op: pathOpAdd, Path: "/Matrix/0", Value: json.RawMessage(`1`)}, "fixed-size array"},
+		{"replace missing key", pathOp{Op: pathOpReplace, Path: "/Tags/missing", Value: json.RawMessage(`""`)}, "does not exist"},
+		{"insert past end", pathOp{Op: pathOpAdd, Path: "/Values/4", Value: json.RawMessage(`1`)}, "out of range"},
+		{"wrong type", pathOp{Op: pathOpReplace, Path: "/Values/0", Value: json.RawMessage(`"one"`)}, "cannot decode"},
+		{"unknown field in value", pathOp{Op: pathOpReplace, Path: "/Child", Value: json.RawMessage(`{"Other":1}`)}, "unknown field"},
+		{"missing value", pathOp{Op: pathOpReplace, Path: "/Name"}, "missing value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newPathTestDoc()
+			original := mustJson(t, doc)
+			_, err := applyPathOp(doc, tt.op)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("applyPathOp error = %v, want %q", err, tt.wantErr)
+			}
+			if got := mustJson(t, doc); got != original {
+				t.Errorf("failed operation changed the document:\n got %s\nwant %s", got, original)
+			}
+		})
+	}
+}
+
+// 中途失败时已执行的操作必须回滚
+func TestApplyPathOpsRollback(t *testing.T) {
+	doc := newPathTestDoc()
+	original := mustJson(t, doc)
+	_, err := applyPathOps(doc, []pathOp{
+		{Op: pathOpReplace, Path: "/Name", Value: json.RawMessage(`"changed"`)},
+		{Op: pathOpRemove, Path: "/Values/0"},
+		{Op: pathOpRemove, Path: "/Values/5"},
+	})
+	if err == nil {
+		t.Fatal("applyPathOps succeeded")
+	}
+	if got := mustJson(t, doc); got != original {
+		t.Errorf("document not rolled back:\n got %s\nwant %s", got, original)
+	}
+}
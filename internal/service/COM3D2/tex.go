@@ -1,54 +1,170 @@
 package COM3D2
 
 import (
-	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
 	"github.com/MeidoPromotionAssociation/MeidoSerialization/tools"
 	"github.com/emmansun/base64" // use faster base64 implementation
-	"os"
 	"path/filepath"
 	"strings"
 )
 
+// texFormat .tex 文件格式，只支持二进制，图片转换见 TexService
+var texFormat = registerFormat(&Format[COM3D2.Tex]{
+	fileType:  "tex",
+	signature: COM3D2.TexSignature,
+	json:      false,
+	read:      readBuffered(COM3D2.ReadTex, 1024*1024*10), // 10MB 缓冲区
+	dump:      (*COM3D2.Tex).Dump,
+	locate:    scanTex,
+})
+
+// 与 .tex 版本相关的结构变化
+const (
+	texSizeVersion  = 1010 // 名称之后带有宽度、高度和纹理格式，之前的版本数据位只能是 PNG
+	texRectsVersion = 1011 // 名称之后带有纹理图集的矩形
+)
+
+// pngHeaderLen PNG 签名和 IHDR 块中宽度、高度之前的部分
+const pngHeaderLen = 8 + 4 + 4
+
+// pngSignature PNG 文件开头的 8 个字节
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
 // TexService 专门处理 .tex 文件的读写
 type TexService struct{}
 
-// ReadTexFile 读取 .tex 文件并返回对应结构体
-func (t *TexService) ReadTexFile(path string) (*COM3D2.Tex, error) {
-	f, err := os.Open(path)
+// TexInfo .tex 中图像数据以外的内容，由 ReadTexInfo 读取
+type TexInfo struct {
+	Signature     string           `json:"Signature"`
+	Version       int32            `json:"Version"`
+	TextureName   string           `json:"TextureName"`
+	Rects         []COM3D2.TexRect `json:"Rects"`         // 1011 之前的版本没有
+	Width         int32            `json:"Width"`         // 1010 之前的版本从 PNG 数据中读取
+	Height        int32            `json:"Height"`        // 同上
+	TextureFormat int32            `json:"TextureFormat"` // 1010 之前的版本没有
+	DataSize      int32            `json:"DataSize"`      // 图像数据的字节数
+}
+
+// ReadTexInfo 只读取 .tex 文件的文件头、宽度、高度和纹理格式，不读取图像数据，适合扫描目录
+func (t *TexService) ReadTexInfo(path string) (*TexInfo, error) {
+	file, err := openFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open .tex file: %w", err)
 	}
-	defer f.Close()
+	defer file.Close()
 
-	br := bufio.NewReaderSize(f, 1024*1024*10) //10MB 缓冲区
-	TexData, err := COM3D2.ReadTex(br)
+	s := newBinaryScanner(file, -1)
+	info, err := scanTexInfo(s)
+	if err == nil && info.Signature != COM3D2.TexSignature {
+		err = fmt.Errorf("invalid .tex signature %q", info.Signature)
+	}
+	if err == nil && info.Version < texSizeVersion {
+		err = scanPngSize(s, info)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("parsing the .tex file failed: %w", err)
+		return nil, s.parseError("tex", path, err)
 	}
+	return info, nil
+}
 
-	return TexData, nil
+// scanTexInfo 读取图像数据之前的部分，停在图像数据的开头
+func scanTexInfo(s *binaryScanner) (*TexInfo, error) {
+	info := &TexInfo{}
+	var err error
+	if info.Signature, info.Version, err = scanHeader(s); err != nil {
+		return info, err
+	}
+	if info.TextureName, err = s.string("texture name"); err != nil {
+		return info, err
+	}
+	if info.Version >= texRectsVersion {
+		n, err := s.count("rect count")
+		if err != nil {
+			return info, err
+		}
+		info.Rects = make([]COM3D2.TexRect, n)
+		for i := range info.Rects {
+			for _, field := range []struct {
+				label string
+				value *float32
+			}{
+				{"x", &info.Rects[i].X},
+				{"y", &info.Rects[i].Y},
+				{"w", &info.Rects[i].W},
+				{"h", &info.Rects[i].H},
+			} {
+				if *field.value, err = s.float32(fmt.Sprintf("rect %d %s", i, field.label)); err != nil {
+					return info, err
+				}
+			}
+		}
+	}
+	if info.Version >= texSizeVersion {
+		for _, field := range []struct {
+			label string
+			value *int32
+		}{
+			{"width", &info.Width},
+			{"height", &info.Height},
+			{"texture format", &info.TextureFormat},
+		} {
+			if *field.value, err = s.int32(field.label); err != nil {
+				return info, err
+			}
+		}
+	}
+	if info.DataSize, err = s.int32("data size"); err != nil {
+		return info, err
+	}
+	if info.DataSize < 0 {
+		return info, s.errorf("negative data size %d", info.DataSize)
+	}
+	return info, nil
 }
 
-// WriteTexFile 接收 Tex 数据并写入 .tex 文件
-func (t *TexService) WriteTexFile(path string, TexData *COM3D2.Tex) error {
-	f, err := os.Create(path)
+// scanPngSize 1010 之前的版本没有宽度和高度，从 PNG 的 IHDR 块中读取（大端序）
+func scanPngSize(s *binaryScanner, info *TexInfo) error {
+	if info.DataSize < pngHeaderLen+8 {
+		return s.errorf("image data is too short for a PNG header")
+	}
+	buf, err := s.bytes("PNG header", pngHeaderLen+8)
 	if err != nil {
-		return fmt.Errorf("unable to create .tex file: %w", err)
+		return err
 	}
-	defer f.Close()
-
-	bw := bufio.NewWriter(f)
-	if err := TexData.Dump(bw); err != nil {
-		return fmt.Errorf("failed to write to .tex file: %w", err)
+	// 不是 PNG 时宽度和高度的位置没有意义
+	if !bytes.Equal(buf[:len(pngSignature)], pngSignature) {
+		return s.errorf("image data is not a PNG (starts with % x)", buf[:len(pngSignature)])
 	}
-	if err := bw.Flush(); err != nil {
-		return fmt.Errorf("an error occurred while flush bufio: %w", err)
+	if string(buf[12:pngHeaderLen]) != "IHDR" {
+		return s.errorf("PNG data does not start with an IHDR chunk")
 	}
+	info.Width = int32(binary.BigEndian.Uint32(buf[pngHeaderLen:]))
+	info.Height = int32(binary.BigEndian.Uint32(buf[pngHeaderLen+4:]))
 	return nil
 }
 
+// scanTex .tex 的结构：文件头、名称、矩形、宽度、高度、纹理格式、图像数据
+func scanTex(s *binaryScanner) error {
+	info, err := scanTexInfo(s)
+	if err != nil {
+		return err
+	}
+	return s.skip("image data", int64(info.DataSize))
+}
+
+// ReadTexFile 读取 .tex 文件并返回对应结构体
+func (t *TexService) ReadTexFile(path string) (*COM3D2.Tex, error) {
+	return texFormat.ReadFile(path)
+}
+
+// WriteTexFile 接收 Tex 数据并写入 .tex 文件
+func (t *TexService) WriteTexFile(path string, TexData *COM3D2.Tex) error {
+	return texFormat.WriteFile(path, TexData)
+}
+
 // CovertTexToImageResult 前端不接受多个返回值，因此使用结构体
 type CovertTexToImageResult struct {
 	Base64EncodedImageData string
@@ -86,11 +202,13 @@ func (t *TexService) CovertTexToImage(inputPath string, forcePng bool) (covertTe
 // 如果 forcePNG 为 true 则强制保存为 PNG，不考虑图像格式和透明通道
 // 如果是 1011 版本的 tex（纹理图集），则还会生成一个 .uv.csv 文件（例如 foo.png 对应 foo.png.uv.csv），文件内容为矩形数组 x, y, w, h 一行一组
 func (t *TexService) ConvertTexToImageAndWrite(tex *COM3D2.Tex, outputPath string, forcePng bool) error {
-	err := COM3D2.ConvertTexToImageAndWrite(tex, outputPath, forcePng)
+	outputPath, err := resolveWritePath(outputPath)
 	if err != nil {
 		return err
 	}
-	return nil
+	return writeFilesAtomicVia(outputPath, func(tmpPath string) error {
+		return COM3D2.ConvertTexToImageAndWrite(tex, tmpPath, forcePng)
+	})
 }
 
 // ConvertImageToTex 将任意 ImageMagick 支持的文件格式转换为 tex 格式，但不写出
@@ -118,11 +236,13 @@ func (t *TexService) ConvertImageToTex(inputPath string, texName string, compres
 // 如果要生成 1011 版本的 tex（纹理图集），需要在图片目录下有一个同名的 .uv.csv 文件（例如 foo.png 对应 foo.png.uv.csv），文件内容为矩形数组 x, y, w, h 一行一组，否则生成 1010 版本的 tex
 // 如果输入输出都是 .tex，则原样复制
 func (t *TexService) ConvertImageToTexAndWrite(inputPath string, texName string, compress bool, forcePNG bool, outputPath string) error {
-	err := COM3D2.ConvertImageToTexAndWrite(inputPath, texName, compress, forcePNG, outputPath)
+	outputPath, err := resolveWritePath(outputPath)
 	if err != nil {
 		return err
 	}
-	return nil
+	return writeFilesAtomicVia(outputPath, func(tmpPath string) error {
+		return COM3D2.ConvertImageToTexAndWrite(inputPath, texName, compress, forcePNG, tmpPath)
+	})
 }
 
 // ConvertAnyToPng 任意 ImageMagick 支持的格式转换为 PNG，包括 .tex
@@ -180,16 +300,17 @@ func (t *TexService) ConvertAnyToAnyAndWrite(inputPath string, texName string, c
 			return err
 		}
 
-		if forcePNG || filepath.Ext(outputPath) == "" {
-			outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".png"
-		}
-
-		err = tools.ConvertImageToImageAndWrite(inputPath, outputPath)
+		outputPath, err = resolveWritePath(outputPath)
 		if err != nil {
 			return err
 		}
+		if forcePNG || filepath.Ext(outputPath) == "" {
+			outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".png"
+		}
 
-		return nil
+		return writeFilesAtomicVia(outputPath, func(tmpPath string) error {
+			return tools.ConvertImageToImageAndWrite(inputPath, tmpPath)
+		})
 	}
 }
 
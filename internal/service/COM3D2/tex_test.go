@@ -0,0 +1,101 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixturePng 只有签名和 IHDR 块开头的 PNG 数据，足够读取宽度和高度
+func fixturePng(width uint32, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	binary.Write(&buf, binary.BigEndian, uint32(13))
+	buf.WriteString("IHDR")
+	binary.Write(&buf, binary.BigEndian, []uint32{width, height})
+	buf.Write([]byte{8, 6, 0, 0, 0})
+	return buf.Bytes()
+}
+
+// fixtureTex 按版本构造 .tex，1010 之前没有宽度、高度和纹理格式，1011 之前没有矩形
+func fixtureTex(version int32, rects []COM3D2.TexRect, width int32, height int32, format int32, data []byte) []byte {
+	var buf bytes.Buffer
+	appendString(&buf, COM3D2.TexSignature)
+	binary.Write(&buf, binary.LittleEndian, version)
+	appendString(&buf, "body")
+	if version >= texRectsVersion {
+		binary.Write(&buf, binary.LittleEndian, int32(len(rects)))
+		for _, rect := range rects {
+			fixtureFloats(&buf, rect.X, rect.Y, rect.W, rect.H)
+		}
+	}
+	if version >= texSizeVersion {
+		binary.Write(&buf, binary.LittleEndian, []int32{width, height, format})
+	}
+	binary.Write(&buf, binary.LittleEndian, int32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func TestReadTexInfo(t *testing.T) {
+	rects := []COM3D2.TexRect{{X: 0, Y: 0, W: 0.5, H: 1}, {X: 0.5, Y: 0, W: 0.5, H: 1}}
+	dxt := bytes.Repeat([]byte{0xab}, 64)
+	tests := []struct {
+		name string
+		data []byte
+		want TexInfo
+	}{
+		// 1000 只有 PNG 数据，宽度和高度从 IHDR 块读取
+		{"1000", fixtureTex(1000, nil, 0, 0, 0, fixturePng(512, 256)),
+			TexInfo{Signature: COM3D2.TexSignature, Version: 1000, TextureName: "body", Width: 512, Height: 256, DataSize: 29}},
+		{"1010", fixtureTex(1010, nil, 1024, 2048, 12, dxt),
+			TexInfo{Signature: COM3D2.TexSignature, Version: 1010, TextureName: "body", Width: 1024, Height: 2048, TextureFormat: 12, DataSize: 64}},
+		{"1011", fixtureTex(1011, rects, 64, 32, 5, dxt),
+			TexInfo{Signature: COM3D2.TexSignature, Version: 1011, TextureName: "body", Rects: rects, Width: 64, Height: 32, TextureFormat: 5, DataSize: 64}},
+		{"1011 without rects", fixtureTex(1011, nil, 64, 32, 5, dxt),
+			TexInfo{Signature: COM3D2.TexSignature, Version: 1011, TextureName: "body", Rects: []COM3D2.TexRect{}, Width: 64, Height: 32, TextureFormat: 5, DataSize: 64}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := (&TexService{}).ReadTexInfo(writeFixture(t, "body.tex", tt.data))
+			if err != nil {
+				t.Fatalf("ReadTexInfo: %v", err)
+			}
+			if !reflect.DeepEqual(*info, tt.want) {
+				t.Errorf("info = %+v, want %+v", *info, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTexInfoErrors(t *testing.T) {
+	notPng := fixturePng(512, 256)
+	notPng[1] = 'J'
+	noIhdr := fixturePng(512, 256)
+	copy(noIhdr[12:], "IDAT")
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not png", fixtureTex(1000, nil, 0, 0, 0, notPng), "image data is not a PNG"},
+		{"no IHDR", fixtureTex(1000, nil, 0, 0, 0, noIhdr), "does not start with an IHDR chunk"},
+		{"short png", fixtureTex(1000, nil, 0, 0, 0, pngSignature), "too short for a PNG header"},
+		{"truncated", fixtureTex(1011, nil, 64, 32, 5, nil)[:20], "unexpected EOF"},
+		{"signature", fixtureMenu(), `invalid .tex signature "CM3D2_MENU"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&TexService{}).ReadTexInfo(writeFixture(t, "body.tex", tt.data))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadTexInfo error = %v, want a *ParseError containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package COM3D2
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 虚拟路径用于直接读取压缩包内的文件，格式为 压缩包路径!/内部路径，例如 GameData/mod.zip!/menu/foo.menu
+// 目前只支持 zip，游戏的 .arc 压缩包需要等 .arc 的读取实现之后才能打开
+// 所有 Read*File 都通过 openFile 打开文件，因此都支持虚拟路径
+// 压缩包内的文件只读，写入虚拟路径时会被拒绝，或者在设置了 archiveOutputDir 时重定向到该目录
+
+// ArchiveSeparator 虚拟路径中压缩包路径与内部路径的分隔符
+const ArchiveSeparator = "!/"
+
+// ErrArchiveReadOnly 尝试写入压缩包内的路径
+var ErrArchiveReadOnly = errors.New("files inside an archive are read-only")
+
+// ErrArcUnsupported 游戏的 .arc 压缩包还没有读取实现，需要先用其他工具解包
+var ErrArcUnsupported = fmt.Errorf("%w: .arc archives cannot be opened yet, extract them with an ARC tool first", ErrUnsupportedFileType)
+
+// ArchiveFS 打开的压缩包，使用完毕后需要关闭
+type ArchiveFS interface {
+	fs.FS
+	io.Closer
+}
+
+// archiveOpeners 按扩展名注册的压缩包打开方式，新增压缩包格式时在这里注册
+// .arc 也在这里登记，这样 archive.arc!/menu/foo.menu 会返回 ErrArcUnsupported，而不是被当作不存在的普通路径
+var archiveOpeners = map[string]func(path string) (ArchiveFS, error){
+	".zip": openZipArchive,
+	".arc": openArcArchive,
+}
+
+var (
+	archiveOutputDirMu sync.RWMutex
+	archiveOutputDir   string // 写入压缩包路径时重定向到的目录，为空时拒绝写入
+)
+
+// ArchiveEntry 压缩包内的一个文件
+type ArchiveEntry struct {
+	Path     string `json:"Path"`     // 虚拟路径，可以直接传给 Read*File
+	Name     string `json:"Name"`     // 压缩包内的路径
+	Size     int64  `json:"Size"`     // 解压后的大小
+	FileType string `json:"FileType"` // 根据扩展名判断的文件类型，无法判断时为空
+}
+
+// vfsFile 打开的只读文件，压缩包内的文件会读入内存以支持 Seek
+type vfsFile interface {
+	io.ReadSeeker
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// memFile 读入内存的压缩包内文件
+type memFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (m *memFile) Close() error               { return nil }
+func (m *memFile) Stat() (fs.FileInfo, error) { return m.info, nil }
+
+// openZipArchive 打开 zip 压缩包
+func openZipArchive(path string) (ArchiveFS, error) {
+	return zip.OpenReader(path)
+}
+
+// openArcArchive .arc 还不能读取，见 ErrArcUnsupported
+func openArcArchive(path string) (ArchiveFS, error) {
+	return nil, ErrArcUnsupported
+}
+
+// IsArchivePath 判断是否为压缩包内的虚拟路径
+func IsArchivePath(path string) bool {
+	_, _, ok := splitArchivePath(path)
+	return ok
+}
+
+// splitArchivePath 拆分虚拟路径，返回压缩包路径和压缩包内的路径
+// 只有分隔符前的部分是已注册的压缩包格式时才视为虚拟路径
+func splitArchivePath(p string) (archivePath string, innerPath string, ok bool) {
+	normalized := strings.ReplaceAll(p, "!\\", ArchiveSeparator)
+	searchFrom := 0
+	for {
+		i := strings.Index(normalized[searchFrom:], ArchiveSeparator)
+		if i < 0 {
+			return "", "", false
+		}
+		i += searchFrom
+		candidate := p[:i]
+		if _, registered := archiveOpeners[strings.ToLower(filepath.Ext(candidate))]; registered {
+			inner := strings.ReplaceAll(normalized[i+len(ArchiveSeparator):], "\\", "/")
+			inner = strings.TrimPrefix(path.Clean("/"+inner), "/")
+			return candidate, inner, true
+		}
+		searchFrom = i + len(ArchiveSeparator)
+	}
+}
+
+// openArchive 根据扩展名打开压缩包
+func openArchive(archivePath string) (ArchiveFS, error) {
+	opener, ok := archiveOpeners[strings.ToLower(filepath.Ext(archivePath))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported archive type: %s", archivePath)
+	}
+	archive, err := opener(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open archive %s: %w", archivePath, err)
+	}
+	return archive, nil
+}
+
+// openFile 打开普通路径或虚拟路径
+func openFile(p string) (vfsFile, error) {
+	archivePath, innerPath, ok := splitArchivePath(p)
+	if !ok {
+		return os.Open(p)
+	}
+
+	archive, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	f, err := archive.Open(innerPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", p)
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from archive: %w", innerPath, err)
+	}
+	return &memFile{Reader: bytes.NewReader(data), info: info}, nil
+}
+
+// resolveWritePath 检查写入路径，普通路径原样返回
+// 虚拟路径在设置了输出目录时重定向到 输出目录/内部路径，否则返回 ErrArchiveReadOnly
+func resolveWritePath(p string) (string, error) {
+	_, innerPath, ok := splitArchivePath(p)
+	if !ok {
+		return p, nil
+	}
+
+	archiveOutputDirMu.RLock()
+	outputDir := archiveOutputDir
+	archiveOutputDirMu.RUnlock()
+	if outputDir == "" {
+		return "", fmt.Errorf("%w: %s", ErrArchiveReadOnly, p)
+	}
+
+	target := filepath.Join(outputDir, filepath.FromSlash(innerPath))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("unable to create output directory: %w", err)
+	}
+	return target, nil
+}
+
+// OpenFile 打开普通路径或压缩包内的虚拟路径，只读
+func OpenFile(p string) (io.ReadSeekCloser, error) {
+	return openFile(p)
+}
+
+// StatFile 获取普通路径或虚拟路径的文件信息
+func StatFile(p string) (fs.FileInfo, error) {
+	archivePath, innerPath, ok := splitArchivePath(p)
+	if !ok {
+		return os.Stat(p)
+	}
+
+	archive, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	return fs.Stat(archive, innerPath)
+}
+
+// ListArchive 列出压缩包内的所有文件，返回的 Path 可以直接传给 Read*File
+func (m *CommonService) ListArchive(archivePath string) ([]ArchiveEntry, error) {
+	archive, err := openArchive(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var entries []ArchiveEntry
+	err = fs.WalkDir(archive, ".", func(p string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := ArchiveEntry{
+			Path: archivePath + ArchiveSeparator + p,
+			Name: p,
+			Size: info.Size(),
+		}
+		if f, ok := FormatByPath(p); ok {
+			entry.FileType = f.FileType()
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive %s: %w", archivePath, err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// SetArchiveOutputDir 设置写入压缩包路径时重定向到的目录，为空时拒绝写入压缩包路径
+func (m *CommonService) SetArchiveOutputDir(dir string) {
+	archiveOutputDirMu.Lock()
+	defer archiveOutputDirMu.Unlock()
+	archiveOutputDir = dir
+}
+
+// GetArchiveOutputDir 获取写入压缩包路径时重定向到的目录
+func (m *CommonService) GetArchiveOutputDir() string {
+	archiveOutputDirMu.RLock()
+	defer archiveOutputDirMu.RUnlock()
+	return archiveOutputDir
+}
@@ -0,0 +1,199 @@
+package COM3D2
+
+import (
+	"archive/zip"
+	"errors"
+	"github.com/MeidoPromotionAssociation/MeidoSerialization/serialization/COM3D2"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeZipFixture 写出包含 files 的 zip，返回路径
+func writeZipFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mod.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(fw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setArchiveOutputDir 在测试期间设置 archiveOutputDir，结束后恢复
+func setArchiveOutputDir(t *testing.T, dir string) {
+	t.Helper()
+	common := &CommonService{}
+	previous := common.GetArchiveOutputDir()
+	common.SetArchiveOutputDir(dir)
+	t.Cleanup(func() { common.SetArchiveOutputDir(previous) })
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		archive string
+		inner   string
+		ok      bool
+	}{
+		{"mod.zip!/menu/foo.menu", "mod.zip", "menu/foo.menu", true},
+		{"GameData/Mod.ZIP!/a/b/c/deep.mate", "GameData/Mod.ZIP", "a/b/c/deep.mate", true},
+		{`C:\mods\mod.zip!\menu\foo.menu`, `C:\mods\mod.zip`, "menu/foo.menu", true},
+		{"mod.zip!/menu//./foo.menu", "mod.zip", "menu/foo.menu", true},
+		{"mod.zip!/../../outside.menu", "mod.zip", "outside.menu", true},
+		{"wow!/mod.zip!/foo.menu", "wow!/mod.zip", "foo.menu", true},
+		{"mod.zip!/inner.zip!/foo.menu", "mod.zip", "inner.zip!/foo.menu", true},
+		{"game.arc!/menu/foo.menu", "game.arc", "menu/foo.menu", true},
+		{"mod.zip!/", "mod.zip", "", true},
+		{"mod.rar!/foo.menu", "", "", false},
+		{"mod.zip/foo.menu", "", "", false},
+		{"foo.menu", "", "", false},
+	}
+	for _, tt := range tests {
+		archive, inner, ok := splitArchivePath(tt.path)
+		if archive != tt.archive || inner != tt.inner || ok != tt.ok {
+			t.Errorf("splitArchivePath(%q) = %q, %q, %v, want %q, %q, %v", tt.path, archive, inner, ok, tt.archive, tt.inner, tt.ok)
+		}
+		if IsArchivePath(tt.path) != tt.ok {
+			t.Errorf("IsArchivePath(%q) = %v", tt.path, !tt.ok)
+		}
+	}
+}
+
+func TestOpenFileInZip(t *testing.T) {
+	archive := writeZipFixture(t, map[string]string{
+		"menu/foo.menu":     "menu data",
+		"a/b/c/deep.mate":   "mate data",
+		"texture/readme.md": "text",
+	})
+
+	for inner, want := range map[string]string{"menu/foo.menu": "menu data", "a/b/c/deep.mate": "mate data"} {
+		path := archive + ArchiveSeparator + inner
+		f, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile(%s): %v", path, err)
+		}
+		// 压缩包内的文件读入内存，支持 Seek
+		if _, err := f.Seek(1, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(got) != want[1:] {
+			t.Errorf("read %s = %q, %v, want %q", path, got, err, want[1:])
+		}
+
+		info, err := StatFile(path)
+		if err != nil || info.Size() != int64(len(want)) {
+			t.Errorf("StatFile(%s) = %v, %v", path, info, err)
+		}
+	}
+
+	for _, inner := range []string{"menu/missing.menu", "a/b", "missing.zip!/foo.menu"} {
+		if f, err := OpenFile(archive + ArchiveSeparator + inner); err == nil {
+			f.Close()
+			t.Errorf("OpenFile of %s succeeded", inner)
+		}
+	}
+	if _, err := OpenFile(filepath.Join(t.TempDir(), "missing.zip") + ArchiveSeparator + "foo.menu"); err == nil {
+		t.Error("OpenFile in a missing archive succeeded")
+	}
+	if _, err := OpenFile("game.arc" + ArchiveSeparator + "foo.menu"); !errors.Is(err, ErrArcUnsupported) {
+		t.Errorf("OpenFile in an .arc error = %v, want %v", err, ErrArcUnsupported)
+	}
+}
+
+func TestListArchive(t *testing.T) {
+	archive := writeZipFixture(t, map[string]string{
+		"menu/foo.menu":     "menu data",
+		"a/b/c/deep.mate":   "mate data",
+		"texture/readme.md": "text",
+	})
+	entries, err := (&CommonService{}).ListArchive(archive)
+	if err != nil {
+		t.Fatalf("ListArchive: %v", err)
+	}
+	want := []ArchiveEntry{
+		{Path: archive + "!/a/b/c/deep.mate", Name: "a/b/c/deep.mate", Size: 9, FileType: "mate"},
+		{Path: archive + "!/menu/foo.menu", Name: "menu/foo.menu", Size: 9, FileType: "menu"},
+		{Path: archive + "!/texture/readme.md", Name: "texture/readme.md", Size: 4},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ListArchive = %+v, want %+v", entries, want)
+	}
+}
+
+func TestResolveWritePath(t *testing.T) {
+	plain := filepath.Join(t.TempDir(), "foo.menu")
+
+	setArchiveOutputDir(t, "")
+	if got, err := resolveWritePath(plain); err != nil || got != plain {
+		t.Errorf("resolveWritePath(%q) = %q, %v", plain, got, err)
+	}
+	if _, err := resolveWritePath("mod.zip!/menu/foo.menu"); !errors.Is(err, ErrArchiveReadOnly) {
+		t.Errorf("write into an archive error = %v, want %v", err, ErrArchiveReadOnly)
+	}
+
+	outputDir := t.TempDir()
+	setArchiveOutputDir(t, outputDir)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"mod.zip!/menu/foo.menu", filepath.Join(outputDir, "menu", "foo.menu")},
+		{"mod.zip!/a/b/c/deep.mate", filepath.Join(outputDir, "a", "b", "c", "deep.mate")},
+		{"mod.zip!/../../outside.menu", filepath.Join(outputDir, "outside.menu")},
+	}
+	for _, tt := range tests {
+		got, err := resolveWritePath(tt.path)
+		if err != nil || got != tt.want {
+			t.Errorf("resolveWritePath(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+			continue
+		}
+		if info, err := os.Stat(filepath.Dir(got)); err != nil || !info.IsDir() {
+			t.Errorf("output directory for %s was not created", tt.path)
+		}
+	}
+}
+
+// 写入压缩包内的路径时重定向到输出目录，压缩包本身不变
+func TestWriteFileRedirectsArchivePath(t *testing.T) {
+	archive := writeZipFixture(t, map[string]string{"preset/foo.preset.json": "{}"})
+	before, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	setArchiveOutputDir(t, outputDir)
+
+	preset := &COM3D2.Preset{Signature: COM3D2.PresetSignature, Version: 2000}
+	if err := presetFormat.WriteFile(archive+"!/preset/foo.preset.json", preset); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	got, err := presetFormat.ReadFile(filepath.Join(outputDir, "preset", "foo.preset.json"))
+	if err != nil {
+		t.Fatalf("redirected file: %v", err)
+	}
+	if got.Signature != preset.Signature || got.Version != preset.Version {
+		t.Errorf("redirected file = %+v", got)
+	}
+	if after, err := os.ReadFile(archive); err != nil || string(after) != string(before) {
+		t.Error("the archive was modified")
+	}
+}
@@ -0,0 +1,227 @@
+package COM3D2
+
+import (
+	"context"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 监视已打开的文件在磁盘上的变化，例如在文本编辑器中修改了正在编辑的 .mate.json
+// Linux 使用 inotify 监视文件所在目录（这样编辑器先写临时文件再重命名覆盖的保存方式也能识别），其他平台定时检查修改时间，见 watcher_linux.go、watcher_other.go
+// 本程序自己写入的文件不会产生事件，见 recordOwnWrite
+
+// FileChangedEvent 文件在磁盘上被修改、删除或重命名时发送给前端的事件名称，数据为 FileChangeEvent
+const FileChangedEvent = "file-changed"
+
+// 文件变化类型
+const (
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+	FileRenamed  = "renamed" // 被重命名或移走，能确定新路径时 NewPath 不为空
+)
+
+// watchDebounce 合并短时间内的多个事件，编辑器保存一次通常会产生多个事件
+const watchDebounce = 200 * time.Millisecond
+
+// FileChangeEvent 文件变化
+type FileChangeEvent struct {
+	Path    string `json:"Path"`
+	Change  string `json:"Change"` // modified/deleted/renamed，见顶部常量定义
+	NewPath string `json:"NewPath"`
+}
+
+// WatcherService 监视文件在磁盘上的变化
+// 通过 DocumentService 打开的文件会自动监视，直接使用 ReadXFile 读取的文件需要调用 WatchFile
+type WatcherService struct{}
+
+// watchBackend 平台相关的监视实现，发现变化时调用 fileWatcher.handle
+type watchBackend interface {
+	add(path string) error
+	remove(path string)
+}
+
+// fileStamp 用于判断文件内容是否变化
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// equal 修改时间和大小都相同
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+// watcher 所有服务共用的监视器
+type watcher struct {
+	mu        sync.Mutex
+	backend   watchBackend
+	files     map[string]int // 绝对路径 → 引用计数，同一个文件可能被多处打开
+	ownWrites map[string]fileStamp
+	pending   map[string]FileChangeEvent
+	timer     *time.Timer
+	listeners []func(FileChangeEvent)
+}
+
+// fileWatcher 包级监视器，后端在第一次监视文件时创建
+var fileWatcher = &watcher{
+	files:     map[string]int{},
+	ownWrites: map[string]fileStamp{},
+	pending:   map[string]FileChangeEvent{},
+}
+
+// Startup 保存 wails 上下文，文件变化时发送 FileChangedEvent 事件
+func (w *WatcherService) Startup(ctx context.Context) {
+	fileWatcher.addListener(func(event FileChangeEvent) {
+		runtime.EventsEmit(ctx, FileChangedEvent, event)
+	})
+}
+
+// WatchFile 开始监视文件，压缩包内的文件无法监视
+func (w *WatcherService) WatchFile(path string) error {
+	return fileWatcher.watch(path)
+}
+
+// UnwatchFile 停止监视文件，与 WatchFile 成对调用
+func (w *WatcherService) UnwatchFile(path string) {
+	fileWatcher.unwatch(path)
+}
+
+// WatchedFiles 返回正在监视的文件
+func (w *WatcherService) WatchedFiles() []string {
+	fileWatcher.mu.Lock()
+	defer fileWatcher.mu.Unlock()
+	files := make([]string, 0, len(fileWatcher.files))
+	for path := range fileWatcher.files {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// addListener 添加事件接收者
+func (w *watcher) addListener(listener func(FileChangeEvent)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, listener)
+}
+
+// watch 增加文件的引用计数，第一次监视时交给后端
+func (w *watcher) watch(path string) error {
+	if IsArchivePath(path) {
+		return fmt.Errorf("cannot watch %s: files inside archives are not watched", path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.files[path] > 0 {
+		w.files[path]++
+		return nil
+	}
+	if w.backend == nil {
+		if w.backend, err = newWatchBackend(w); err != nil {
+			return fmt.Errorf("cannot start file watcher: %w", err)
+		}
+	}
+	if err := w.backend.add(path); err != nil {
+		return fmt.Errorf("cannot watch %s: %w", path, err)
+	}
+	w.files[path] = 1
+	return nil
+}
+
+// unwatch 减少文件的引用计数，计数为 0 时停止监视
+func (w *watcher) unwatch(path string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.files[path] == 0 {
+		return
+	}
+	w.files[path]--
+	if w.files[path] == 0 {
+		delete(w.files, path)
+		delete(w.ownWrites, path)
+		w.backend.remove(path)
+	}
+}
+
+// recordOwnWrite 记录本程序写入后的文件状态，之后的修改事件与之相同时忽略
+func recordOwnWrite(path string) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	fileWatcher.mu.Lock()
+	defer fileWatcher.mu.Unlock()
+	if fileWatcher.files[path] == 0 {
+		return
+	}
+	if stamp, ok := statStamp(path); ok {
+		fileWatcher.ownWrites[path] = stamp
+	}
+}
+
+// handle 由后端调用，只处理正在监视的文件，事件在 watchDebounce 后合并发送
+func (w *watcher) handle(event FileChangeEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.files[event.Path] == 0 {
+		return
+	}
+	w.pending[event.Path] = event
+	if w.timer == nil {
+		w.timer = time.AfterFunc(watchDebounce, w.flush)
+	}
+}
+
+// flush 发送合并后的事件
+func (w *watcher) flush() {
+	w.mu.Lock()
+	events := make([]FileChangeEvent, 0, len(w.pending))
+	for path, event := range w.pending {
+		if event.Change == FileModified {
+			stamp, exists := statStamp(path)
+			if !exists {
+				event.Change = FileDeleted
+			} else if own, ok := w.ownWrites[path]; ok && own.equal(stamp) {
+				continue
+			}
+		}
+		events = append(events, event)
+	}
+	w.pending = map[string]FileChangeEvent{}
+	w.timer = nil
+	listeners := append([]func(FileChangeEvent){}, w.listeners...)
+	w.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	for _, event := range events {
+		for _, listener := range listeners {
+			listener(event)
+		}
+	}
+}
+
+// statStamp 返回文件当前的状态，文件不存在时返回 false
+func statStamp(path string) (fileStamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, false
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, true
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package COM3D2
+
+import (
+	"bytes"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask 监视目录时关心的事件
+// 写入完成、移入（编辑器重命名覆盖）视为修改，移出视为重命名，删除视为删除
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_DELETE
+
+// inotifyBackend 使用 inotify 监视文件所在的目录，同一目录只添加一次
+type inotifyBackend struct {
+	fd      int
+	watcher *watcher
+
+	mu       sync.Mutex
+	dirs     map[string]int // 目录 → 监视描述符
+	dirCount map[string]int // 目录 → 其中被监视的文件数量
+	wdDirs   map[int]string // 监视描述符 → 目录
+}
+
+// newWatchBackend 创建 inotify 实例并开始读取事件，读取协程在程序退出前一直运行
+func newWatchBackend(w *watcher) (watchBackend, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	b := &inotifyBackend{
+		fd:       fd,
+		watcher:  w,
+		dirs:     map[string]int{},
+		dirCount: map[string]int{},
+		wdDirs:   map[int]string{},
+	}
+	go b.readEvents()
+	return b, nil
+}
+
+func (b *inotifyBackend) add(path string) error {
+	dir := filepath.Dir(path)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dirCount[dir] == 0 {
+		wd, err := syscall.InotifyAddWatch(b.fd, dir, inotifyMask)
+		if err != nil {
+			return err
+		}
+		b.dirs[dir] = wd
+		b.wdDirs[wd] = dir
+	}
+	b.dirCount[dir]++
+	return nil
+}
+
+func (b *inotifyBackend) remove(path string) {
+	dir := filepath.Dir(path)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.dirCount[dir] == 0 {
+		return
+	}
+	b.dirCount[dir]--
+	if b.dirCount[dir] == 0 {
+		wd := b.dirs[dir]
+		syscall.InotifyRmWatch(b.fd, uint32(wd))
+		delete(b.dirCount, dir)
+		delete(b.dirs, dir)
+		delete(b.wdDirs, wd)
+	}
+}
+
+// readEvents 读取并转换 inotify 事件
+// 同一次读取中 IN_MOVED_FROM 和 IN_MOVED_TO 按 cookie 配对，得到重命名后的路径
+func (b *inotifyBackend) readEvents() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := syscall.Read(b.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			return
+		}
+
+		movedFrom := map[uint32]string{}
+		var movedOrder []uint32
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+
+			b.mu.Lock()
+			dir, ok := b.wdDirs[int(raw.Wd)]
+			if raw.Mask&syscall.IN_IGNORED != 0 {
+				delete(b.wdDirs, int(raw.Wd))
+			}
+			b.mu.Unlock()
+			if !ok || raw.Len == 0 {
+				continue
+			}
+			path := filepath.Join(dir, string(bytes.TrimRight(nameBytes, "\x00")))
+
+			switch {
+			case raw.Mask&syscall.IN_MOVED_FROM != 0:
+				movedFrom[raw.Cookie] = path
+				movedOrder = append(movedOrder, raw.Cookie)
+			case raw.Mask&syscall.IN_MOVED_TO != 0:
+				if from, ok := movedFrom[raw.Cookie]; ok {
+					delete(movedFrom, raw.Cookie)
+					b.watcher.handle(FileChangeEvent{Path: from, Change: FileRenamed, NewPath: path})
+				}
+				b.watcher.handle(FileChangeEvent{Path: path, Change: FileModified})
+			case raw.Mask&syscall.IN_DELETE != 0:
+				b.watcher.handle(FileChangeEvent{Path: path, Change: FileDeleted})
+			case raw.Mask&syscall.IN_CLOSE_WRITE != 0:
+				b.watcher.handle(FileChangeEvent{Path: path, Change: FileModified})
+			}
+		}
+
+		// 移到了未监视的目录，无法确定新路径
+		for _, cookie := range movedOrder {
+			if from, ok := movedFrom[cookie]; ok {
+				b.watcher.handle(FileChangeEvent{Path: from, Change: FileRenamed})
+			}
+		}
+	}
+}
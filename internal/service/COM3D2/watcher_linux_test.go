@@ -0,0 +1,123 @@
+//go:build linux
+
+package COM3D2
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// inotifyWait 等待 inotify 事件经过合并后发送的时间
+const inotifyWait = time.Second
+
+// newInotifyWatcher 创建使用 inotify 的监视器并监视 paths
+func newInotifyWatcher(t *testing.T, paths ...string) chan FileChangeEvent {
+	t.Helper()
+	w, events := newTestWatcher(nil)
+	backend, err := newWatchBackend(w)
+	if err != nil {
+		t.Fatalf("newWatchBackend: %v", err)
+	}
+	w.backend = backend
+	for _, path := range paths {
+		if err := w.watch(path); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { w.unwatch(path) })
+	}
+	return events
+}
+
+func TestInotifyEvents(t *testing.T) {
+	dir := t.TempDir()
+	modified := filepath.Join(dir, "modified.menu")
+	replaced := filepath.Join(dir, "replaced.menu")
+	renamed := filepath.Join(dir, "renamed.menu")
+	deleted := filepath.Join(dir, "deleted.menu")
+	for _, path := range []string{modified, replaced, renamed, deleted} {
+		if err := os.WriteFile(path, []byte("menu"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := newInotifyWatcher(t, modified, replaced, renamed, deleted)
+
+	if err := os.WriteFile(modified, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 编辑器先写临时文件再重命名覆盖
+	temp := filepath.Join(dir, ".replaced.menu.tmp")
+	if err := os.WriteFile(temp, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(temp, replaced); err != nil {
+		t.Fatal(err)
+	}
+	newPath := filepath.Join(dir, "new.menu")
+	if err := os.Rename(renamed, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(deleted); err != nil {
+		t.Fatal(err)
+	}
+
+	got := receiveEvents(t, events, inotifyWait)
+	want := []FileChangeEvent{
+		{Path: deleted, Change: FileDeleted},
+		{Path: modified, Change: FileModified},
+		{Path: renamed, Change: FileRenamed, NewPath: newPath},
+		{Path: replaced, Change: FileModified},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+// 移到未监视的目录时无法确定新路径
+func TestInotifyMoveOut(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "item.menu")
+	if err := os.WriteFile(path, []byte("menu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := newInotifyWatcher(t, path)
+
+	if err := os.Rename(path, filepath.Join(t.TempDir(), "item.menu")); err != nil {
+		t.Fatal(err)
+	}
+	got := receiveEvents(t, events, inotifyWait)
+	if want := []FileChangeEvent{{Path: path, Change: FileRenamed}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+// 同一目录中的多个文件共用一个 inotify 监视，最后一个文件取消监视后移除
+func TestInotifyDirectoryCount(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := newTestWatcher(nil)
+	backend, err := newWatchBackend(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := backend.(*inotifyBackend)
+	a, c := filepath.Join(dir, "a.menu"), filepath.Join(dir, "c.menu")
+	for _, path := range []string{a, c} {
+		if err := b.add(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(b.dirs) != 1 || b.dirCount[dir] != 2 {
+		t.Errorf("dirs = %v, count = %v", b.dirs, b.dirCount)
+	}
+	b.remove(a)
+	if b.dirCount[dir] != 1 {
+		t.Errorf("count after removing one file = %d", b.dirCount[dir])
+	}
+	b.remove(c)
+	b.remove(c)
+	if len(b.dirs) != 0 || len(b.dirCount) != 0 {
+		t.Errorf("dirs = %v, count = %v", b.dirs, b.dirCount)
+	}
+}
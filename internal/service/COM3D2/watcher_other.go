@@ -0,0 +1,69 @@
+//go:build !linux
+
+package COM3D2
+
+import (
+	"sync"
+	"time"
+)
+
+// pollInterval 检查文件修改时间的间隔
+const pollInterval = time.Second
+
+// pollBackend 定时检查文件的修改时间和大小，无法区分重命名和删除，都报告为删除
+type pollBackend struct {
+	watcher *watcher
+
+	mu    sync.Mutex
+	files map[string]fileStamp
+	found map[string]bool // 上次检查时文件是否存在
+}
+
+// newWatchBackend 创建轮询后端，检查协程在程序退出前一直运行
+func newWatchBackend(w *watcher) (watchBackend, error) {
+	b := &pollBackend{watcher: w, files: map[string]fileStamp{}, found: map[string]bool{}}
+	go b.poll()
+	return b, nil
+}
+
+func (b *pollBackend) add(path string) error {
+	stamp, exists := statStamp(path)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files[path] = stamp
+	b.found[path] = exists
+	return nil
+}
+
+func (b *pollBackend) remove(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.files, path)
+	delete(b.found, path)
+}
+
+// poll 定时比较文件状态
+func (b *pollBackend) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		var events []FileChangeEvent
+		b.mu.Lock()
+		for path, previous := range b.files {
+			stamp, exists := statStamp(path)
+			switch {
+			case !exists && b.found[path]:
+				events = append(events, FileChangeEvent{Path: path, Change: FileDeleted})
+			case exists && (!b.found[path] || !stamp.equal(previous)):
+				events = append(events, FileChangeEvent{Path: path, Change: FileModified})
+			}
+			b.files[path] = stamp
+			b.found[path] = exists
+		}
+		b.mu.Unlock()
+
+		for _, event := range events {
+			b.watcher.handle(event)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package COM3D2
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend 记录 add 和 remove 的调用，事件由测试直接交给 watcher.handle
+type fakeBackend struct {
+	mu      sync.Mutex
+	added   []string
+	removed []string
+}
+
+func (b *fakeBackend) add(path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.added = append(b.added, path)
+	return nil
+}
+
+func (b *fakeBackend) remove(path string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removed = append(b.removed, path)
+}
+
+// newTestWatcher 创建使用 backend 的监视器，返回接收事件的通道
+func newTestWatcher(backend watchBackend) (*watcher, chan FileChangeEvent) {
+	w := &watcher{
+		backend:   backend,
+		files:     map[string]int{},
+		ownWrites: map[string]fileStamp{},
+		pending:   map[string]FileChangeEvent{},
+	}
+	events := make(chan FileChangeEvent, 16)
+	w.addListener(func(event FileChangeEvent) { events <- event })
+	return w, events
+}
+
+// receiveEvents 接收 watchDebounce 之后发送的事件，直到一段时间内没有新事件
+func receiveEvents(t *testing.T, events chan FileChangeEvent, wait time.Duration) []FileChangeEvent {
+	t.Helper()
+	var received []FileChangeEvent
+	timeout := time.After(wait)
+	for {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-timeout:
+			return received
+		}
+	}
+}
+
+func TestWatcherReferenceCount(t *testing.T) {
+	backend := &fakeBackend{}
+	w, _ := newTestWatcher(backend)
+	path := filepath.Join(t.TempDir(), "item.menu")
+
+	for i := 0; i < 2; i++ {
+		if err := w.watch(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.watch("mod.zip!/item.menu"); err == nil {
+		t.Error("watching a path inside an archive succeeded")
+	}
+	w.unwatch(path)
+	if len(backend.removed) != 0 {
+		t.Error("unwatch removed a file that is still watched")
+	}
+	w.unwatch(path)
+	w.unwatch(path)
+	if !reflect.DeepEqual(backend.added, []string{path}) || !reflect.DeepEqual(backend.removed, []string{path}) {
+		t.Errorf("added %v, removed %v", backend.added, backend.removed)
+	}
+	if len(w.files) != 0 {
+		t.Errorf("files = %v", w.files)
+	}
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	w, events := newTestWatcher(&fakeBackend{})
+	dir := t.TempDir()
+	watched := filepath.Join(dir, "b.menu")
+	deleted := filepath.Join(dir, "a.menu")
+	if err := os.WriteFile(watched, []byte("menu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{watched, deleted} {
+		if err := w.watch(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 同一文件的多个事件合并为最后一个，未监视的文件忽略，修改事件发送时文件不存在视为删除
+	w.handle(FileChangeEvent{Path: watched, Change: FileRenamed})
+	w.handle(FileChangeEvent{Path: watched, Change: FileModified})
+	w.handle(FileChangeEvent{Path: deleted, Change: FileModified})
+	w.handle(FileChangeEvent{Path: filepath.Join(dir, "other.menu"), Change: FileModified})
+
+	got := receiveEvents(t, events, watchDebounce*3)
+	want := []FileChangeEvent{
+		{Path: deleted, Change: FileDeleted},
+		{Path: watched, Change: FileModified},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+// 本程序写入后的修改事件不发送，之后其他程序的修改照常发送
+func TestWatcherIgnoresOwnWrites(t *testing.T) {
+	w, events := newTestWatcher(&fakeBackend{})
+	path := filepath.Join(t.TempDir(), "item.menu")
+	if err := os.WriteFile(path, []byte("menu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.watch(path); err != nil {
+		t.Fatal(err)
+	}
+	stamp, _ := statStamp(path)
+	w.ownWrites[path] = stamp
+
+	w.handle(FileChangeEvent{Path: path, Change: FileModified})
+	if got := receiveEvents(t, events, watchDebounce*3); len(got) != 0 {
+		t.Errorf("events after own write = %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte("changed by another program"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.handle(FileChangeEvent{Path: path, Change: FileModified})
+	if got := receiveEvents(t, events, watchDebounce*3); len(got) != 1 || got[0].Change != FileModified {
+		t.Errorf("events after external write = %+v", got)
+	}
+}
+
+func TestRecordOwnWriteOnlyWatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "item.menu")
+	if err := os.WriteFile(path, []byte("menu"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	recordOwnWrite(path)
+	fileWatcher.mu.Lock()
+	_, recorded := fileWatcher.ownWrites[path]
+	fileWatcher.mu.Unlock()
+	if recorded {
+		t.Error("recorded a write to a file that is not watched")
+	}
+
+	if err := fileWatcher.watch(path); err != nil {
+		t.Fatal(err)
+	}
+	defer fileWatcher.unwatch(path)
+	recordOwnWrite(path)
+	fileWatcher.mu.Lock()
+	_, recorded = fileWatcher.ownWrites[path]
+	fileWatcher.mu.Unlock()
+	if !recorded {
+		t.Error("a write to a watched file was not recorded")
+	}
+}
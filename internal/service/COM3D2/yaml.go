@@ -0,0 +1,274 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML 是 JSON 之外的另一种文本格式，例如 .menu.yaml、.mate.yml
+// 转换时先按 JSON 编码，再将 JSON 逐个 token 映射为 YAML 节点，读取时反过来
+// 这样结构体的自定义 JSON 编码（例如多态的属性列表和碰撞体列表）同样适用于 YAML，字段顺序和数字的写法也保持不变
+
+// 文本格式的后缀
+const (
+	jsonSuffix = ".json"
+	yamlSuffix = ".yaml"
+	ymlSuffix  = ".yml"
+)
+
+// yamlIndent 写出 YAML 时默认的缩进
+const yamlIndent = 2
+
+// jsonNumberPattern JSON 允许的数字写法
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// textSuffix 返回路径的文本格式后缀 .json/.yaml/.yml，二进制文件返回空字符串
+func textSuffix(path string) string {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case jsonSuffix, yamlSuffix, ymlSuffix:
+		return ext
+	default:
+		return ""
+	}
+}
+
+// trimTextSuffix 去掉路径末尾的 .json/.yaml/.yml
+func trimTextSuffix(path string) string {
+	if suffix := textSuffix(path); suffix != "" {
+		return path[:len(path)-len(suffix)]
+	}
+	return path
+}
+
+// isYamlPath 路径是否为 .yaml 或 .yml 文件
+func isYamlPath(path string) bool {
+	suffix := textSuffix(path)
+	return suffix == yamlSuffix || suffix == ymlSuffix
+}
+
+// marshalText 按路径后缀将数据编码为 JSON 或 YAML，JSON 输出选项同样适用于 YAML
+func marshalText(path string, data interface{}) ([]byte, error) {
+	if !isYamlPath(path) {
+		return marshalJson(data)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	options := (&CommonService{}).GetJsonOptions()
+	indent := options.Indent
+	if indent <= 0 {
+		indent = yamlIndent
+	}
+	// 先按 JSON 选项排序键和处理浮点数精度，再转换为 YAML
+	options.Indent = 0
+	raw, err = formatJson(raw, options)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYaml(raw, indent)
+}
+
+// unmarshalText 按路径后缀将 JSON 或 YAML 解码到 data
+func unmarshalText(path string, r io.Reader, data interface{}) error {
+	if !isYamlPath(path) {
+		return json.NewDecoder(r).Decode(data)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	raw, err = yamlToJson(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, data)
+}
+
+// jsonToYaml 将 JSON 转换为 YAML 文本
+func jsonToYaml(raw []byte, indent int) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	node, err := yamlNodeFromJson(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlNodeFromJson 读取下一个 JSON 值并转换为 YAML 节点，对象的键保持原来的顺序
+func yamlNodeFromJson(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if v == '{' {
+			node.Kind, node.Tag = yaml.MappingNode, "!!map"
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			child, err := yamlNodeFromJson(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		// 读取结尾的 } 或 ]
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON token %v", token)
+	}
+}
+
+// yamlToJson 将 YAML 文本转换为 JSON，只接受能用 JSON 表示的内容
+func yamlToJson(raw []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind == 0 {
+		return nil, fmt.Errorf("YAML document is empty")
+	}
+	var buf bytes.Buffer
+	if err := writeYamlAsJson(&buf, &document); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYamlAsJson 将 YAML 节点写为 JSON，数字保持原来的写法，锚点引用会被展开
+func writeYamlAsJson(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeYamlAsJson(buf, node.Content[0])
+
+	case yaml.AliasNode:
+		return writeYamlAsJson(buf, node.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: mapping keys must be strings", key.Line)
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJsonString(buf, key.Value)
+			buf.WriteByte(':')
+			if err := writeYamlAsJson(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeYamlAsJson(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	case yaml.ScalarNode:
+		return writeYamlScalarAsJson(buf, node)
+
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", node.Line)
+	}
+}
+
+// writeYamlScalarAsJson 将 YAML 标量写为 JSON，时间等其他类型按字符串处理
+func writeYamlScalarAsJson(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return err
+		}
+		fmt.Fprint(buf, b)
+	case "!!int", "!!float":
+		if jsonNumberPattern.MatchString(node.Value) {
+			buf.WriteString(node.Value)
+			return nil
+		}
+		// 0x10、1_000、.5 等 YAML 特有的写法
+		var number interface{}
+		if err := node.Decode(&number); err != nil {
+			return err
+		}
+		if f, ok := number.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return fmt.Errorf("line %d: %s cannot be represented", node.Line, node.Value)
+		}
+		encoded, err := json.Marshal(number)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		buf.Write(encoded)
+	default:
+		writeJsonString(buf, node.Value)
+	}
+	return nil
+}
+
+// writeJsonString 写出 JSON 字符串
+func writeJsonString(buf *bytes.Buffer, s string) {
+	encoded, _ := json.Marshal(s)
+	buf.Write(encoded)
+}
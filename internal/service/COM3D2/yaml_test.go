@@ -0,0 +1,138 @@
+package COM3D2
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTextSuffix(t *testing.T) {
+	tests := []struct {
+		path    string
+		suffix  string
+		trimmed string
+		yaml    bool
+	}{
+		{"body.menu", "", "body.menu", false},
+		{"body.menu.json", ".json", "body.menu", false},
+		{"body.menu.yaml", ".yaml", "body.menu", true},
+		{"body.mate.YML", ".yml", "body.mate", true},
+		{"dir.json/body.model", "", "dir.json/body.model", false},
+	}
+	for _, tt := range tests {
+		if got := textSuffix(tt.path); got != tt.suffix {
+			t.Errorf("textSuffix(%q) = %q, want %q", tt.path, got, tt.suffix)
+		}
+		if got := trimTextSuffix(tt.path); got != tt.trimmed {
+			t.Errorf("trimTextSuffix(%q) = %q, want %q", tt.path, got, tt.trimmed)
+		}
+		if got := isYamlPath(tt.path); got != tt.yaml {
+			t.Errorf("isYamlPath(%q) = %v, want %v", tt.path, got, tt.yaml)
+		}
+	}
+}
+
+// JSON 转换为 YAML 再转换回来必须与原 JSON 相同，包括键的顺序和数字的写法
+func TestJsonYamlRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"key order", `{"Signature":"CM3D2_MENU","Version":1000,"A":1,"B":2}`},
+		{"numbers", `{"Int":-3,"Float":1.0,"Small":1e-7,"Big":12345678901234567890,"Exp":2.5E+10}`},
+		{"strings that look like other types", `["true","null","123","1.5","~","yes","0x10",""]`},
+		{"special characters", `{"Name":"メイド\n\"quoted\"\ttab","Key: colon":"# not a comment","-":"- dash"}`},
+		{"empty containers", `{"Object":{},"Array":[],"Null":null}`},
+		{"nested", `{"Materials":[{"Properties":[{"TypeName":"col","Color":[1,0.5,0.25,1]},{"TypeName":"keyword","Keywords":[{"Key":"_A","Value":true}]}]}]}`},
+		{"scalar root", `42`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlText, err := jsonToYaml([]byte(tt.json), yamlIndent)
+			if err != nil {
+				t.Fatalf("jsonToYaml: %v", err)
+			}
+			got, err := yamlToJson(yamlText)
+			if err != nil {
+				t.Fatalf("yamlToJson: %v\n%s", err, yamlText)
+			}
+			if string(got) != tt.json {
+				t.Errorf("round trip:\n got %s\nwant %s\nyaml:\n%s", got, tt.json, yamlText)
+			}
+		})
+	}
+}
+
+func TestJsonToYamlIndent(t *testing.T) {
+	got, err := jsonToYaml([]byte(`{"A":{"B":1}}`), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "A:\n    B: 1\n"; string(got) != want {
+		t.Errorf("jsonToYaml = %q, want %q", got, want)
+	}
+}
+
+// 手写的 YAML 可能使用 JSON 没有的写法
+func TestYamlToJson(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{name: "flow style", yaml: `{A: [1, 2], B: x}`, want: `{"A":[1,2],"B":"x"}`},
+		{name: "hex and underscores", yaml: "A: 0x10\nB: 1_000\n", want: `{"A":16,"B":1000}`},
+		{name: "leading dot float", yaml: "A: .5\n", want: `{"A":0.5}`},
+		{name: "null forms", yaml: "A: ~\nB:\nC: null\n", want: `{"A":null,"B":null,"C":null}`},
+		{name: "quoted scalars stay strings", yaml: "A: '1'\nB: \"true\"\n", want: `{"A":"1","B":"true"}`},
+		{name: "yaml 1.1 booleans are strings", yaml: "A: yes\nB: on\n", want: `{"A":"yes","B":"on"}`},
+		{name: "timestamps are strings", yaml: "A: 2024-01-02\n", want: `{"A":"2024-01-02"}`},
+		{name: "anchors are expanded", yaml: "A: &x {B: 1}\nC: *x\n", want: `{"A":{"B":1},"C":{"B":1}}`},
+		{name: "block string", yaml: "A: |\n  line 1\n  line 2\n", want: `{"A":"line 1\nline 2\n"}`},
+		{name: "empty document", yaml: "", wantErr: "empty"},
+		{name: "infinity", yaml: "A: .inf\n", wantErr: "cannot be represented"},
+		{name: "complex key", yaml: "? [1, 2]\n: x\n", wantErr: "mapping keys must be strings"},
+		{name: "invalid yaml", yaml: "A: [1, 2\n", wantErr: "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJson([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("yamlToJson error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("yamlToJson: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("yamlToJson = %s, want %s", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("yamlToJson produced invalid JSON %s", got)
+			}
+		})
+	}
+}
+
+// .yaml 和 .json 文件读出的结构体相同
+func TestUnmarshalTextYamlAndJson(t *testing.T) {
+	type document struct {
+		Name   string    `json:"Name"`
+		Values []float32 `json:"Values"`
+	}
+	var fromJson, fromYaml document
+	if err := unmarshalText("a.json", bytes.NewReader([]byte(`{"Name":"x","Values":[1,2.5]}`)), &fromJson); err != nil {
+		t.Fatal(err)
+	}
+	if err := unmarshalText("a.yaml", bytes.NewReader([]byte("Name: x\nValues:\n  - 1\n  - 2.5\n")), &fromYaml); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJson, fromYaml) {
+		t.Errorf("json = %+v, yaml = %+v", fromJson, fromYaml)
+	}
+}
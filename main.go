@@ -1,17 +1,35 @@
 package main
 
 import (
+	"COM3D2_MOD_EDITOR_V2/internal/cli"
 	"COM3D2_MOD_EDITOR_V2/internal/service/COM3D2"
+	"context"
 	"embed"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"net"
+	"os"
 )
 
 //go:embed all:frontend/dist
 var assets embed.FS
 
 func main() {
+	// 第一个参数是子命令时以无界面的命令行模式运行，不启动 GUI
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	// 已有实例在运行时把文件转发给它，然后退出
+	if forwardToRunningInstance(startupFilePaths(os.Args[1:])) {
+		return
+	}
+
+	if err := COM3D2.LoadSettings(); err != nil {
+		println("Warning:", err.Error())
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -25,6 +43,17 @@ func main() {
 	TexService := &COM3D2.TexService{}
 	AnmService := &COM3D2.AnmService{}
 	ModelService := &COM3D2.ModelService{}
+	PresetService := &COM3D2.PresetService{}
+	SaveService := &COM3D2.SaveService{}
+	BatchService := &COM3D2.BatchService{}
+	DependencyService := &COM3D2.DependencyService{}
+	DiffService := &COM3D2.DiffService{}
+	MergeService := &COM3D2.MergeService{}
+	DocumentService := &COM3D2.DocumentService{}
+	PatchService := &COM3D2.PatchService{}
+	WatcherService := &COM3D2.WatcherService{}
+	SettingsService := &COM3D2.SettingsService{}
+	LintService := &COM3D2.LintService{}
 
 	MenuModel := &COM3D2.MenuModel{}
 	MateModel := &COM3D2.MateModel{}
@@ -35,6 +64,16 @@ func main() {
 	TexModel := &COM3D2.TexModel{}
 	AnmModel := &COM3D2.AnmModel{}
 	ModelModel := &COM3D2.ModelModel{}
+	PresetModel := &COM3D2.PresetModel{}
+	SaveModel := &COM3D2.SaveModel{}
+	BatchModel := &COM3D2.BatchModel{}
+	DependencyModel := &COM3D2.DependencyModel{}
+	DiffModel := &COM3D2.DiffModel{}
+	MergeModel := &COM3D2.MergeModel{}
+	DocumentModel := &COM3D2.DocumentModel{}
+	WatcherModel := &COM3D2.WatcherModel{}
+
+	var instanceListener net.Listener
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -51,7 +90,23 @@ func main() {
 			Assets: assets,
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
-		OnStartup:        app.Startup,
+		OnStartup: func(ctx context.Context) {
+			app.Startup(ctx)
+			BatchService.Startup(ctx)
+			WatcherService.Startup(ctx)
+
+			listener, err := listenForInstances(app.openForwardedFiles)
+			if err != nil {
+				println("Warning:", err.Error())
+				return
+			}
+			instanceListener = listener
+		},
+		OnShutdown: func(ctx context.Context) {
+			if instanceListener != nil {
+				instanceListener.Close()
+			}
+		},
 		Bind: []interface{}{
 			app,
 			CommonService,
@@ -64,6 +119,17 @@ func main() {
 			TexService,
 			AnmService,
 			ModelService,
+			PresetService,
+			SaveService,
+			BatchService,
+			DependencyService,
+			DiffService,
+			MergeService,
+			DocumentService,
+			PatchService,
+			WatcherService,
+			SettingsService,
+			LintService,
 			MenuModel,
 			MateModel,
 			PMatModel,
@@ -73,6 +139,14 @@ func main() {
 			TexModel,
 			AnmModel,
 			ModelModel,
+			PresetModel,
+			SaveModel,
+			BatchModel,
+			DependencyModel,
+			DiffModel,
+			MergeModel,
+			DocumentModel,
+			WatcherModel,
 		},
 	})
 